@@ -5,6 +5,7 @@ import (
 
 	"github.com/Nerzal/gocloak/v13"
 	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/credstore"
 	fs "github.com/fmjstudios/gopskit/pkg/fsi"
 	"github.com/fmjstudios/gopskit/pkg/kube"
 	"github.com/fmjstudios/gopskit/pkg/log"
@@ -79,6 +80,14 @@ func New(opts ...Opt) (*State, error) {
 		KeycloakClient: key,
 	}
 
+	// files in the cache path, unless configured otherwise by the CLI flags. Warnings about plaintext
+	// credentials are left to the flags' configuration, so that they're only logged once.
+	store, err := credstore.FromConfig(credstore.DefaultConfig(Name), Name, a.Paths, a.Kube)
+	if err != nil {
+		return nil, err
+	}
+	a.Credentials = store
+
 	// (re-)configure if the user wants to do so
 	for _, o := range opts {
 		o(a)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/fmjstudios/gopskit/internal/ssolo/app"
+	"github.com/fmjstudios/gopskit/pkg/credstore"
 	"github.com/spf13/cobra"
 )
 
@@ -19,6 +20,7 @@ func NewRootCommand(ssolo *app.State) *cobra.Command {
 		label       string
 		environment string
 		namespace   string
+		credentials credstore.Config
 	)
 
 	cmd := &cobra.Command{
@@ -27,6 +29,9 @@ func NewRootCommand(ssolo *app.State) *cobra.Command {
 		Long:             "Manage authentication for Kubernetes applications using Keycloak",
 		TraverseChildren: true,
 		SilenceErrors:    true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return ssolo.ConfigureCredentials(credentials)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				cmd.Usage()
@@ -48,6 +53,16 @@ func NewRootCommand(ssolo *app.State) *cobra.Command {
 	cmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "",
 		"The Kubernetes namespace to use. None equates to checking the entire cluster.")
 
+	credentials = credstore.DefaultConfig(ssolo.Name)
+	cmd.PersistentFlags().StringVar(&credentials.Backend, "credentials-backend", credentials.Backend,
+		fmt.Sprintf("The backend to store credentials in. One of: %v", credstore.Backends))
+	cmd.PersistentFlags().StringVar(&credentials.Location, "credentials-location", "",
+		"The backend-specific location of the credentials, e.g. a directory, '<namespace>/<secret>' or a file path. "+
+			fmt.Sprintf("Credentials are encrypted if %s is set", credstore.PassphraseEnv(ssolo.Name)))
+	cmd.PersistentFlags().BoolVar(&credentials.Unencrypted, "credentials-unencrypted", false,
+		fmt.Sprintf("Deliberately store credentials without encryption if %s is unset, e.g. within a backend "+
+			"encrypting them itself", credstore.PassphraseEnv(ssolo.Name)))
+
 	// add subcommands
 	for _, opt := range Commands {
 		cmd.AddCommand(opt(ssolo))
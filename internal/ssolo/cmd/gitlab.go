@@ -34,6 +34,7 @@ func NewGitLabCommand(ssolo *app.State) *cobra.Command {
 			var exists bool = true // assume true to avoid unneeded creations
 
 			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
 			envF := proc.Must(cmd.Flags().GetString("environment"))
 			environment := proc.Must(core.EnvFromString(envF))
 			namespace := proc.Must(cmd.Flags().GetString("namespace"))
@@ -57,14 +58,18 @@ func NewGitLabCommand(ssolo *app.State) *cobra.Command {
 			}
 
 			// wait until the pod is running
-			kcLeaderPod, err := cmdutil.LeaderPod(ssolo, pods, namespace, label)
+			kcLeaderPod, err := cmdutil.LeaderPod(ctx, ssolo, pods)
+			if err != nil {
+				return err
+			}
+
+			kcLeaderPod, err = cmdutil.WaitForRunning(ctx, ssolo, *kcLeaderPod)
 			if err != nil {
 				return err
 			}
-			cmdutil.WaitUntilRunning(ssolo, *kcLeaderPod)
 
 			// port-forward the (leader)
-			ssolo.Log.Infof("Port-forwarding Vault Leader: %s", kcLeaderPod.Name)
+			ssolo.Log.Infof("Port-forwarding Keycloak Pod: %s", kcLeaderPod.Name)
 			go func() {
 				err := ssolo.Kube.PortForward(ctx, *kcLeaderPod)
 				if err != nil {
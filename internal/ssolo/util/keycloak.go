@@ -1,15 +1,13 @@
 package util
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"path/filepath"
-	"strings"
-	"time"
+	"path"
 
 	"github.com/fmjstudios/gopskit/internal/ssolo/app"
 	"github.com/fmjstudios/gopskit/pkg/core"
-	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/kube"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -25,35 +23,24 @@ type Credentials struct {
 	Tokens map[string]string
 }
 
-// CredentialPath builds the filesystem path to write the credentials to after we unseal the Vault,
-// since it most likely is required for later commands. This function make the path deterministic
-// per execution env.Environment.
-func CredentialPath(a *app.State, env core.Environment) string {
-	return filepath.Join(a.Paths.Cache, env.String(), "keycloak-credentials.json")
+// CredentialKey is the key the Keycloak Credentials are stored under within the app's credential store
+// for the given env.Environment
+func CredentialKey(env core.Environment) string {
+	return path.Join(env.String(), "keycloak-credentials.json")
 }
 
-// WriteCredentials writes the Vault Credentials to the CredentialPath for the given env.Environment
+// WriteCredentials writes the Keycloak Credentials to the app's credential store for the given env.Environment
 func WriteCredentials(a *app.State, env core.Environment, credentials *Credentials) error {
-	p := CredentialPath(a, env)
-	jsn, err := json.MarshalIndent(credentials, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return fs.Write(p, jsn)
+	a.MigrateCredentials(CredentialKey(env))
+	return a.Credentials.Write(CredentialKey(env), credentials)
 }
 
-// ReadCredentials reads the Vault Credentials from the CredentialPath for the given env.Environment
+// ReadCredentials reads the Keycloak Credentials from the app's credential store for the given env.Environment
 func ReadCredentials(a *app.State, env core.Environment) (*Credentials, error) {
-	p := CredentialPath(a, env)
-	raw, err := fs.Read(p)
-	if err != nil {
-		return nil, err
-	}
+	a.MigrateCredentials(CredentialKey(env))
 
 	var credentials Credentials
-	err = json.Unmarshal(raw, &credentials)
-	if err != nil {
+	if err := a.Credentials.Read(CredentialKey(env), &credentials); err != nil {
 		return nil, err
 	}
 
@@ -80,57 +67,34 @@ func Pods(a *app.State, namespace, label string) ([]corev1.Pod, error) {
 	return pods, nil
 }
 
-// TODO(FMJdev): this is duplicated because the original source was a internal package
-func LeaderPod(a *app.State, pods []corev1.Pod, namespace, label string) (*corev1.Pod, error) {
-	var leader *corev1.Pod
-	var activePods []corev1.Pod
-	var err error
-
-	if len(pods) > 1 {
-		label = fmt.Sprintf("%s,%s", label, "vault-active=true")
-		activePods, err = a.Kube.Pods(namespace, metav1.ListOptions{
-			LabelSelector: label,
-		})
-
-		if err != nil {
-			return nil, err
-		}
-	}
+// KeycloakLeader is the kube.LeaderStrategy for Keycloak. Keycloak instances are equal peers, so the
+// first Pod of the StatefulSet is preferred, falling back to the oldest ready Pod.
+func KeycloakLeader() kube.LeaderStrategy {
+	return kube.FirstOf(kube.OrdinalLeader(0), kube.ReadyLeader())
+}
 
-	// matched more than one
-	if len(activePods) > 1 {
-		return nil, fmt.Errorf("could not determine Vault leader pod. "+
-			"Invalid configuration: Kubernetes label %s matched more than one pod", label)
+// LeaderPod determines the Keycloak Pod to port-forward among the given Pods
+func LeaderPod(ctx context.Context, a *app.State, pods []corev1.Pod) (*corev1.Pod, error) {
+	leader, err := a.Kube.LeaderPod(ctx, pods, KeycloakLeader())
+	if err != nil {
+		return nil, fmt.Errorf("could not determine Keycloak pod: %v", err)
 	}
 
-	// no pod is labeled with "vault-active=true"
-	if len(activePods) == 0 {
-		for _, pod := range pods {
-			if strings.Contains(pod.Name, "0") {
-				leader = &pod
-			}
-		}
-
-		if leader == nil {
-			return nil, fmt.Errorf("could not determine Vault leader pod. Unfamiliar naming scheme. " +
-				"None of your Vault Pod names contain a zero")
-		}
+	return leader, nil
+}
 
-		return leader, nil
+// WaitForRunning waits until the Keycloak Pod is running and returns its latest state
+func WaitForRunning(ctx context.Context, a *app.State, pod corev1.Pod) (*corev1.Pod, error) {
+	if kube.PodRunning(&pod) {
+		return &pod, nil
 	}
 
-	return &pods[0], nil
-}
-
-// TODO(FMJdev): this is duplicated because the original source was a internal package
-func WaitUntilRunning(a *app.State, pod corev1.Pod) {
-	for {
-		if pod.Status.Phase != corev1.PodRunning {
-			a.Log.Infof("main vault Pod: %s is not running yet - waiting for Pod to start", pod.Name)
-			time.Sleep(2500 * time.Millisecond)
-		} else {
-			a.Log.Infof("main vault Pod: %s is running", pod.Name)
-			break
-		}
+	a.Log.Infof("Keycloak Pod: %s is not running yet - waiting for Pod to start", pod.Name)
+	running, err := a.Kube.WaitForPod(ctx, pod.Namespace, pod.Name, kube.PodRunning)
+	if err != nil {
+		return nil, fmt.Errorf("keycloak Pod: %s did not start: %v", pod.Name, err)
 	}
+
+	a.Log.Infof("Keycloak Pod: %s is running", pod.Name)
+	return running, nil
 }
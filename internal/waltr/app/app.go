@@ -2,9 +2,12 @@ package app
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/credstore"
 	fs "github.com/fmjstudios/gopskit/pkg/fsi"
 	"github.com/fmjstudios/gopskit/pkg/log"
 	"github.com/fmjstudios/gopskit/pkg/proc"
@@ -32,6 +35,56 @@ type State struct {
 	// VaultClient is the HashCorp first-party Go Vault HTTP client, which waltr
 	// uses for nearly all of its functionality
 	VaultClient *vault.Client
+
+	// Vault are the options VaultClient was configured with
+	Vault VaultOptions
+}
+
+// VaultOptions configure how waltr reaches Vault and verifies its TLS certificate
+type VaultOptions struct {
+	// Address is the address of a Vault reachable without port-forwarding, e.g. through an Ingress. If
+	// it is unset, waltr port-forwards the Vault leader Pod.
+	Address string
+
+	// CACert is the path to a PEM-encoded CA certificate verifying Vault's TLS certificate
+	CACert string
+
+	// CACertBytes is PEM-encoded CA certificate data, e.g. read from CASecret. It takes precedence
+	// over CACert.
+	CACertBytes []byte
+
+	// CASecret is the Kubernetes Secret holding Vault's CA certificate as 'ca.crt', given as
+	// '<namespace>/<name>' or as '<name>' within Vault's namespace
+	CASecret string
+
+	// ClientCert and ClientKey are the paths to a PEM-encoded client certificate and its key
+	ClientCert string
+	ClientKey  string
+
+	// ServerName is the hostname Vault's TLS certificate is verified against
+	ServerName string
+
+	// SkipVerify disables the verification of Vault's TLS certificate
+	SkipVerify bool
+}
+
+// DefaultVaultOptions returns the VaultOptions configured by the environment variables of the Vault CLI
+func DefaultVaultOptions() VaultOptions {
+	skip, _ := strconv.ParseBool(os.Getenv("VAULT_SKIP_VERIFY"))
+
+	return VaultOptions{
+		Address:    os.Getenv("VAULT_ADDR"),
+		CACert:     os.Getenv("VAULT_CACERT"),
+		ClientCert: os.Getenv("VAULT_CLIENT_CERT"),
+		ClientKey:  os.Getenv("VAULT_CLIENT_KEY"),
+		ServerName: os.Getenv("VAULT_TLS_SERVER_NAME"),
+		SkipVerify: skip,
+	}
+}
+
+// Direct reports whether Vault is reachable without port-forwarding
+func (o VaultOptions) Direct() bool {
+	return o.Address != ""
 }
 
 // New creates a newly initialized instance of the State type
@@ -69,15 +122,6 @@ func New(opts ...Opt) (*State, error) {
 	// 	return nil, err
 	// }
 
-	// enforce HTTPS
-	va := fmt.Sprintf("https://127.0.0.1:%s", kube.DefaultLocalPort)
-	vc, err := vault.New(vault.WithAddress(va), vault.WithRequestTimeout(60*time.Second), vault.WithTLS(vault.TLSConfiguration{
-		InsecureSkipVerify: true,
-	}))
-
-	if err != nil {
-		return nil, fmt.Errorf("could not create vault client: %v", err)
-	}
 	stamps := stamp.New()
 
 	a := &State{
@@ -90,9 +134,21 @@ func New(opts ...Opt) (*State, error) {
 			Paths: platf,
 			Stamp: stamps,
 		},
-		VaultClient: vc,
 	}
 
+	// the Vault CLI's environment, unless configured otherwise by the CLI flags
+	if err := a.ConfigureVault(DefaultVaultOptions()); err != nil {
+		return nil, err
+	}
+
+	// files in the cache path, unless configured otherwise by the CLI flags. Warnings about plaintext
+	// credentials are left to the flags' configuration, so that they're only logged once.
+	store, err := credstore.FromConfig(credstore.DefaultConfig(Name), Name, a.Paths, a.Kube)
+	if err != nil {
+		return nil, err
+	}
+	a.Credentials = store
+
 	// (re-)configure if the user wants to do so
 	for _, o := range opts {
 		o(a)
@@ -101,6 +157,58 @@ func New(opts ...Opt) (*State, error) {
 	return a, nil
 }
 
+// ConfigureVault (re-)creates the State's VaultClient with the given VaultOptions. The client talks to
+// the locally port-forwarded Vault unless an Address is set.
+func (a *State) ConfigureVault(opts VaultOptions) error {
+	vc, err := newVaultClient(opts)
+	if err != nil {
+		return err
+	}
+
+	a.VaultClient = vc
+	a.Vault = opts
+	return nil
+}
+
+// VaultClientFor creates a new Vault client with the State's VaultOptions for a different address, e.g.
+// to talk to a specific Vault Pod through its own port-forward. Unless the VaultOptions configure a
+// ServerName, Vault's TLS certificate is verified against serverName.
+func (a *State) VaultClientFor(address, serverName string) (*vault.Client, error) {
+	opts := a.Vault
+	opts.Address = address
+	if opts.ServerName == "" {
+		opts.ServerName = serverName
+	}
+
+	return newVaultClient(opts)
+}
+
+// newVaultClient creates a Vault client with the given VaultOptions
+func newVaultClient(opts VaultOptions) (*vault.Client, error) {
+	address := opts.Address
+	if address == "" {
+		address = fmt.Sprintf("https://127.0.0.1:%s", kube.DefaultLocalPort)
+	}
+
+	tls := vault.TLSConfiguration{
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.SkipVerify,
+	}
+	tls.ServerCertificate.FromBytes = opts.CACertBytes
+	if len(opts.CACertBytes) == 0 {
+		tls.ServerCertificate.FromFile = opts.CACert
+	}
+	tls.ClientCertificate.FromFile = opts.ClientCert
+	tls.ClientCertificateKey.FromFile = opts.ClientKey
+
+	vc, err := vault.New(vault.WithAddress(address), vault.WithRequestTimeout(60*time.Second), vault.WithTLS(tls))
+	if err != nil {
+		return nil, fmt.Errorf("could not create vault client: %v", err)
+	}
+
+	return vc, nil
+}
+
 // WithVaultOpts configures waltr's VaultClient instance with custom Options
 // from the vault-client-go package
 func WithVaultOpts(opts ...vault.ClientOption) Opt {
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewApplyCommand // assure type compatibility
+
+func NewApplyCommand(app *app.State) *cobra.Command {
+	var (
+		token string
+		file  string
+		plan  bool
+		prune bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply a declarative Vault configuration",
+		Long: "Converge Vault to the authentication methods, secrets engines, ACL policies, password policies, " +
+			"Kubernetes Auth roles and transit keys described by a YAML or HCL file. Without a file the built-in " +
+			"configuration is applied.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec, err := loadSpec(app, file)
+			if err != nil {
+				return err
+			}
+
+			changes, cancel, err := specChanges(app, cmd, token, spec, prune)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			if len(changes) == 0 {
+				app.Log.Info("Vault configuration is up to date")
+				return nil
+			}
+
+			if plan {
+				return util.RenderPlan(os.Stdout, changes)
+			}
+
+			if err := util.ApplyChanges(app, changes); err != nil {
+				return err
+			}
+
+			app.Log.Infof("successfully applied %d change(s) to Vault", len(changes))
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&file, "file", "f", "", "The YAML or HCL file describing the Vault configuration")
+	cmd.PersistentFlags().BoolVar(&plan, "plan", false, "Only print the changes instead of applying them")
+	cmd.PersistentFlags().BoolVar(&prune, "prune", false,
+		"Delete policies, roles and authentication methods which are not part of the configuration")
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+
+	return cmd
+}
+
+// loadSpec loads the Spec from the given file or, if the file is unset, returns the built-in Spec
+func loadSpec(waltr *app.State, file string) (*util.Spec, error) {
+	if file == "" {
+		waltr.Log.Info("'file' option is unset, using the built-in Vault configuration")
+		return util.DefaultSpec(), nil
+	}
+
+	return util.LoadSpec(file)
+}
+
+// specChanges connects to Vault and computes the changes required to converge Vault to the given Spec.
+// The returned context.CancelFunc shuts down the port-forward.
+func specChanges(waltr *app.State, cmd *cobra.Command, token string, spec *util.Spec, prune bool) ([]util.Change, context.CancelFunc, error) {
+	cancel, err := connect(waltr, cmd, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes, err := util.Changes(waltr, spec, util.ChangeOptions{Prune: prune})
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	return changes, cancel, nil
+}
+
+// applySpec prints the plan to converge Vault to the given Spec without pruning and applies it, unless
+// plan is set. It's how the subcommands covering a part of the configuration apply their scope of the Spec.
+func applySpec(waltr *app.State, cmd *cobra.Command, token string, spec *util.Spec, plan bool) error {
+	changes, cancel, err := specChanges(waltr, cmd, token, spec, false)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	if len(changes) == 0 {
+		waltr.Log.Info("Vault configuration is up to date")
+		return nil
+	}
+
+	if err := util.RenderPlan(os.Stdout, changes); err != nil {
+		return err
+	}
+
+	if plan {
+		return nil
+	}
+
+	if err := util.ApplyChanges(waltr, changes); err != nil {
+		return err
+	}
+
+	waltr.Log.Infof("successfully applied %d change(s) to Vault", len(changes))
+	return nil
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewAuditCommand // assure type compatibility
+
+func NewAuditCommand(app *app.State) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:              "audit",
+		Short:            "Manage Vault's audit devices",
+		Long:             "Enable, disable and list the file, syslog and socket audit devices of Vault",
+		TraverseChildren: true,
+	}
+
+	// subcommands
+	for _, subc := range AuditSubcommands {
+		cmd.AddCommand(subc(app))
+	}
+
+	return cmd
+}
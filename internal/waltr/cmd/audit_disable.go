@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewAuditDisableCommand // assure type compatibility
+
+func NewAuditDisableCommand(app *app.State) *cobra.Command {
+	var token string
+
+	cmd := &cobra.Command{
+		Use:              "disable <path>",
+		Short:            "Disable an audit device",
+		Long:             "Disable the Vault audit device enabled at the given path. Absent devices are skipped.",
+		Args:             cobra.ExactArgs(1),
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			disabled, err := util.DisableAudit(app, args[0])
+			if err != nil {
+				return err
+			}
+
+			if !disabled {
+				app.Log.Infof("audit device: %s is not enabled - skipping", args[0])
+				return nil
+			}
+
+			app.Log.Infof("successfully disabled audit device: %s", args[0])
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+
+	return cmd
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewAuditEnableCommand // assure type compatibility
+
+func NewAuditEnableCommand(app *app.State) *cobra.Command {
+	var (
+		token              string
+		path               string
+		description        string
+		local              bool
+		replace            bool
+		filePath           string
+		mode               string
+		facility           string
+		tag                string
+		address            string
+		socketType         string
+		format             string
+		prefix             string
+		logRaw             bool
+		hmacAccessor       bool
+		elideListResponses bool
+		options            map[string]string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "enable <type>",
+		Short: "Enable an audit device",
+		Long: fmt.Sprintf("Enable a Vault audit device of one of the types: %v. Re-running the command with "+
+			"the same configuration is a no-op.", util.AuditTypes),
+		Args:             cobra.ExactArgs(1),
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			typ := args[0]
+
+			opts := map[string]string{
+				"format": format,
+				"prefix": prefix,
+			}
+
+			switch typ {
+			case util.AuditFile:
+				opts["file_path"] = filePath
+				opts["mode"] = mode
+			case util.AuditSyslog:
+				opts["facility"] = facility
+				opts["tag"] = tag
+			case util.AuditSocket:
+				opts["address"] = address
+				opts["socket_type"] = socketType
+			}
+
+			// only pass the boolean options along if they're set explicitly, Vault applies the defaults
+			if cmd.Flags().Changed("log-raw") {
+				opts["log_raw"] = strconv.FormatBool(logRaw)
+			}
+
+			if cmd.Flags().Changed("hmac-accessor") {
+				opts["hmac_accessor"] = strconv.FormatBool(hmacAccessor)
+			}
+
+			if cmd.Flags().Changed("elide-list-responses") {
+				opts["elide_list_responses"] = strconv.FormatBool(elideListResponses)
+			}
+
+			for k, v := range options {
+				opts[k] = v
+			}
+
+			device := util.NewAuditDevice(typ, path, opts)
+			device.Description = description
+			device.Local = local
+
+			if err := device.Validate(); err != nil {
+				return err
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			enabled, err := util.EnableAudit(app, device, replace)
+			if err != nil {
+				return err
+			}
+
+			if enabled {
+				app.Log.Infof("successfully enabled %s audit device at: %s", device.Type, device.Path)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&path, "path", "p", "",
+		"The path to enable the audit device at. Defaults to its type")
+	cmd.PersistentFlags().StringVar(&description, "description", "", "A human-friendly description of the audit device")
+	cmd.PersistentFlags().BoolVar(&local, "local", false, "Do not replicate the audit device to performance secondaries")
+	cmd.PersistentFlags().BoolVar(&replace, "replace", false,
+		"Disable and re-enable an existing audit device at the same path with a different configuration")
+	cmd.PersistentFlags().StringVar(&filePath, "file-path", util.DefaultAuditFilePath,
+		"The path of the log file within the Vault Pods (file devices)")
+	cmd.PersistentFlags().StringVar(&mode, "mode", "", "The permission bits of the log file, e.g. '0600' (file devices)")
+	cmd.PersistentFlags().StringVar(&facility, "facility", "", "The syslog facility, e.g. 'AUTH' (syslog devices)")
+	cmd.PersistentFlags().StringVar(&tag, "tag", "", "The syslog program name (syslog devices)")
+	cmd.PersistentFlags().StringVar(&address, "address", "", "The 'host:port' to send audit logs to (socket devices)")
+	cmd.PersistentFlags().StringVar(&socketType, "socket-type", "",
+		"The socket type, e.g. 'tcp' or 'udp' (socket devices)")
+	cmd.PersistentFlags().StringVar(&format, "format", "", "The output format of the audit log. One of: json, jsonx")
+	cmd.PersistentFlags().StringVar(&prefix, "prefix", "", "A customizable string prefixed to every audit log line")
+	cmd.PersistentFlags().BoolVar(&logRaw, "log-raw", false,
+		"Log sensitive values without hashing them with the salted HMAC. Only use this for debugging")
+	cmd.PersistentFlags().BoolVar(&hmacAccessor, "hmac-accessor", true, "Hash token accessors with the salted HMAC")
+	cmd.PersistentFlags().BoolVar(&elideListResponses, "elide-list-responses", false,
+		"Replace the entries of list responses with their count")
+	cmd.PersistentFlags().StringToStringVar(&options, "option", nil,
+		"Additional audit device options as 'key=value' pairs. Takes precedence over the dedicated flags")
+
+	return cmd
+}
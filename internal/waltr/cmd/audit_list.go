@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewAuditListCommand // assure type compatibility
+
+func NewAuditListCommand(app *app.State) *cobra.Command {
+	var (
+		token  string
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:              "list",
+		Short:            "List audit devices",
+		Aliases:          []string{"ls"},
+		Long:             "List the enabled Vault audit devices and their options",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			devices, err := util.AuditDevices(app)
+			if err != nil {
+				return err
+			}
+
+			return util.RenderAuditDevices(os.Stdout, output, devices)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+
+	return cmd
+}
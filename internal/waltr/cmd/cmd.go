@@ -1,9 +1,16 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/credstore"
+	"github.com/fmjstudios/gopskit/pkg/proc"
 	"github.com/spf13/cobra"
 )
 
@@ -11,16 +18,91 @@ var (
 	// Commands is a slice of CLIOpt options for subcommands of the 'waltr' CLI
 	Commands = []app.CLIOpt{
 		NewInitCommand,
+		NewUnsealCommand,
 		NewMountsCommand,
 		NewConfigureCommand,
 		NewPrepareCommand,
 		NewTransitCommand,
-		NewTestCommand,
+		NewApplyCommand,
+		NewPlanCommand,
+		NewStatusCommand,
+		NewSnapshotCommand,
+		NewRekeyCommand,
+		NewGenerateRootCommand,
+		NewAuditCommand,
+		NewKVCommand,
+		NewVSOCommand,
+		NewOIDCCommand,
+		NewPKICommand,
+		NewDatabaseCommand,
+		NewTokenCommand,
+		NewDoctorCommand,
+		NewSealCommand,
+		NewSecretsCommand,
+		NewIdentityCommand,
+		NewPolicyCommand,
 	}
 
-	// PrepareSubcommands is a slice of CLIOpt options for subcommands of the 'prepare' subcommand
-	PrepareSubcommands = []app.CLIOpt{
-		NewPrepareKeycloakCommand,
+	// PolicySubcommands is a slice of CLIOpt options for subcommands of the 'policy' subcommand
+	PolicySubcommands = []app.CLIOpt{
+		NewPolicyTestCommand,
+	}
+
+	// SealSubcommands is a slice of CLIOpt options for subcommands of the 'seal' subcommand
+	SealSubcommands = []app.CLIOpt{
+		NewSealMigrateCommand,
+	}
+
+	// SecretsSubcommands is a slice of CLIOpt options for subcommands of the 'secrets' subcommand
+	SecretsSubcommands = []app.CLIOpt{
+		NewSecretsGenerateCommand,
+	}
+
+	// SnapshotSubcommands is a slice of CLIOpt options for subcommands of the 'snapshot' subcommand
+	SnapshotSubcommands = []app.CLIOpt{
+		NewSnapshotSaveCommand,
+		NewSnapshotRestoreCommand,
+		NewSnapshotListCommand,
+	}
+
+	// AuditSubcommands is a slice of CLIOpt options for subcommands of the 'audit' subcommand
+	AuditSubcommands = []app.CLIOpt{
+		NewAuditEnableCommand,
+		NewAuditDisableCommand,
+		NewAuditListCommand,
+	}
+
+	// IdentitySubcommands is a slice of CLIOpt options for subcommands of the 'identity' subcommand
+	IdentitySubcommands = []app.CLIOpt{
+		NewIdentitySyncCommand,
+	}
+
+	// KVSubcommands is a slice of CLIOpt options for subcommands of the 'kv' subcommand
+	KVSubcommands = []app.CLIOpt{
+		NewKVExportCommand,
+		NewKVImportCommand,
+		NewKVSyncCommand,
+	}
+
+	// TokenSubcommands is a slice of CLIOpt options for subcommands of the 'token' subcommand
+	TokenSubcommands = []app.CLIOpt{
+		NewTokenCreateCommand,
+		NewTokenLookupCommand,
+		NewTokenRenewCommand,
+		NewTokenRevokeCommand,
+		NewTokenListAccessorsCommand,
+	}
+
+	// TransitSubcommands is a slice of CLIOpt options for subcommands of the 'transit' subcommand
+	TransitSubcommands = []app.CLIOpt{
+		NewTransitRotateCommand,
+		NewTransitConfigCommand,
+		NewTransitRewrapCommand,
+	}
+
+	// VSOSubcommands is a slice of CLIOpt options for subcommands of the 'vso' subcommand
+	VSOSubcommands = []app.CLIOpt{
+		NewVSOGenerateCommand,
 	}
 )
 
@@ -29,6 +111,8 @@ func NewRootCommand(waltr *app.State) *cobra.Command {
 		environment string
 		label       string
 		namespace   string
+		credentials credstore.Config
+		vault       app.VaultOptions
 	)
 
 	cmd := &cobra.Command{
@@ -37,6 +121,13 @@ func NewRootCommand(waltr *app.State) *cobra.Command {
 		Long:             "Manage HashCorp Vault on Kubernetes",
 		TraverseChildren: true,
 		SilenceErrors:    true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := waltr.ConfigureVault(vault); err != nil {
+				return err
+			}
+
+			return waltr.ConfigureCredentials(credentials)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				cmd.Usage()
@@ -59,6 +150,35 @@ func NewRootCommand(waltr *app.State) *cobra.Command {
 	cmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "",
 		"The Kubernetes namespace to use. None equates to checking the entire cluster.")
 
+	credentials = credstore.DefaultConfig(waltr.Name)
+	cmd.PersistentFlags().StringVar(&credentials.Backend, "credentials-backend", credentials.Backend,
+		fmt.Sprintf("The backend to store credentials in. One of: %v", credstore.Backends))
+	cmd.PersistentFlags().StringVar(&credentials.Location, "credentials-location", "",
+		"The backend-specific location of the credentials, e.g. a directory, '<namespace>/<secret>' or a file path. "+
+			fmt.Sprintf("Credentials are encrypted if %s is set", credstore.PassphraseEnv(waltr.Name)))
+	cmd.PersistentFlags().BoolVar(&credentials.Unencrypted, "credentials-unencrypted", false,
+		fmt.Sprintf("Deliberately store credentials without encryption if %s is unset, e.g. within a backend "+
+			"encrypting them itself", credstore.PassphraseEnv(waltr.Name)))
+
+	// Vault Flags, which default to the Vault CLI's environment variables
+	vault = app.DefaultVaultOptions()
+	cmd.PersistentFlags().StringVar(&vault.Address, "vault-addr", vault.Address,
+		"The address of a Vault reachable without port-forwarding, e.g. through an Ingress. Defaults to VAULT_ADDR")
+	cmd.PersistentFlags().StringVar(&vault.CACert, "vault-cacert", vault.CACert,
+		"A PEM-encoded CA certificate file verifying Vault's TLS certificate. Defaults to VAULT_CACERT")
+	cmd.PersistentFlags().StringVar(&vault.CASecret, "vault-ca-secret", "",
+		fmt.Sprintf("A Kubernetes Secret holding Vault's CA certificate as '%s'. Either '<namespace>/<name>' or "+
+			"'<name>' within Vault's namespace", util.VaultCAKey))
+	cmd.PersistentFlags().StringVar(&vault.ClientCert, "vault-client-cert", vault.ClientCert,
+		"A PEM-encoded client certificate file for TLS authentication. Defaults to VAULT_CLIENT_CERT")
+	cmd.PersistentFlags().StringVar(&vault.ClientKey, "vault-client-key", vault.ClientKey,
+		"The private key file of the client certificate. Defaults to VAULT_CLIENT_KEY")
+	cmd.PersistentFlags().StringVar(&vault.ServerName, "tls-server-name", vault.ServerName,
+		"The hostname Vault's TLS certificate is verified against. Defaults to VAULT_TLS_SERVER_NAME or, when "+
+			"port-forwarding, the in-cluster DNS name of Vault's Service")
+	cmd.PersistentFlags().BoolVar(&vault.SkipVerify, "tls-skip-verify", vault.SkipVerify,
+		"Disable the verification of Vault's TLS certificate. Defaults to VAULT_SKIP_VERIFY")
+
 	// add subcommands
 	for _, opt := range Commands {
 		cmd.AddCommand(opt(waltr))
@@ -66,3 +186,40 @@ func NewRootCommand(waltr *app.State) *cobra.Command {
 
 	return cmd
 }
+
+// connect connects waltr's VaultClient to the Vault leader using the persistent flags of the root
+// command. The returned context.CancelFunc shuts down the port-forward.
+func connect(waltr *app.State, cmd *cobra.Command, token string) (context.CancelFunc, error) {
+	envF := proc.Must(cmd.Flags().GetString("environment"))
+	label := proc.Must(cmd.Flags().GetString("label"))
+	namespace := proc.Must(cmd.Flags().GetString("namespace"))
+	environment, err := core.EnvFromString(envF)
+	if err != nil {
+		return nil, err
+	}
+
+	return util.Connect(waltr, util.ConnectOptions{
+		Namespace:   namespace,
+		Label:       label,
+		Token:       token,
+		Environment: environment,
+	})
+}
+
+// confirm asks the user the given question on the command's standard error and reports whether they
+// answered with 'y' or 'yes' on its standard input
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s [y/N]: ", question)
+
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, fmt.Errorf("could not read confirmation: %v", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCommandsHelp renders the help of every command, which merges the persistent flags of its parents
+// into its flag set and panics on conflicting names or shorthands
+func TestCommandsHelp(t *testing.T) {
+	state := &app.State{API: &core.API{Name: app.Name}}
+
+	var paths [][]string
+	var walk func(c *cobra.Command, path []string)
+	walk = func(c *cobra.Command, path []string) {
+		for _, sub := range c.Commands() {
+			p := append(append([]string{}, path...), sub.Name())
+			paths = append(paths, p)
+			walk(sub, p)
+		}
+	}
+	walk(NewRootCommand(state), nil)
+	assert.Contains(t, paths, []string{"token", "create"})
+
+	for _, p := range paths {
+		root := NewRootCommand(state)
+		root.SetOut(io.Discard)
+		root.SetErr(io.Discard)
+		root.SetArgs(append(p, "--help"))
+
+		assert.NotPanics(t, func() {
+			assert.NoError(t, root.Execute())
+		}, strings.Join(p, " "))
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewDatabaseCommand // assure type compatibility
+
+func NewDatabaseCommand(app *app.State) *cobra.Command {
+	var (
+		token      string
+		file       string
+		plan       bool
+		rotateRoot bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "database",
+		Short:   "Configure dynamic database credentials",
+		Aliases: []string{"db"},
+		Long: "Enable the database secrets engine and register the PostgreSQL and MySQL connections and roles " +
+			"described by a (SOPS-encrypted) YAML file. The root credentials of new connections are rotated " +
+			"right after their creation, so that only Vault knows them.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("the 'file' option is required")
+			}
+
+			cfg, err := util.LoadDatabaseConfig(file)
+			if err != nil {
+				return err
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			changes, err := util.DatabaseChanges(app, cfg)
+			if err != nil {
+				return err
+			}
+
+			if len(changes) > 0 {
+				if err := util.RenderPlan(os.Stdout, changes); err != nil {
+					return err
+				}
+
+				if plan {
+					return nil
+				}
+
+				if err := util.ApplyChanges(app, changes); err != nil {
+					return err
+				}
+
+				app.Log.Infof("successfully applied %d change(s) to the database secrets engine: %s", len(changes),
+					cfg.Mount)
+			} else {
+				app.Log.Infof("database secrets engine: %s is up to date", cfg.Mount)
+			}
+
+			if !rotateRoot || plan {
+				return nil
+			}
+
+			for _, name := range cfg.RotatableConnections(changes) {
+				if err := util.RotateDatabaseRoot(context.Background(), app, cfg.Mount, name); err != nil {
+					return err
+				}
+
+				app.Log.Infof("rotated root credentials of database connection: %s", name)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&file, "file", "f", "",
+		"The (SOPS-encrypted) YAML file describing the database connections and roles")
+	cmd.PersistentFlags().BoolVar(&plan, "plan", false, "Only print the changes instead of applying them")
+	cmd.PersistentFlags().BoolVar(&rotateRoot, "rotate-root", false,
+		"Rotate the root credentials of existing connections, except those which skip root rotation")
+
+	return cmd
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/credstore"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewDoctorCommand // assure type compatibility
+
+func NewDoctorCommand(app *app.State) *cobra.Command {
+	var (
+		token        string
+		output       string
+		failOn       string
+		minThreshold int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the security and configuration posture of Vault",
+		Long: "Inspect the running Vault for a still valid root token, missing audit devices, disabled TLS " +
+			"verification, insecure listeners, low Shamir thresholds, unbounded KV-v2 versions and policies " +
+			"granting 'sudo' broadly. Exits non-zero if any finding reaches the 'fail-on' severity or any check " +
+			"could not be completed.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envF := proc.Must(cmd.Flags().GetString("environment"))
+			label := proc.Must(cmd.Flags().GetString("label"))
+			namespace := proc.Must(cmd.Flags().GetString("namespace"))
+			environment := proc.Must(core.EnvFromString(envF))
+
+			threshold, err := util.SeverityFromString(failOn)
+			if err != nil {
+				return err
+			}
+
+			pods, err := util.Pods(app, namespace, label)
+			if err != nil {
+				return fmt.Errorf("could not retrieve Vault pods for label: %s. Error: %v", label, err)
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			// the stored root token is checked even if waltr authenticates with another token
+			opts := util.DoctorOptions{Pods: pods, MinThreshold: minThreshold}
+			creds, err := util.ReadCredentials(app, environment)
+			switch {
+			case errors.Is(err, credstore.ErrNotFound):
+				app.Log.Debugf("could not read Vault credentials: %v", err)
+			case err != nil:
+				opts.CredentialsError = err
+			default:
+				opts.RootToken = creds.Token
+			}
+
+			findings := util.Doctor(app, opts)
+			if err := util.RenderFindings(os.Stdout, output, findings); err != nil {
+				return err
+			}
+
+			if n := util.CountFindings(findings, threshold); n > 0 {
+				return fmt.Errorf("found %d issue(s) with severity %s or higher", n, threshold)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+	cmd.PersistentFlags().StringVar(&failOn, "fail-on", string(util.SeverityCritical),
+		fmt.Sprintf("The lowest severity of findings failing the command. One of: %v", util.Severities))
+	cmd.PersistentFlags().IntVar(&minThreshold, "min-threshold", 3,
+		"The lowest acceptable number of key shares required to unseal Vault")
+
+	return cmd
+}
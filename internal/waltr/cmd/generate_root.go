@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewGenerateRootCommand // assure type compatibility
+
+func NewGenerateRootCommand(app *app.State) *cobra.Command {
+	var (
+		token      string
+		keys       []string
+		reset      bool
+		printToken bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "generate-root",
+		Short: "Generate a new Vault root token",
+		Long: "Generate a new root token with the unseal (or recovery) keys through an OTP-based root token " +
+			"generation, e.g. after the initial root token was revoked. The token is written to the credentials store.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envF := proc.Must(cmd.Flags().GetString("environment"))
+			environment := proc.Must(core.EnvFromString(envF))
+
+			creds, keys, err := credentialKeys(app, environment, keys)
+			if err != nil {
+				return err
+			}
+
+			// root token generation is unauthenticated, the stored token may be revoked already
+			if token == "" && creds.Token == "" {
+				token = "none"
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			root, err := util.GenerateRoot(app, keys, reset)
+			if err != nil {
+				return err
+			}
+			app.Log.Info("successfully generated a new Vault root token")
+
+			creds.Token = root
+
+			if err := util.WriteCredentials(app, environment, creds); err != nil {
+				return util.EmitCredentials(app, &util.Credentials{Token: root}, err)
+			}
+
+			if printToken {
+				fmt.Printf("Vault Root Token: %s\n", root)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault token to connect with")
+	cmd.PersistentFlags().StringSliceVar(&keys, "key", nil,
+		"The unseal (or recovery) keys. Defaults to the keys in the credentials store")
+	cmd.PersistentFlags().BoolVar(&reset, "reset", false, "Cancel a root token generation already in progress")
+	cmd.PersistentFlags().BoolVar(&printToken, "print", false, "Print the new root token to stdout")
+
+	return cmd
+}
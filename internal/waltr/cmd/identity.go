@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewIdentityCommand // assure type compatibility
+
+func NewIdentityCommand(app *app.State) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:              "identity",
+		Short:            "Manage Vault's identity groups and entities",
+		Long:             "Map the groups and users of a Keycloak realm to Vault identity groups and entities",
+		TraverseChildren: true,
+	}
+
+	// subcommands
+	for _, subc := range IdentitySubcommands {
+		cmd.AddCommand(subc(app))
+	}
+
+	return cmd
+}
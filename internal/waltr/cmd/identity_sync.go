@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewIdentitySyncCommand // assure type compatibility
+
+func NewIdentitySyncCommand(app *app.State) *cobra.Command {
+	var (
+		token    string
+		keycloak util.KeycloakOptions
+		file     string
+		mount    string
+		plan     bool
+		opts     util.IdentitySyncOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync Keycloak groups and members to Vault identity groups and entities",
+		Long: "Read the groups and members of a Keycloak realm and create external identity groups, whose aliases " +
+			"on the OIDC authentication method match the Keycloak groups, with the policies of a mapping file. " +
+			"Members of mapped groups get identity entities with aliases on the OIDC authentication method.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			if file == "" {
+				return fmt.Errorf("the 'file' option is required")
+			}
+
+			mapping, err := util.LoadIdentityMapping(file)
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("mount") {
+				mapping.Mount = mount
+			}
+
+			kc, err := util.NewKeycloak(ctx, keycloak)
+			if err != nil {
+				return err
+			}
+
+			groups, err := kc.Groups(ctx, !opts.SkipEntities)
+			if err != nil {
+				return err
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			changes, err := util.IdentitySyncChanges(app, mapping, groups, opts)
+			if err != nil {
+				return err
+			}
+
+			if len(changes) == 0 {
+				app.Log.Infof("all %d identity groups of realm: %s are up to date", len(mapping.Groups),
+					keycloak.Realm)
+				return nil
+			}
+
+			if err := util.RenderPlan(os.Stdout, changes); err != nil {
+				return err
+			}
+
+			if plan {
+				return nil
+			}
+
+			if err := util.ApplyChanges(app, changes); err != nil {
+				return err
+			}
+
+			app.Log.Infof("successfully applied %d identity change(s) to Vault", len(changes))
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	keycloakFlags(cmd, &keycloak)
+	cmd.PersistentFlags().StringVarP(&file, "file", "f", "",
+		"The YAML file mapping Keycloak groups to the policies of Vault identity groups")
+	cmd.PersistentFlags().StringVarP(&mount, "mount", "m", util.DefaultOIDCMount,
+		"The mount path of the OIDC authentication method. Overrides the mount of the mapping file")
+	cmd.PersistentFlags().StringVar(&opts.UserClaim, "user-claim", "sub",
+		"The user claim of the OIDC role, which names the entity aliases. Either 'sub' or 'preferred_username'")
+	cmd.PersistentFlags().BoolVar(&opts.SkipEntities, "skip-entities", false,
+		"Only sync identity groups and let Vault create entities on the users' first login")
+	cmd.PersistentFlags().BoolVar(&plan, "plan", false, "Only print the changes instead of writing them")
+
+	return cmd
+}
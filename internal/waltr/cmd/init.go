@@ -4,18 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
+	"path/filepath"
 
 	"github.com/fmjstudios/gopskit/internal/waltr/app"
 	cmdutil "github.com/fmjstudios/gopskit/internal/waltr/util"
 	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/credstore"
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/kube"
 	"github.com/fmjstudios/gopskit/pkg/proc"
 	"github.com/fmjstudios/gopskit/pkg/tools"
-	"github.com/hashicorp/hcl/v2/gohcl"
-	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/vault-client-go/schema"
 	"github.com/spf13/cobra"
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -30,6 +30,9 @@ func NewInitCommand(app *app.State) *cobra.Command {
 		shares           int
 		secretFile       string
 		credentialFile   string
+		pgpKeysDir       string
+		rootTokenPGPKey  string
+		keySharesDir     string
 	)
 
 	cmd := &cobra.Command{
@@ -46,21 +49,62 @@ func NewInitCommand(app *app.State) *cobra.Command {
 
 			var needsUnseal, hasCustomConfig bool
 			var vaultNamespace, customConfigName string
-			var vaultLeaderPod *corev1.Pod
 			var creds *cmdutil.Credentials
+			var pgpKeys, holders []string
+			var rootKey string
 
 			// we unseal by default
 			needsUnseal = true
 
-			// look across the entire cluster
-			pods, err := cmdutil.Pods(app, "", label)
+			// every key holder receives exactly one share encrypted with their public key
+			if pgpKeysDir != "" {
+				files, err := cmdutil.PGPKeyFiles(pgpKeysDir)
+				if err != nil {
+					return err
+				}
+
+				pgpKeys, err = cmdutil.ReadPGPKeys(files)
+				if err != nil {
+					return err
+				}
+
+				if cmd.Flags().Changed("shares") && shares != len(pgpKeys) {
+					return fmt.Errorf("found %d PGP public keys in: %s, but %d key shares were requested",
+						len(pgpKeys), pgpKeysDir, shares)
+				}
+				shares = len(pgpKeys)
+
+				holders, err = cmdutil.KeyHolders(files)
+				if err != nil {
+					return err
+				}
+			}
+
+			if threshold > shares {
+				return fmt.Errorf("the key threshold %d exceeds the amount of key shares %d", threshold, shares)
+			}
+
+			if rootTokenPGPKey != "" {
+				keys, err := cmdutil.ReadPGPKeys([]string{rootTokenPGPKey})
+				if err != nil {
+					return err
+				}
+				rootKey = keys[0]
+			}
+
+			if keySharesDir == "" {
+				keySharesDir = filepath.Join(app.Paths.Cache, environment.String(), "key-shares")
+			}
+
+			// look across the entire cluster unless the namespace is passed in
+			pods, err := cmdutil.Pods(app, namespace, label)
 			if err != nil {
 				return fmt.Errorf("could not retrieve Vault pods for label: %s. Error: %v", label, err)
 			}
 
-			// ensure we're using a single namespace, or it's passed in
-			vaultNamespace, err = cmdutil.EnsureNamespace(pods)
-			if err != nil && namespace == "" {
+			// ensure we're using a single namespace
+			vaultNamespace, err = kube.ResolveNamespace(pods, namespace)
+			if err != nil {
 				return fmt.Errorf("found multiple possible Vault pods. the namespace option is unset and %v", err)
 			}
 
@@ -75,7 +119,10 @@ func NewInitCommand(app *app.State) *cobra.Command {
 				}
 
 				// pod has to run first
-				cmdutil.WaitUntilRunning(app, p)
+				if _, err := cmdutil.WaitForRunning(context.Background(), app, p); err != nil {
+					return err
+				}
+
 				if err := cmdutil.DisableAshHistory(app, p); err != nil {
 					app.Log.Errorf("could not disable Ash Shell history for Pod: %s. Error: %v", p.Name, err)
 				}
@@ -101,21 +148,12 @@ func NewInitCommand(app *app.State) *cobra.Command {
 				// auto-unseal cannot be configured without this key
 				val, ok := cm.Data["extraconfig-from-values.hcl"]
 				if ok {
-					var cfg cmdutil.VaultConfig
-					hcp := hclparse.NewParser()
-
-					// ignore the 'example.hcl' it somehow needs a file name
-					f, err := hcp.ParseHCL([]byte(val), "example.hcl")
-					if err != nil {
-						return fmt.Errorf("cannot parse Vault HCL configuration. Error: %v", err)
-					}
-
-					err = gohcl.DecodeBody(f.Body, nil, &cfg)
+					cfg, err := cmdutil.ParseVaultConfig(val, "extraconfig-from-values.hcl")
 					if err != nil {
-						return fmt.Errorf("invalid Vault configuration. Error: %v", err)
+						return err
 					}
 
-					needsUnseal = cfg.Seal == nil
+					needsUnseal = cfg.ActiveSeal() == nil
 					if !needsUnseal {
 						app.Log.Info(
 							"found custom chart configuration enabling Auto-Unseal! skipping unseal steps")
@@ -125,22 +163,11 @@ func NewInitCommand(app *app.State) *cobra.Command {
 				app.Log.Info("found no configuration ConfigMaps for Vault - proceeding with default steps")
 			}
 
-			// wait until the pod is running
-			vaultLeaderPod, err = cmdutil.LeaderPod(app, pods, vaultNamespace, label)
+			// port-forward the (leader), unless Vault is reachable directly
+			cancel, err := cmdutil.Forward(app, cmdutil.ConnectOptions{Namespace: vaultNamespace, Label: label})
 			if err != nil {
 				return err
 			}
-			cmdutil.WaitUntilRunning(app, *vaultLeaderPod)
-
-			// port-forward the (leader)
-			app.Log.Infof("Port-forwarding Vault Leader: %s", vaultLeaderPod.Name)
-			ctx, cancel := context.WithCancel(context.Background())
-			go func() {
-				err := app.Kube.PortForward(ctx, *vaultLeaderPod)
-				if err != nil {
-					panic(err)
-				}
-			}()
 
 			// get current status
 			status, err := app.VaultClient.System.SealStatus(context.Background())
@@ -154,6 +181,8 @@ func NewInitCommand(app *app.State) *cobra.Command {
 				req = schema.InitializeRequest{
 					SecretShares:    int32(shares),
 					SecretThreshold: int32(threshold),
+					PgpKeys:         pgpKeys,
+					RootTokenPgpKey: rootKey,
 				}
 
 				// HA requires the use of recovery keys, Shamir doesn't support it however
@@ -161,6 +190,8 @@ func NewInitCommand(app *app.State) *cobra.Command {
 					req = schema.InitializeRequest{
 						RecoveryShares:    int32(shares),
 						RecoveryThreshold: int32(threshold),
+						RecoveryPgpKeys:   pgpKeys,
+						RootTokenPgpKey:   rootKey,
 					}
 				}
 
@@ -170,30 +201,75 @@ func NewInitCommand(app *app.State) *cobra.Command {
 					return fmt.Errorf("could not initialize Vault instance: %v", err)
 				}
 
-				app.Log.Infof("successfully initialized Vault instance: %s", vaultLeaderPod.Name)
+				app.Log.Info("successfully initialized Vault")
 
 				var data initResponse
-				jsn, err := json.MarshalIndent(initRes, "", "")
+				jsn, err := json.Marshal(initRes)
+				if err == nil {
+					err = json.Unmarshal(jsn, &data)
+				}
 				if err != nil {
-					app.Log.Errorf("could not marshal init request: %v. Vault returned invalid response.", err)
+					defer cancel()
+					app.Log.Errorf("could not decode init response: %v. Printing the raw response", err)
+					fmt.Printf("%+v\n", initRes)
+					return fmt.Errorf("vault returned an invalid init response: %v", err)
 				}
-				if err := json.Unmarshal(jsn, &data); err != nil {
-					app.Log.Errorf("could not unmarshal init response: %v. Vault returned invalid response.",
-						err)
+
+				// HA (auto-unseal) returns recovery keys in place of unseal keys
+				keys, keysBase64 := data.Data.Keys, data.Data.KeysBase64
+				if highAvailability {
+					keys, keysBase64 = data.Data.RecoveryKeys, data.Data.RecoveryKeysBase64
+				}
+
+				// all material Vault returned, which is printed if it cannot be persisted
+				issued := &cmdutil.Credentials{Keys: keys, KeysBase64: keysBase64, Token: data.Data.RootToken}
+
+				// the encrypted shares can only be used by their holders, so they're not stored with the credentials
+				creds = &cmdutil.Credentials{Keys: keys, KeysBase64: keysBase64, Token: data.Data.RootToken}
+				if len(pgpKeys) > 0 {
+					creds.Keys, creds.KeysBase64 = nil, nil
+					app.Log.Infof("key shares are encrypted for the key holders in order: %v", holders)
+				}
+
+				if rootKey != "" {
+					creds.Token = ""
+				}
+
+				// always write credentials to the credential store first
+				if err := cmdutil.WriteCredentials(app, environment, creds); err != nil {
+					defer cancel()
+					return cmdutil.EmitCredentials(app, issued, err)
+				}
+
+				if len(pgpKeys) > 0 {
+					paths, err := cmdutil.WriteKeyShares(keySharesDir, holders, keysBase64)
+					if err != nil {
+						defer cancel()
+						return cmdutil.EmitCredentials(app, issued, err)
+					}
+
+					for i, p := range paths {
+						app.Log.Infof("wrote encrypted key share for: %s to %s", holders[i], p)
+					}
 				}
 
-				// determine Vault credentials
-				creds = &cmdutil.Credentials{
-					Keys:       data.Data.Keys,
-					KeysBase64: data.Data.KeysBase64,
-					Token:      data.Data.RootToken,
+				if rootKey != "" {
+					holder := cmdutil.KeyHolder(rootTokenPGPKey)
+					p := filepath.Join(keySharesDir, fmt.Sprintf("%s.root-token.b64", holder))
+					if err := fs.Write(p, []byte(data.Data.RootToken+"\n")); err != nil {
+						defer cancel()
+						return cmdutil.EmitCredentials(app, issued, fmt.Errorf("could not write encrypted root "+
+							"token to: %s. Error: %v", p, err))
+					}
+
+					app.Log.Infof("wrote encrypted root token for: %s to %s", holder, p)
 				}
 
 				// write Token somewhere where we can retrieve it
-				if secretFile != "" {
+				if secretFile != "" && creds.Token != "" {
 					_, err := tools.AddSecretValue(secretFile, map[string]interface{}{
 						"vault": map[string]interface{}{
-							"token": data.Data.RootToken,
+							"token": creds.Token,
 						},
 					}, false)
 
@@ -201,58 +277,22 @@ func NewInitCommand(app *app.State) *cobra.Command {
 						app.Log.Errorf("could not add secret value to file: %s. Error: %v", secretFile, err)
 					}
 				} else {
-					app.Log.Info("secret-file unset. only writing Vault Token to cache path!")
+					app.Log.Info("secret-file unset or root token encrypted. not writing Vault Token to secret-file!")
 				}
 
-				// write to filesystem
-				// if credentialFile == "" {
-				// 	credentialFile = filepath.Join(app.Paths.Data, "credentials", "vault-credentials.json")
-				// }
-
-				// file, err := os.Open(credentialFile)
-				// if err != nil {
-				// 	app.Log.Error("could not open Vault credential file path: %s", err)
-				// 	app.Log.Debug("printing Vault credentials to prevent loss!")
-				// 	fmt.Printf(`
-				// 	Vault Token: %s
-				// 	Vault Unseal Keys: [%s]
-				// 	`, data.Data.RootToken, strings.Join(data.Data.Keys, ","))
-				// }
-
-				// json, err := json.Marshal(data)
-				// if err != nil {
-				// 	app.Log.Error("could not open marshal Vault data to JSON: %s", err)
-				// 	app.Log.Debug("printing Vault credentials to prevent loss!")
-				// 	fmt.Printf(`
-				// 	Vault Token: %s
-				// 	Vault Unseal Keys: [%s]
-				// 	`, data.Data.RootToken, strings.Join(data.Data.Keys, ","))
-				// }
-
-				// err = fs.WriteFile(file, json)
-
-				// err = app.KV.Set("token", []byte(data.Data.RootToken))
-				// if err != nil {
-				// 	defer cancel()
-				// 	return err
-				// }
-
-				// err = app.KV.Set("unseal-keys", []byte(strings.Join(data.Data.Keys, ",")))
-				// if err != nil {
-				// 	defer cancel()
-				// 	return err
-				// }
-
-				// always write backup json file to cache path
-				err = cmdutil.WriteCredentials(app, environment, creds)
-				if err != nil {
-					defer cancel()
-					return err
+				// additionally write the credentials to a custom file, encrypted like the credential store
+				if credentialFile != "" {
+					store := app.Credentials.WithBackend(&credstore.FileBackend{Dir: filepath.Dir(credentialFile)})
+					if err := store.Write(filepath.Base(credentialFile), creds); err != nil {
+						app.Log.Errorf("could not write Vault credentials to: %s. Error: %v", credentialFile, err)
+					}
 				}
 			} else {
 				app.Log.Info("skipping Vault initialization")
 			}
-			app.Log.Info("Shutting down Port-forward for Vault Leader Pod")
+			if !app.Vault.Direct() {
+				app.Log.Info("Shutting down Port-forward for Vault Leader Pod")
+			}
 			cancel()
 
 			// re-read credentials if we skipped initialization
@@ -264,73 +304,29 @@ func NewInitCommand(app *app.State) *cobra.Command {
 				}
 			}
 
-			if err := app.VaultClient.SetToken(creds.Token); err != nil {
-				return fmt.Errorf("could not set Vault token: %v", err)
+			if creds.Token != "" {
+				if err := app.VaultClient.SetToken(creds.Token); err != nil {
+					return fmt.Errorf("could not set Vault token: %v", err)
+				}
 			}
 
 			// unseal the pod(s) - if auto-unseal is not enabled or if we're not initialized yet
-			if needsUnseal || !status.Data.Initialized {
-				for _, p := range pods {
-					err := func() error {
-						// wait for boot-up
-						for {
-							if p.Status.Phase != corev1.PodRunning {
-								app.Log.Infof("Vault Pod: %s is not running yet - waiting for Pod to start", p.Name)
-								time.Sleep(2500 * time.Millisecond)
-							} else {
-								app.Log.Infof("Vault Pod: %s is running", p.Name)
-								break
-							}
-						}
-
-						ctx, loopCancel := context.WithCancel(context.Background())
-						app.Log.Infof("starting Vault port-forward for pod: %s", p.Name)
-						go func() {
-							err := app.Kube.PortForward(ctx, p)
-							if err != nil {
-								panic(err)
-							}
-						}()
-
-						app.Log.Info("waiting for API's to boot...")
-						time.Sleep(time.Millisecond * 2000)
-
-						// unseal
-						for i := 0; i < threshold; i++ {
-							sealed, err := app.VaultClient.System.SealStatus(context.Background())
-							if err != nil {
-								loopCancel()
-								return fmt.Errorf("could not get Vault status: %v", err)
-							}
-
-							if !sealed.Data.Sealed {
-								app.Log.Infof("skipping Vault unseal iteration: %d - Vault is unsealed", i)
-								continue
-							}
-
-							_, err = app.VaultClient.System.Unseal(context.Background(), schema.UnsealRequest{
-								Key:   creds.Keys[i],
-								Reset: false,
-							})
-
-							if err != nil {
-								loopCancel()
-								return fmt.Errorf("could not unseal Vault instance: %s", p.Name)
-							}
-
-							app.Log.Infof("successfully unsealed Vault instance: %s with key %d of threshold %d",
-								p.Name, i+1, threshold)
-							time.Sleep(time.Millisecond * 250)
-						}
-
-						loopCancel()
-						return nil
-					}()
-
-					if err != nil {
-						return err
+			if (needsUnseal || !status.Data.Initialized) && len(creds.Keys) == 0 {
+				app.Log.Info("Skipping Vault unseal - the unseal keys are PGP-encrypted. Key holders have to " +
+					"unseal Vault with 'waltr unseal --key'")
+			} else if needsUnseal || !status.Data.Initialized {
+				var failed int
+				for _, r := range cmdutil.UnsealPods(app, pods, creds.Keys, cmdutil.DefaultUnsealTimeout) {
+					if r.Err != nil {
+						failed++
+						app.Log.Errorf("failed to unseal Vault Pod: %s. Error: %v", r.Pod, r.Err)
 					}
 				}
+
+				if failed > 0 {
+					return fmt.Errorf("could not unseal %d of %d Vault pods. Retry with 'waltr unseal'",
+						failed, len(pods))
+				}
 			} else {
 				app.Log.Info("Skipping Vault unseal - Auto-Unseal is enabled.")
 			}
@@ -346,6 +342,13 @@ func NewInitCommand(app *app.State) *cobra.Command {
 	cmd.PersistentFlags().StringVar(&secretFile, "secret-file", "",
 		"A Helm secrets plugin-encrypted file to inject the token into")
 	cmd.PersistentFlags().StringVar(&credentialFile, "credential-file", "", "A custom filepath to store Vault credentials obtained via initialization")
+	cmd.PersistentFlags().StringVar(&pgpKeysDir, "pgp-keys-dir", "",
+		"A directory of key holders' PGP public keys named '<holder>.asc', '.gpg', '.pub' or '.b64'. "+
+			"Every unseal key share is encrypted for one key holder")
+	cmd.PersistentFlags().StringVar(&rootTokenPGPKey, "root-token-pgp-key", "",
+		"The path of the operator's PGP public key to encrypt the root token with")
+	cmd.PersistentFlags().StringVar(&keySharesDir, "key-shares-dir", "",
+		"The directory to write PGP-encrypted key shares and root token to")
 
 	return cmd
 }
@@ -357,9 +360,11 @@ type initResponse struct {
 	LeaseDuration int    `json:"lease_duration"`
 	Renewable     bool   `json:"renewable"`
 	Data          struct {
-		Keys       []string `json:"keys"`
-		KeysBase64 []string `json:"keys_base64"`
-		RootToken  string   `json:"root_token"`
+		Keys               []string `json:"keys"`
+		KeysBase64         []string `json:"keys_base64"`
+		RecoveryKeys       []string `json:"recovery_keys"`
+		RecoveryKeysBase64 []string `json:"recovery_keys_base64"`
+		RootToken          string   `json:"root_token"`
 	} `json:"data"`
 	Warnings any `json:"warnings"`
 }
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewKVCommand // assure type compatibility
+
+func NewKVCommand(app *app.State) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kv",
+		Short: "Move secrets in and out of Vault's KV-v2 secrets engine",
+		Long: "Export and import subtrees of Vault's KV-v2 secrets engine to and from SOPS-encrypted files and " +
+			"sync helm-secrets values into it",
+		TraverseChildren: true,
+	}
+
+	// subcommands
+	for _, subc := range KVSubcommands {
+		cmd.AddCommand(subc(app))
+	}
+
+	return cmd
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewKVExportCommand // assure type compatibility
+
+func NewKVExportCommand(app *app.State) *cobra.Command {
+	var (
+		token       string
+		mount       string
+		file        string
+		allVersions bool
+		unencrypted bool
+		force       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export [path]",
+		Short: "Export a KV-v2 subtree to a SOPS-encrypted file",
+		Long: "Export all secrets below the given path (or the entire mount) to a YAML file, which is encrypted " +
+			"with the Helm Secrets Plugin according to the '.sops.yaml' creation rules for the file",
+		Args:             cobra.MaximumNArgs(1),
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var prefix string
+			if len(args) > 0 {
+				prefix = args[0]
+			}
+
+			if file == "" {
+				return fmt.Errorf("the 'file' option is required")
+			}
+
+			if fs.CheckIfExists(file) && !force {
+				return fmt.Errorf("file: %s already exists. Use the 'force' option to overwrite it", file)
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			export, err := util.ExportKV(app, mount, prefix, allVersions)
+			if err != nil {
+				return err
+			}
+
+			if len(export.Secrets) == 0 {
+				app.Log.Warnf("found no secrets below: %s/%s", mount, prefix)
+			}
+
+			if err := util.WriteKVExport(file, export, unencrypted); err != nil {
+				return err
+			}
+
+			if unencrypted {
+				app.Log.Warnf("exported file: %s contains unencrypted secrets", file)
+			}
+
+			app.Log.Infof("successfully exported %d secrets from %s/%s to: %s", len(export.Secrets), mount,
+				prefix, file)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&mount, "mount", "m", util.DefaultKVMount,
+		"The mount path of the KV-v2 secrets engine")
+	cmd.PersistentFlags().StringVarP(&file, "file", "f", "", "The YAML file to export the secrets to")
+	cmd.PersistentFlags().BoolVar(&allVersions, "all-versions", false,
+		"Export every readable version of the secrets instead of the latest one")
+	cmd.PersistentFlags().BoolVar(&unencrypted, "unencrypted", false, "Do not encrypt the exported file")
+	cmd.PersistentFlags().BoolVar(&force, "force", false, "Overwrite an existing file")
+
+	return cmd
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewKVImportCommand // assure type compatibility
+
+func NewKVImportCommand(app *app.State) *cobra.Command {
+	var (
+		token string
+		mount string
+		path  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import a KV-v2 subtree from a SOPS-encrypted file",
+		Long: "Import the secrets of a file written by 'waltr kv export'. New secrets receive all exported " +
+			"versions, existing secrets only receive the latest version if it differs.",
+		Args:             cobra.ExactArgs(1),
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			export, err := util.ReadKVExport(args[0])
+			if err != nil {
+				return err
+			}
+
+			if mount == "" {
+				mount = export.Mount
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			imported, err := util.ImportKV(app, export, mount, path)
+			if err != nil {
+				return err
+			}
+
+			for _, p := range imported {
+				app.Log.Infof("imported KV secret: %s/%s", mount, p)
+			}
+
+			app.Log.Infof("successfully imported %d of %d secrets from: %s", len(imported), len(export.Secrets),
+				args[0])
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&mount, "mount", "m", "",
+		"The mount path of the KV-v2 secrets engine. Defaults to the mount the secrets were exported from")
+	cmd.PersistentFlags().StringVarP(&path, "path", "p", "",
+		"Relocate the secrets below this path instead of the one they were exported from")
+
+	return cmd
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewKVSyncCommand // assure type compatibility
+
+func NewKVSyncCommand(app *app.State) *cobra.Command {
+	var (
+		token      string
+		mount      string
+		secretFile string
+		releases   []string
+		plan       bool
+		prune      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync helm-secrets values into Vault's KV-v2 secrets engine",
+		Long: "Write the 'secrets.<release>' values of a helm-secrets file to '<mount>/data/<release>/...'. " +
+			"The changes are printed with fingerprints instead of values and only differing secrets are written.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if secretFile == "" {
+				return fmt.Errorf("the 'secret-file' option is required")
+			}
+
+			secrets, err := util.ReadReleaseSecrets(secretFile, releases)
+			if err != nil {
+				return err
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			changes, err := util.KVSyncChanges(app, secrets, util.SyncOptions{
+				Mount: mount,
+				Prune: prune,
+			})
+			if err != nil {
+				return err
+			}
+
+			if len(changes) == 0 {
+				app.Log.Infof("all %d secrets within %s are up to date", len(secrets), mount)
+				return nil
+			}
+
+			if err := util.RenderPlan(os.Stdout, changes); err != nil {
+				return err
+			}
+
+			if plan {
+				return nil
+			}
+
+			if err := util.ApplyChanges(app, changes); err != nil {
+				return err
+			}
+
+			app.Log.Infof("successfully synced %d secret(s) to Vault", len(changes))
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&mount, "mount", "m", util.DefaultKVMount,
+		"The mount path of the KV-v2 secrets engine")
+	cmd.PersistentFlags().StringVarP(&secretFile, "secret-file", "f", "",
+		"The (SOPS-encrypted) helm-secrets file to read the 'secrets' values from")
+	cmd.PersistentFlags().StringSliceVarP(&releases, "release", "r", nil,
+		"Only sync the secrets of these releases. Defaults to every release within the file, except for "+
+			"waltr's own 'vault' and 'credentials' keys")
+	cmd.PersistentFlags().BoolVar(&plan, "plan", false, "Only print the changes instead of writing them")
+	cmd.PersistentFlags().BoolVar(&prune, "prune", false,
+		"Remove keys from the secrets in Vault which are not part of the file")
+
+	return cmd
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewOIDCCommand // assure type compatibility
+
+func NewOIDCCommand(app *app.State) *cobra.Command {
+	var (
+		token       string
+		keycloak    util.KeycloakOptions
+		vaultURL    string
+		adminGroups []string
+		adminPolicy string
+	)
+	opts := util.OIDCOptions{
+		Mount:       util.DefaultOIDCMount,
+		ClientID:    "vault",
+		Role:        "default",
+		UserClaim:   "sub",
+		GroupsClaim: util.KeycloakGroupsClaim,
+		Scopes:      []string{"profile", "email"},
+		Policies:    []string{"default"},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "oidc",
+		Short: "Configure Vault's OIDC authentication with Keycloak",
+		Long: "Create Vault's client within a Keycloak realm, configure the OIDC authentication method with its " +
+			"discovery URL, client ID, secret and default role, and map Keycloak groups to external Vault " +
+			"identity groups bound to the admin policy",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			kc, err := util.NewKeycloak(ctx, keycloak)
+			if err != nil {
+				return err
+			}
+
+			opts.RedirectURIs = util.OIDCRedirectURIs(vaultURL, opts.Mount)
+			opts.DiscoveryURL = kc.IssuerURL()
+			opts.ClientSecret, err = kc.EnsureClient(ctx, opts.ClientID, opts.RedirectURIs)
+			if err != nil {
+				return err
+			}
+			app.Log.Infof("configured Keycloak client: %s in realm: %s", opts.ClientID, keycloak.Realm)
+
+			for _, g := range adminGroups {
+				created, err := kc.EnsureGroup(ctx, g)
+				if err != nil {
+					return err
+				}
+
+				if created {
+					app.Log.Infof("created Keycloak group: %s in realm: %s", g, keycloak.Realm)
+				}
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			if err := util.ConfigureOIDC(app, opts); err != nil {
+				return err
+			}
+			app.Log.Infof("configured Vault OIDC authentication method: %s with discovery URL: %s", opts.Mount,
+				opts.DiscoveryURL)
+
+			accessor, err := util.AuthMountAccessor(app, opts.Mount)
+			if err != nil {
+				return err
+			}
+
+			for _, g := range adminGroups {
+				changed, err := util.EnsureExternalGroup(app, util.IdentityGroup{
+					Name:     g,
+					Alias:    g,
+					Policies: []string{adminPolicy},
+				}, accessor)
+				if err != nil {
+					return err
+				}
+
+				if changed {
+					app.Log.Infof("mapped Keycloak group: %s to Vault identity group with policy: %s", g, adminPolicy)
+				} else {
+					app.Log.Infof("Vault identity group: %s is already up to date", g)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	keycloakFlags(cmd, &keycloak)
+	cmd.PersistentFlags().StringVar(&vaultURL, "vault-url", "",
+		"The external URL of Vault, e.g. 'https://vault.example.com'. Allows logins through the Vault UI")
+	cmd.PersistentFlags().StringVarP(&opts.Mount, "mount", "m", opts.Mount,
+		"The mount path of the OIDC authentication method")
+	cmd.PersistentFlags().StringVar(&opts.ClientID, "client-id", opts.ClientID, "The ID of Vault's Keycloak client")
+	cmd.PersistentFlags().StringVar(&opts.Role, "role", opts.Role, "The name of the default OIDC role")
+	cmd.PersistentFlags().StringVar(&opts.UserClaim, "user-claim", opts.UserClaim,
+		"The claim uniquely identifying users")
+	cmd.PersistentFlags().StringSliceVar(&opts.Policies, "policy", opts.Policies,
+		"The policies of tokens issued by the default role")
+	cmd.PersistentFlags().StringSliceVar(&adminGroups, "admin-group", []string{"vault-admins"},
+		"The Keycloak groups whose members are granted the admin policy. Missing groups are created")
+	cmd.PersistentFlags().StringVar(&adminPolicy, "admin-policy", "admin",
+		"The policy attached to the identity groups of the admin groups")
+
+	return cmd
+}
+
+// keycloakFlags adds the flags configuring the connection to Keycloak to cmd
+func keycloakFlags(cmd *cobra.Command, opts *util.KeycloakOptions) {
+	cmd.PersistentFlags().StringVar(&opts.URL, "keycloak-url", "",
+		"The base URL of Keycloak, which must be reachable by waltr, Vault and users, e.g. 'https://sso.example.com'")
+	cmd.PersistentFlags().StringVar(&opts.Realm, "realm", "", "The Keycloak realm Vault users authenticate with")
+	cmd.PersistentFlags().StringVar(&opts.Username, "keycloak-username", "admin", "The Keycloak administrator")
+	cmd.PersistentFlags().StringVar(&opts.Password, "keycloak-password", "",
+		"The password of the Keycloak administrator. Defaults to "+util.KeycloakPasswordEnv)
+	cmd.PersistentFlags().StringVar(&opts.AdminRealm, "keycloak-admin-realm", "master",
+		"The realm of the Keycloak administrator")
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewPKICommand // assure type compatibility
+
+func NewPKICommand(app *app.State) *cobra.Command {
+	var (
+		token      string
+		vaultAddr  string
+		urlsAddr   string
+		issuer     string
+		namespaced bool
+		outputDir  string
+		apply      bool
+	)
+	opts := util.DefaultPKIOptions()
+	role := util.PKIRole{
+		Name:   "default",
+		MaxTTL: "720h",
+	}
+	issuerOpts := util.DefaultIssuerOptions()
+
+	cmd := &cobra.Command{
+		Use:   "pki",
+		Short: "Bootstrap a PKI with a root and intermediate CA",
+		Long: "Enable the root and intermediate PKI secrets engines, generate the root CA, sign the intermediate " +
+			"CA with it, configure the issuing certificate and CRL URLs and create an issuing role for the " +
+			"configured domains. Optionally configure a cert-manager Issuer or ClusterIssuer authenticating " +
+			"with Vault's Kubernetes authentication method.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			label := proc.Must(cmd.Flags().GetString("label"))
+			namespace := proc.Must(cmd.Flags().GetString("namespace"))
+
+			if opts.CommonName == "" {
+				return fmt.Errorf("the common name of the root CA is required")
+			}
+
+			if len(role.AllowedDomains) == 0 {
+				return fmt.Errorf("at least one domain is required for the issuing role: %s", role.Name)
+			}
+
+			if vaultAddr == "" {
+				addr, err := util.ServiceAddress(app, namespace, label)
+				if err != nil {
+					return err
+				}
+
+				vaultAddr = addr
+			}
+
+			// the issuing certificate and CRL URLs are embedded into certificates and fetched by clients,
+			// which usually live outside the cluster
+			switch {
+			case urlsAddr != "":
+			case app.Vault.Direct():
+				urlsAddr = app.Vault.Address
+			default:
+				urlsAddr = vaultAddr
+				app.Log.Warnf("issuing certificate and CRL URLs point to in-cluster address: %s, which clients "+
+					"outside the cluster cannot reach. Pass the 'urls-address' option to change them", vaultAddr)
+			}
+			opts.Address = urlsAddr
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			if err := util.ConfigurePKI(app, opts); err != nil {
+				return err
+			}
+
+			if err := util.WritePKIRole(app, opts.IntermediateMount, role); err != nil {
+				return err
+			}
+			app.Log.Infof("configured PKI role: %s for domains: %v", role.Name, role.AllowedDomains)
+
+			if issuer == "" {
+				return nil
+			}
+
+			issuerOpts.Name = issuer
+			issuerOpts.Cluster = !namespaced
+			issuerOpts.Server = vaultAddr
+			issuerOpts.PKIMount = opts.IntermediateMount
+			issuerOpts.Role = role.Name
+
+			// the issuer authenticates with a Service Account named like itself, unless given explicitly
+			if issuerOpts.ServiceAccount == "" {
+				issuerOpts.ServiceAccount = issuer
+			}
+
+			if err := util.ConfigureIssuerAuth(app, issuerOpts); err != nil {
+				return err
+			}
+			app.Log.Infof("configured Vault policy and Kubernetes Auth role: %s for %s: %s", issuerOpts.Name,
+				issuerOpts.Kind(), issuer)
+
+			objs := util.IssuerManifests(issuerOpts)
+			switch {
+			case apply:
+				if err := util.ApplyManifests(app, objs); err != nil {
+					return err
+				}
+
+				app.Log.Infof("successfully applied %s: %s", issuerOpts.Kind(), issuer)
+			case outputDir != "":
+				files, err := util.WriteManifestFiles(outputDir, objs)
+				if err != nil {
+					return err
+				}
+
+				for _, f := range files {
+					app.Log.Infof("wrote manifest: %s", f)
+				}
+			default:
+				return util.WriteManifests(os.Stdout, objs)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVar(&opts.CommonName, "common-name", "", "The common name of the root CA")
+	cmd.PersistentFlags().StringVar(&opts.RootMount, "root-mount", opts.RootMount,
+		"The mount path of the root PKI secrets engine")
+	cmd.PersistentFlags().StringVar(&opts.IntermediateMount, "intermediate-mount", opts.IntermediateMount,
+		"The mount path of the intermediate PKI secrets engine")
+	cmd.PersistentFlags().StringVar(&opts.RootTTL, "root-ttl", opts.RootTTL, "The validity of the root CA")
+	cmd.PersistentFlags().StringVar(&opts.IntermediateTTL, "intermediate-ttl", opts.IntermediateTTL,
+		"The validity of the intermediate CA")
+	cmd.PersistentFlags().StringVar(&opts.KeyType, "key-type", opts.KeyType, "The key type of both CAs")
+	cmd.PersistentFlags().IntVar(&opts.KeyBits, "key-bits", opts.KeyBits, "The key size of both CAs")
+	cmd.PersistentFlags().StringVar(&vaultAddr, "issuer-vault-address", "",
+		"The in-cluster address of Vault used by the issuer. Defaults to the Service of the discovered Vault "+
+			"release")
+	cmd.PersistentFlags().StringVar(&urlsAddr, "urls-address", "",
+		"The externally reachable address of Vault the issuing certificate and CRL URLs point to. Defaults to "+
+			"the address Vault is connected to directly, if any, and otherwise to the issuer's in-cluster address")
+	cmd.PersistentFlags().StringVar(&role.Name, "role", role.Name, "The name of the issuing role")
+	cmd.PersistentFlags().StringSliceVarP(&role.AllowedDomains, "domain", "d", nil,
+		"The domains the issuing role may issue certificates for")
+	cmd.PersistentFlags().BoolVar(&role.AllowSubdomains, "allow-subdomains", true,
+		"Allow certificates for subdomains of the domains")
+	cmd.PersistentFlags().BoolVar(&role.AllowBareDomains, "allow-bare-domains", false,
+		"Allow certificates for the domains themselves")
+	cmd.PersistentFlags().StringVar(&role.MaxTTL, "max-ttl", role.MaxTTL,
+		"The maximum validity of certificates issued by the role")
+	cmd.PersistentFlags().StringVar(&issuer, "issuer", "",
+		"Configure a cert-manager ClusterIssuer with this name. Nothing is configured if unset")
+	cmd.PersistentFlags().BoolVar(&namespaced, "namespaced", false,
+		"Create a namespaced Issuer within the issuer namespace instead of a ClusterIssuer")
+	cmd.PersistentFlags().StringVar(&issuerOpts.Namespace, "issuer-namespace", issuerOpts.Namespace,
+		"The namespace of the issuer's Service Account. ClusterIssuers require cert-manager's cluster "+
+			"resource namespace")
+	cmd.PersistentFlags().StringVar(&issuerOpts.ServiceAccount, "issuer-service-account", "",
+		"The Service Account the issuer authenticates with. Defaults to the name of the issuer")
+	cmd.PersistentFlags().StringVar(&issuerOpts.CABundleSecret, "ca-bundle-secret", "",
+		"A Secret within the issuer namespace holding Vault's CA certificate as 'ca.crt'")
+	cmd.PersistentFlags().StringVar(&issuerOpts.AuthMount, "auth-mount", issuerOpts.AuthMount,
+		"The mount path of the Kubernetes authentication method")
+	cmd.PersistentFlags().StringVar(&outputDir, "output-dir", "",
+		"Write every issuer manifest to '<output-dir>/<namespace>/<kind>-<name>.yaml' instead of stdout")
+	cmd.PersistentFlags().BoolVar(&apply, "apply", false, "Create or update the issuer resources within the cluster")
+
+	return cmd
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewPlanCommand // assure type compatibility
+
+func NewPlanCommand(app *app.State) *cobra.Command {
+	var (
+		token string
+		file  string
+		prune bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the changes 'apply' would make to Vault",
+		Long: "Compare the live Vault with a declarative YAML or HCL configuration and print the resources " +
+			"which would be created, updated or deleted, including policy body changes",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec, err := loadSpec(app, file)
+			if err != nil {
+				return err
+			}
+
+			changes, cancel, err := specChanges(app, cmd, token, spec, prune)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			return util.RenderPlan(os.Stdout, changes)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&file, "file", "f", "", "The YAML or HCL file describing the Vault configuration")
+	cmd.PersistentFlags().BoolVar(&prune, "prune", false,
+		"Include deletions of policies, roles and authentication methods which are not part of the configuration")
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+
+	return cmd
+}
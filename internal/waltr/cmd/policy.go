@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewPolicyCommand // assure type compatibility
+
+func NewPolicyCommand(app *app.State) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:              "policy",
+		Short:            "Inspect Vault's ACL policies",
+		Long:             "Evaluate the effective capabilities of ACL policies and tokens and lint policy files",
+		TraverseChildren: true,
+	}
+
+	// subcommands
+	for _, subc := range PolicySubcommands {
+		cmd.AddCommand(subc(app))
+	}
+
+	return cmd
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewPolicyTestCommand // assure type compatibility
+
+func NewPolicyTestCommand(app *app.State) *cobra.Command {
+	var (
+		token     string
+		testToken string
+		output    string
+		failOn    string
+		policies  []string
+		files     []string
+		builtin   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test [path...]",
+		Short: "Evaluate ACL policies against paths and lint policy files",
+		Long: "Report the effective capabilities of ACL policies, a token or, by default, the token waltr is " +
+			"authenticated with on the given paths. Policies are evaluated with a short-lived token holding only " +
+			"them. Policy files and the built-in policies are checked locally for unknown capabilities, overly " +
+			"broad wildcard paths and the use of 'sudo'. Exits non-zero if any finding reaches the 'fail-on' " +
+			"severity.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			threshold, err := util.SeverityFromString(failOn)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 && len(files) == 0 && !builtin {
+				return fmt.Errorf("nothing to test. Pass paths to evaluate, policy files or the 'builtin' option")
+			}
+
+			if len(policies) > 0 && testToken != "" {
+				return fmt.Errorf("the 'policy' and 'test-token' options are mutually exclusive")
+			}
+
+			if len(args) > 0 {
+				cancel, err := connect(app, cmd, token)
+				if err != nil {
+					return err
+				}
+				defer cancel()
+
+				var caps []util.PathCapabilities
+				if len(policies) > 0 {
+					caps, err = util.TestPolicyCapabilities(app, policies, args)
+				} else {
+					caps, err = util.TestCapabilities(app, testToken, args)
+				}
+				if err != nil {
+					return err
+				}
+
+				if err := util.RenderCapabilities(os.Stdout, output, caps); err != nil {
+					return err
+				}
+			}
+
+			if len(files) == 0 && !builtin {
+				return nil
+			}
+
+			lint, err := util.LoadPolicyFiles(files)
+			if err != nil {
+				return err
+			}
+
+			if builtin {
+				for name, body := range util.ConfigAclPolicies {
+					if _, ok := lint[name]; !ok {
+						lint[name] = body
+					}
+				}
+			}
+
+			findings := util.LintPolicies(lint)
+			if err := util.RenderFindings(os.Stdout, output, findings); err != nil {
+				return err
+			}
+
+			if n := util.CountFindings(findings, threshold); n > 0 {
+				return fmt.Errorf("found %d issue(s) with severity %s or higher", n, threshold)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVar(&testToken, "test-token", "",
+		"The token whose capabilities are evaluated. Defaults to the token waltr is authenticated with")
+	cmd.PersistentFlags().StringSliceVarP(&policies, "policy", "p", nil,
+		"The ACL policies within Vault whose combined capabilities are evaluated")
+	cmd.PersistentFlags().StringSliceVarP(&files, "file", "f", nil,
+		"The HCL or JSON ACL policy files to lint. They're named after the file without extension")
+	cmd.PersistentFlags().BoolVar(&builtin, "builtin", false,
+		"Lint the built-in ACL policies 'waltr configure' writes, unless overridden by a file of the same name")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+	cmd.PersistentFlags().StringVar(&failOn, "fail-on", string(util.SeverityCritical),
+		fmt.Sprintf("The lowest severity of lint findings failing the command. One of: %v", util.Severities))
+
+	return cmd
+}
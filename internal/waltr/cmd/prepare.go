@@ -1,24 +1,113 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
 	"github.com/spf13/cobra"
 )
 
 var _ app.CLIOpt = NewPrepareCommand // assure type compatibility
 
 func NewPrepareCommand(app *app.State) *cobra.Command {
+	var (
+		token     string
+		file      string
+		all       bool
+		plan      bool
+		kvMount   string
+		overrides util.ReleaseProfile
+	)
+
 	cmd := &cobra.Command{
-		Use:              "prepare",
-		Short:            "Prepare Vault for various applications",
-		Long:             "Prepare Vault for various applications like GitLab, AWX or Keycloak",
+		Use:   "prepare [release...]",
+		Short: "Prepare Vault for various applications",
+		Long: "Prepare Vault for releases like GitLab, AWX or Keycloak with their ACL policy, a Kubernetes Auth " +
+			"role named like the release and generated credentials. Service Accounts, namespaces, audience, TTLs " +
+			"and extra policies default to the release's name and can be configured with a profile file or flags.",
 		TraverseChildren: true,
-	}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles := make(map[string]util.ReleaseProfile)
+			if file != "" {
+				p, err := util.LoadReleaseProfiles(file)
+				if err != nil {
+					return err
+				}
+
+				profiles = p
+			}
+
+			releases := args
+			if all {
+				releases = util.ProfileReleases(profiles)
+			}
+
+			if len(releases) == 0 {
+				return fmt.Errorf("no releases given. Pass release names or the 'all' option")
+			}
+
+			var resolved []util.ReleaseProfile
+			for _, r := range releases {
+				p := util.ReleaseProfileFor(r, profiles)
+				p.Merge(overrides)
+				resolved = append(resolved, p)
+			}
 
-	// subcommands
-	for _, subc := range PrepareSubcommands {
-		cmd.AddCommand(subc(app))
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			changes, err := util.PrepareChanges(app, resolved, kvMount)
+			if err != nil {
+				return err
+			}
+
+			if len(changes) == 0 {
+				app.Log.Infof("Vault is prepared for %d release(s)", len(resolved))
+				return nil
+			}
+
+			if err := util.RenderPlan(os.Stdout, changes); err != nil {
+				return err
+			}
+
+			if plan {
+				return nil
+			}
+
+			if err := util.ApplyChanges(app, changes); err != nil {
+				return err
+			}
+
+			app.Log.Infof("successfully prepared Vault for %d release(s)", len(resolved))
+			return nil
+		},
 	}
 
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&file, "file", "f", "", "A YAML file with a list of release profiles")
+	cmd.PersistentFlags().BoolVar(&all, "all", false,
+		"Prepare all built-in releases and releases of the profile file")
+	cmd.PersistentFlags().BoolVar(&plan, "plan", false, "Only print the changes instead of applying them")
+	cmd.PersistentFlags().StringVar(&kvMount, "kv-mount", util.DefaultKVMount,
+		"The mount path of the KV-v2 secrets engine storing generated credentials")
+	cmd.PersistentFlags().StringVarP(&overrides.Mount, "mount", "m", "",
+		"The mount path of the Kubernetes authentication method")
+	cmd.PersistentFlags().StringSliceVar(&overrides.ServiceAccounts, "service-account", nil,
+		"The Service Accounts bound to the role. Defaults to the release name")
+	cmd.PersistentFlags().StringSliceVar(&overrides.Namespaces, "release-namespace", nil,
+		"The namespaces of the bound Service Accounts. Defaults to the release name")
+	cmd.PersistentFlags().StringVar(&overrides.Audience, "audience", "",
+		"The audience of the bound Service Account tokens. Defaults to 'vault'")
+	cmd.PersistentFlags().StringVar(&overrides.TokenPeriod, "token-period", "",
+		"The period of the issued Vault tokens. Defaults to '24h'")
+	cmd.PersistentFlags().StringVar(&overrides.TokenTTL, "token-ttl", "", "The TTL of the issued Vault tokens")
+	cmd.PersistentFlags().StringSliceVar(&overrides.Policies, "policy", nil,
+		"Extra policies granted in addition to the release policy")
+
 	return cmd
 }
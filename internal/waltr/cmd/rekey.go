@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/credstore"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewRekeyCommand // assure type compatibility
+
+func NewRekeyCommand(app *app.State) *cobra.Command {
+	var (
+		token     string
+		keys      []string
+		pgpKeys   []string
+		pgpDir    string
+		outputDir string
+		shares    int
+		threshold int
+		backup    bool
+		recovery  bool
+		reset     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rekey",
+		Short: "Rotate Vault's unseal keys",
+		Long: "Generate new unseal (or recovery) key shares with a new share count and threshold. If PGP public " +
+			"keys are given, every new share is encrypted for and written to a file for one key holder.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envF := proc.Must(cmd.Flags().GetString("environment"))
+			environment := proc.Must(core.EnvFromString(envF))
+
+			creds, keys, err := credentialKeys(app, environment, keys)
+			if err != nil {
+				return err
+			}
+
+			// every key holder receives exactly one share encrypted with their public key
+			if pgpDir != "" {
+				files, err := util.PGPKeyFiles(pgpDir)
+				if err != nil {
+					return err
+				}
+				pgpKeys = append(pgpKeys, files...)
+			}
+
+			if len(pgpKeys) > 0 {
+				if cmd.Flags().Changed("shares") && shares != len(pgpKeys) {
+					return fmt.Errorf("found %d PGP public keys, but %d key shares were requested", len(pgpKeys),
+						shares)
+				}
+				shares = len(pgpKeys)
+			}
+
+			holders, err := util.KeyHolders(pgpKeys)
+			if err != nil {
+				return err
+			}
+
+			encodedKeys, err := util.ReadPGPKeys(pgpKeys)
+			if err != nil {
+				return err
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			rekeyed, err := util.Rekey(app, keys, util.RekeyOptions{
+				Shares:    shares,
+				Threshold: threshold,
+				PGPKeys:   encodedKeys,
+				Backup:    backup,
+				Recovery:  recovery,
+				Reset:     reset,
+			})
+			if err != nil {
+				return err
+			}
+			app.Log.Infof("successfully rekeyed Vault into %d key shares with a threshold of %d", shares, threshold)
+
+			// the previous keys are invalid now, so the new ones are persisted before anything else
+			creds.Keys, creds.KeysBase64 = rekeyed.Keys, rekeyed.KeysBase64
+
+			// the encrypted shares can only be used by their holders, so they're not stored with the credentials
+			if len(pgpKeys) > 0 {
+				creds.Keys, creds.KeysBase64 = nil, nil
+			}
+
+			if err := util.WriteCredentials(app, environment, creds); err != nil {
+				return util.EmitCredentials(app, rekeyed, err)
+			}
+
+			if len(pgpKeys) == 0 {
+				return nil
+			}
+
+			if outputDir == "" {
+				outputDir = filepath.Join(app.Paths.Cache, environment.String(), "key-shares")
+			}
+
+			paths, err := util.WriteKeyShares(outputDir, holders, rekeyed.KeysBase64)
+			if err != nil {
+				app.Log.Infof("key shares are encrypted for the key holders in order: %v", holders)
+				return util.EmitCredentials(app, rekeyed, err)
+			}
+
+			for i, p := range paths {
+				app.Log.Infof("wrote encrypted key share for: %s to %s", holders[i], p)
+			}
+
+			app.Log.Warn("key shares are PGP-encrypted - 'waltr unseal' requires the key holders' " +
+				"decrypted shares from now on")
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringSliceVar(&keys, "key", nil,
+		"The current unseal (or recovery) keys. Defaults to the keys in the credentials store")
+	cmd.PersistentFlags().StringSliceVar(&pgpKeys, "pgp-key", nil,
+		"Paths to PGP public keys to encrypt the new key shares with, one per share")
+	cmd.PersistentFlags().StringVar(&pgpDir, "pgp-keys-dir", "",
+		"A directory of key holders' PGP public keys named '<holder>.asc', '.gpg', '.pub' or '.b64'. "+
+			"Every new key share is encrypted for one key holder")
+	cmd.PersistentFlags().StringVar(&outputDir, "output-dir", "",
+		"The directory to write PGP-encrypted key shares to")
+	cmd.PersistentFlags().IntVar(&shares, "shares", 7,
+		"The amount of total key shares to generate. Defaults to the number of PGP public keys, if given")
+	cmd.PersistentFlags().IntVar(&threshold, "threshold", 4, "The threshold of key shares required to unlock Vault")
+	cmd.PersistentFlags().BoolVar(&backup, "backup", false, "Back up the PGP-encrypted key shares within Vault")
+	cmd.PersistentFlags().BoolVar(&recovery, "recovery", false,
+		"Rekey the recovery keys of an auto-unsealed Vault instead of its unseal keys")
+	cmd.PersistentFlags().BoolVar(&reset, "reset", false, "Cancel a rekey operation already in progress")
+
+	return cmd
+}
+
+// credentialKeys returns the given unseal keys or falls back to the keys within the credentials store.
+// The stored credentials are returned as well, since they're rewritten afterwards. Only if the store holds
+// no credentials at all and keys are given, empty credentials are returned. Any other error aborts, so
+// that unreadable credentials are never overwritten.
+func credentialKeys(app *app.State, env core.Environment, keys []string) (*util.Credentials, []string, error) {
+	creds, err := util.ReadCredentials(app, env)
+	if len(keys) > 0 {
+		if errors.Is(err, credstore.ErrNotFound) {
+			return &util.Credentials{}, keys, nil
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read the stored Vault credentials, which would be "+
+				"overwritten: %v", err)
+		}
+
+		return creds, keys, nil
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("key option is unset and could not read Vault credentials: %v", err)
+	}
+
+	if len(creds.Keys) == 0 {
+		return nil, nil, fmt.Errorf("the credentials store doesn't contain any unseal keys. Pass them with --key")
+	}
+
+	return creds, creds.Keys, nil
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewSealCommand // assure type compatibility
+
+func NewSealCommand(app *app.State) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:              "seal",
+		Short:            "Manage Vault's seal",
+		Long:             "Migrate Vault between the Shamir seal and auto-unseal seals like transit or cloud KMS",
+		TraverseChildren: true,
+	}
+
+	// subcommands
+	for _, subc := range SealSubcommands {
+		cmd.AddCommand(subc(app))
+	}
+
+	return cmd
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewSealMigrateCommand // assure type compatibility
+
+func NewSealMigrateCommand(app *app.State) *cobra.Command {
+	var (
+		token   string
+		keys    []string
+		output  string
+		timeout time.Duration
+		plan    bool
+		verify  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate Vault to the seal of its configuration",
+		Long: "Migrate Vault from its current seal to the seal configured in its ConfigMap, e.g. from Shamir to " +
+			"transit or cloud KMS auto-unseal and back. The configuration is validated first, then the standby " +
+			"Pods and finally the leader are restarted one by one and unsealed with migrate=true. Afterwards the " +
+			"(recovery) keys are verified by generating and revoking a root token. Update the seal stanza " +
+			"through the chart's values before running the migration.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envF := proc.Must(cmd.Flags().GetString("environment"))
+			label := proc.Must(cmd.Flags().GetString("label"))
+			namespace := proc.Must(cmd.Flags().GetString("namespace"))
+			environment := proc.Must(core.EnvFromString(envF))
+
+			_, keys, err := credentialKeys(app, environment, keys)
+			if err != nil {
+				return err
+			}
+
+			pods, err := util.Pods(app, namespace, label)
+			if err != nil {
+				return fmt.Errorf("could not retrieve Vault pods for label: %s. Error: %v", label, err)
+			}
+
+			if len(pods) == 0 {
+				return fmt.Errorf("could not find any Vault pods for label: %s", label)
+			}
+
+			// every Pod is port-forwarded on its own, even if Vault is reachable directly
+			if err := util.ConfigureVaultCA(app, pods[0].Namespace); err != nil {
+				return err
+			}
+
+			migration, err := util.PlanSealMigration(app, pods, timeout)
+			if err != nil {
+				return err
+			}
+
+			for _, w := range migration.Warnings {
+				app.Log.Warn(w)
+			}
+
+			if !migration.Required() {
+				app.Log.Infof("Vault already uses the configured seal: %s", migration.To)
+			} else {
+				if err := util.RenderSealMigration(os.Stdout, output, migration); err != nil {
+					return err
+				}
+
+				if plan {
+					return nil
+				}
+
+				for _, p := range migration.Pods {
+					if err := util.MigratePod(context.Background(), app, p, keys, timeout); err != nil {
+						return fmt.Errorf("%v. Fix the Pod and run the migration again", err)
+					}
+				}
+			}
+
+			if !verify || plan {
+				return nil
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			if err := util.VerifySeal(app, migration.To, keys); err != nil {
+				return err
+			}
+
+			app.Log.Infof("verified seal: %s and its keys", migration.To)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringSliceVar(&keys, "key", nil,
+		"The (decrypted) unseal or recovery keys. Defaults to the keys in the credentials store")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+	cmd.PersistentFlags().DurationVar(&timeout, "timeout", util.DefaultUnsealTimeout,
+		"The time a single Pod may take to restart and become unsealed")
+	cmd.PersistentFlags().BoolVar(&plan, "plan", false, "Only validate and print the migration steps")
+	cmd.PersistentFlags().BoolVar(&verify, "verify", true,
+		"Verify the seal and its keys by generating and revoking a root token afterwards")
+
+	return cmd
+}
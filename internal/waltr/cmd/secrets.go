@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewSecretsCommand // assure type compatibility
+
+func NewSecretsCommand(app *app.State) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:              "secrets",
+		Short:            "Manage release secrets within Vault",
+		Long:             "Generate release secrets from Vault's password policies",
+		TraverseChildren: true,
+	}
+
+	// subcommands
+	for _, subc := range SecretsSubcommands {
+		cmd.AddCommand(subc(app))
+	}
+
+	return cmd
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewSecretsGenerateCommand // assure type compatibility
+
+func NewSecretsGenerateCommand(app *app.State) *cobra.Command {
+	var (
+		token    string
+		mount    string
+		specFile string
+		output   string
+		releases []string
+		plan     bool
+		rotate   bool
+		show     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate release secrets from Vault's password policies",
+		Long: "Generate the keys declared within a secrets spec with Vault's password policies and write them to " +
+			"'<mount>/data/<release>/...'. Existing keys are kept unless 'rotate' is set, while static values " +
+			"always overwrite differing ones. Generated values never leave Vault unless 'show' is set.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if specFile == "" {
+				return fmt.Errorf("the 'file' option is required")
+			}
+
+			spec, err := util.LoadSecretsSpec(specFile)
+			if err != nil {
+				return err
+			}
+
+			secrets, err := spec.Select(releases)
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("mount") {
+				spec.Mount = mount
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			var generated []util.GeneratedValue
+			opts := util.GenerateOptions{Mount: spec.Mount, Rotate: rotate}
+			if show {
+				opts.Record = func(v util.GeneratedValue) { generated = append(generated, v) }
+			}
+
+			changes, err := util.GenerateSecretsChanges(app, secrets, opts)
+			if err != nil {
+				return err
+			}
+
+			if len(changes) == 0 {
+				app.Log.Infof("all %d generated secrets within %s are up to date", len(secrets), spec.Mount)
+				return nil
+			}
+
+			if err := util.RenderPlan(os.Stdout, changes); err != nil {
+				return err
+			}
+
+			if plan {
+				return nil
+			}
+
+			if err := util.ApplyChanges(app, changes); err != nil {
+				return err
+			}
+
+			app.Log.Infof("successfully wrote %d generated secret(s) to Vault", len(changes))
+			if !show {
+				return nil
+			}
+
+			return util.RenderGeneratedValues(os.Stdout, output, generated)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&mount, "mount", "m", util.DefaultKVMount,
+		"The mount path of the KV-v2 secrets engine. Overrides the mount of the spec")
+	cmd.PersistentFlags().StringVarP(&specFile, "file", "f", "",
+		"The (SOPS-encrypted) secrets spec declaring the secrets, keys and password policies")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format of the generated values. One of: %v", util.Outputs))
+	cmd.PersistentFlags().StringSliceVarP(&releases, "release", "r", nil,
+		"Only generate the secrets of these releases. Defaults to every release within the spec")
+	cmd.PersistentFlags().BoolVar(&plan, "plan", false, "Only print the changes instead of writing them")
+	cmd.PersistentFlags().BoolVar(&rotate, "rotate", false, "Regenerate keys which already exist within Vault")
+	cmd.PersistentFlags().BoolVar(&show, "show", false, "Print the generated values once they're written")
+
+	return cmd
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/spf13/cobra"
+)
+
+// SnapshotPassphraseEnv is the environment variable the snapshot passphrase is read from if the
+// 'passphrase' flag is unset
+const SnapshotPassphraseEnv = "WALTR_SNAPSHOT_PASSPHRASE"
+
+var _ app.CLIOpt = NewSnapshotCommand // assure type compatibility
+
+func NewSnapshotCommand(app *app.State) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:              "snapshot",
+		Short:            "Back up and restore Vault's integrated storage",
+		Long:             "Save, restore and list Raft snapshots of Vault's integrated storage",
+		TraverseChildren: true,
+	}
+
+	// subcommands
+	for _, subc := range SnapshotSubcommands {
+		cmd.AddCommand(subc(app))
+	}
+
+	return cmd
+}
+
+// snapshotPassphrase returns the passphrase flag's value or falls back to SnapshotPassphraseEnv
+func snapshotPassphrase(passphrase string) string {
+	if passphrase != "" {
+		return passphrase
+	}
+
+	return os.Getenv(SnapshotPassphraseEnv)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewSnapshotListCommand // assure type compatibility
+
+func NewSnapshotListCommand(app *app.State) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:              "list",
+		Short:            "List Raft snapshots",
+		Aliases:          []string{"ls"},
+		Long:             "List the Raft snapshots stored in the data directory, newest first",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envF := proc.Must(cmd.Flags().GetString("environment"))
+			environment := proc.Must(core.EnvFromString(envF))
+
+			snapshots, err := util.Snapshots(app, environment)
+			if err != nil {
+				return err
+			}
+
+			return util.RenderSnapshots(os.Stdout, output, snapshots)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+
+	return cmd
+}
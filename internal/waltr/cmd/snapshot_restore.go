@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewSnapshotRestoreCommand // assure type compatibility
+
+func NewSnapshotRestoreCommand(app *app.State) *cobra.Command {
+	var (
+		token      string
+		passphrase string
+		force      bool
+		yes        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "restore [snapshot]",
+		Short: "Restore a Raft snapshot",
+		Long: "Restore a Raft snapshot into Vault's integrated storage. The snapshot is either a path or the name " +
+			"of a snapshot within the data directory. Without arguments the newest snapshot is restored. Restoring " +
+			"replaces Vault's entire state and therefore has to be confirmed.",
+		Args:             cobra.MaximumNArgs(1),
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envF := proc.Must(cmd.Flags().GetString("environment"))
+			environment := proc.Must(core.EnvFromString(envF))
+
+			var path string
+			switch {
+			case len(args) == 0:
+				snapshots, err := util.Snapshots(app, environment)
+				if err != nil {
+					return err
+				}
+
+				if len(snapshots) == 0 {
+					return fmt.Errorf("could not find any snapshots in: %s", util.SnapshotDir(app, environment))
+				}
+				path = snapshots[0].Path
+			case fs.CheckIfExists(args[0]):
+				path = args[0]
+			default:
+				path = filepath.Join(util.SnapshotDir(app, environment), args[0])
+			}
+
+			if !yes {
+				ok, err := confirm(cmd, fmt.Sprintf("Replace Vault's entire state with Raft snapshot: %s?", path))
+				if err != nil {
+					return fmt.Errorf("%v. Pass the 'yes' option to restore without confirmation", err)
+				}
+
+				if !ok {
+					app.Log.Infof("did not restore Raft snapshot: %s", path)
+					return nil
+				}
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			app.Log.Infof("restoring Raft snapshot: %s", path)
+			if err := util.RestoreSnapshot(app, path, snapshotPassphrase(passphrase), force); err != nil {
+				return err
+			}
+
+			app.Log.Infof("successfully restored Raft snapshot: %s", path)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVar(&passphrase, "passphrase", "", "The passphrase the snapshot was encrypted with")
+	cmd.PersistentFlags().BoolVar(&force, "force", false,
+		"Restore snapshots of a different Vault cluster, i.e. with different unseal keys")
+	cmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "Restore the snapshot without confirmation")
+
+	return cmd
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewSnapshotSaveCommand // assure type compatibility
+
+func NewSnapshotSaveCommand(app *app.State) *cobra.Command {
+	var (
+		token      string
+		file       string
+		passphrase string
+		retain     int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "save",
+		Short: "Save a Raft snapshot",
+		Long: fmt.Sprintf("Take a snapshot of Vault's integrated storage through the leader and write it to the "+
+			"data directory. Snapshots are encrypted if a passphrase is given or %s is set.", SnapshotPassphraseEnv),
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envF := proc.Must(cmd.Flags().GetString("environment"))
+			environment := proc.Must(core.EnvFromString(envF))
+			passphrase = snapshotPassphrase(passphrase)
+
+			if file == "" {
+				file = util.SnapshotPath(app, environment, passphrase != "")
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			if err := util.SaveSnapshot(app, file, passphrase); err != nil {
+				return err
+			}
+			app.Log.Infof("successfully saved Raft snapshot to: %s", file)
+
+			if retain > 0 {
+				removed, err := util.PruneSnapshots(filepath.Dir(file), retain)
+				if err != nil {
+					return err
+				}
+
+				for _, r := range removed {
+					app.Log.Infof("removed Raft snapshot: %s exceeding retention count of %d", r, retain)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&file, "file", "f", "",
+		"A custom path to write the snapshot to. Defaults to a timestamped file within the data directory")
+	cmd.PersistentFlags().StringVar(&passphrase, "passphrase", "", "A passphrase to encrypt the snapshot with")
+	cmd.PersistentFlags().IntVar(&retain, "retain", 0,
+		"The amount of snapshots created by waltr to keep in the directory the snapshot is written to. "+
+			"Other files are never removed. 0 keeps all snapshots")
+
+	return cmd
+}
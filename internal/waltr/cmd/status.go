@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewStatusCommand // assure type compatibility
+
+func NewStatusCommand(app *app.State) *cobra.Command {
+	var (
+		output  string
+		token   string
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Report the health of all Vault Pods",
+		Long: "Report seal status, HA leadership, Raft peer role, version and initialization of every " +
+			"Vault Pod in the cluster",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envF := proc.Must(cmd.Flags().GetString("environment"))
+			label := proc.Must(cmd.Flags().GetString("label"))
+			namespace := proc.Must(cmd.Flags().GetString("namespace"))
+			environment := proc.Must(core.EnvFromString(envF))
+
+			pods, err := util.Pods(app, namespace, label)
+			if err != nil {
+				return fmt.Errorf("could not retrieve Vault pods for label: %s. Error: %v", label, err)
+			}
+
+			if len(pods) == 0 {
+				return fmt.Errorf("could not find any Vault pods for label: %s", label)
+			}
+
+			// every Pod is port-forwarded on its own, even if Vault is reachable directly
+			if err := util.ConfigureVaultCA(app, pods[0].Namespace); err != nil {
+				return err
+			}
+
+			// the Raft configuration requires a token, everything else is unauthenticated
+			if token == "" {
+				creds, err := util.ReadCredentials(app, environment)
+				if err != nil {
+					app.Log.Debugf("could not read Vault credentials - omitting Raft peer roles. Error: %v", err)
+				} else {
+					token = creds.Token
+				}
+			}
+
+			statuses := util.Status(app, pods, token, timeout)
+			return util.RenderStatus(os.Stdout, output, statuses)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault token used to read the Raft configuration")
+	cmd.PersistentFlags().DurationVar(&timeout, "timeout", util.DefaultStatusTimeout,
+		"The time the status of a single Pod may take to be retrieved")
+
+	return cmd
+}
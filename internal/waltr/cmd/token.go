@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewTokenCommand // assure type compatibility
+
+func NewTokenCommand(app *app.State) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:              "token",
+		Short:            "Manage scoped Vault tokens",
+		Long:             "Create, look up, renew and revoke Vault tokens and list their accessors",
+		TraverseChildren: true,
+	}
+
+	// subcommands
+	for _, subc := range TokenSubcommands {
+		cmd.AddCommand(subc(app))
+	}
+
+	return cmd
+}
+
+// tokenArg returns the token passed as the optional first argument
+func tokenArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	return args[0]
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewTokenCreateCommand // assure type compatibility
+
+func NewTokenCreateCommand(app *app.State) *cobra.Command {
+	var (
+		token       string
+		output      string
+		secret      string
+		secretFile  string
+		secretKey   string
+		unencrypted bool
+	)
+	opts := util.TokenOptions{DisplayName: app.Name}
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a scoped Vault token",
+		Long: "Create a Vault token with the given policies, TTLs and optionally as periodic or orphan token. The " +
+			"token is written to stdout, a Kubernetes Secret or a helm-secrets file.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ns, name string
+			if secret != "" {
+				var ok bool
+				ns, name, ok = strings.Cut(secret, "/")
+				if !ok || ns == "" || name == "" {
+					return fmt.Errorf("invalid secret: %q. Must be of the form 'namespace/name'", secret)
+				}
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			created, err := util.CreateToken(app, opts)
+			if err != nil {
+				return err
+			}
+			app.Log.Infof("created Vault token with accessor: %s and policies: %v", created.Accessor,
+				created.Policies)
+
+			if secret == "" && secretFile == "" {
+				return util.RenderTokens(os.Stdout, output, []util.Token{*created})
+			}
+
+			if secret != "" {
+				if err := util.WriteTokenSecret(app, ns, name, created.Token); err != nil {
+					return err
+				}
+
+				app.Log.Infof("wrote Vault token to key: %s of Secret: %s", util.TokenSecretKey, secret)
+			}
+
+			if secretFile != "" {
+				if secretKey == "" {
+					secretKey = "vault.tokens." + opts.DisplayName
+				}
+
+				if err := util.WriteTokenSecretFile(secretFile, secretKey, created.Token, unencrypted); err != nil {
+					return err
+				}
+
+				app.Log.Infof("wrote Vault token to: secrets.%s of file: %s", secretKey, secretFile)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVar(&opts.DisplayName, "display-name", opts.DisplayName,
+		"The display name of the token")
+	cmd.PersistentFlags().StringSliceVarP(&opts.Policies, "policy", "p", nil, "The policies of the token")
+	cmd.PersistentFlags().BoolVar(&opts.NoDefaultPolicy, "no-default-policy", false,
+		"Don't attach the 'default' policy to the token")
+	cmd.PersistentFlags().StringVar(&opts.TTL, "ttl", "", "The initial TTL of the token")
+	cmd.PersistentFlags().StringVar(&opts.ExplicitMax, "explicit-max-ttl", "",
+		"The maximum TTL the token can be renewed to")
+	cmd.PersistentFlags().StringVar(&opts.Period, "period", "",
+		"Create a periodic token which can be renewed indefinitely within the period")
+	cmd.PersistentFlags().BoolVar(&opts.Orphan, "orphan", false,
+		"Create a token without parent, which outlives the revocation of the creating token")
+	cmd.PersistentFlags().IntVar(&opts.NumUses, "num-uses", 0, "The number of uses of the token. 0 is unlimited")
+	cmd.PersistentFlags().StringToStringVar(&opts.Meta, "meta", nil, "Metadata of the token as 'key=value' pairs")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format if the token is written to stdout. One of: %v", util.Outputs))
+	cmd.PersistentFlags().StringVar(&secret, "secret", "",
+		fmt.Sprintf("Write the token to the key '%s' of this 'namespace/name' Kubernetes Secret", util.TokenSecretKey))
+	cmd.PersistentFlags().StringVarP(&secretFile, "secret-file", "f", "",
+		"Write the token to this helm-secrets file")
+	cmd.PersistentFlags().StringVar(&secretKey, "secret-key", "",
+		"The dot-separated key below 'secrets' of the helm-secrets file. Defaults to 'vault.tokens.<display-name>'")
+	cmd.PersistentFlags().BoolVar(&unencrypted, "unencrypted", false,
+		"Don't encrypt the helm-secrets file after writing the token")
+
+	return cmd
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewTokenListAccessorsCommand // assure type compatibility
+
+func NewTokenListAccessorsCommand(app *app.State) *cobra.Command {
+	var (
+		token   string
+		output  string
+		details bool
+	)
+
+	cmd := &cobra.Command{
+		Use:              "list-accessors",
+		Short:            "List the accessors of all Vault tokens",
+		Long:             "List the accessors of all Vault tokens and optionally look up their details",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			tokens, err := util.TokenAccessors(app, details)
+			if err != nil {
+				return err
+			}
+
+			return util.RenderTokens(os.Stdout, output, tokens)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().BoolVarP(&details, "details", "d", false,
+		"Look up the display name, policies and TTL of every token")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+
+	return cmd
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewTokenLookupCommand // assure type compatibility
+
+func NewTokenLookupCommand(app *app.State) *cobra.Command {
+	var (
+		token    string
+		accessor string
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:              "lookup [token]",
+		Short:            "Look up a Vault token",
+		Long:             "Look up a Vault token by value or accessor. Without either the current token is looked up.",
+		Args:             cobra.MaximumNArgs(1),
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			t, err := util.LookupToken(app, tokenArg(args), accessor)
+			if err != nil {
+				return err
+			}
+
+			return util.RenderTokens(os.Stdout, output, []util.Token{*t})
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&accessor, "accessor", "a", "", "The accessor of the token to look up")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+
+	return cmd
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewTokenRenewCommand // assure type compatibility
+
+func NewTokenRenewCommand(app *app.State) *cobra.Command {
+	var (
+		token     string
+		accessor  string
+		increment string
+	)
+
+	cmd := &cobra.Command{
+		Use:              "renew [token]",
+		Short:            "Renew a Vault token",
+		Long:             "Renew a Vault token by value or accessor. Without either the current token is renewed.",
+		Args:             cobra.MaximumNArgs(1),
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			ttl, err := util.RenewToken(app, tokenArg(args), accessor, increment)
+			if err != nil {
+				return err
+			}
+
+			app.Log.Infof("renewed Vault token. New TTL: %s", ttl)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&accessor, "accessor", "a", "", "The accessor of the token to renew")
+	cmd.PersistentFlags().StringVarP(&increment, "increment", "i", "",
+		"The requested TTL extension. Defaults to the token's initial TTL")
+
+	return cmd
+}
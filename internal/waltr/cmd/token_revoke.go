@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewTokenRevokeCommand // assure type compatibility
+
+func NewTokenRevokeCommand(app *app.State) *cobra.Command {
+	var (
+		token    string
+		accessor string
+		orphan   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:              "revoke [token]",
+		Short:            "Revoke a Vault token",
+		Long:             "Revoke a Vault token by value or accessor including its child tokens",
+		Args:             cobra.MaximumNArgs(1),
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			if err := util.RevokeToken(app, tokenArg(args), accessor, orphan); err != nil {
+				return err
+			}
+
+			app.Log.Info("successfully revoked Vault token")
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&accessor, "accessor", "a", "", "The accessor of the token to revoke")
+	cmd.PersistentFlags().BoolVar(&orphan, "orphan", false,
+		"Keep the child tokens by turning them into orphans. Requires the token instead of its accessor")
+
+	return cmd
+}
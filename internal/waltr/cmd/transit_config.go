@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewTransitConfigCommand // assure type compatibility
+
+func NewTransitConfigCommand(app *app.State) *cobra.Command {
+	var (
+		token         string
+		mount         string
+		output        string
+		minDecryption int64
+		minEncryption int64
+		cfg           util.TransitKeyConfig
+	)
+
+	cmd := &cobra.Command{
+		Use:   "config [key...]",
+		Short: "Configure transit keys",
+		Long: "Set the minimum decryption and encryption versions and the auto-rotate period of transit keys. " +
+			"Raise the minimum decryption version only after rewrapping all older ciphertexts. Without options " +
+			"the current configuration is shown. Defaults to the '" + util.VSOTransitKey + "' key.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("min-decryption-version") {
+				cfg.MinDecryptionVersion = &minDecryption
+			}
+
+			if cmd.Flags().Changed("min-encryption-version") {
+				cfg.MinEncryptionVersion = &minEncryption
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			var keys []util.TransitKey
+			for _, n := range transitKeyArgs(args) {
+				key, err := util.ConfigureTransitKey(app, mount, n, cfg)
+				if err != nil {
+					return err
+				}
+
+				keys = append(keys, *key)
+			}
+
+			return util.RenderTransitKeys(os.Stdout, output, keys)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&mount, "mount", "m", util.DefaultTransitMount,
+		"The mount path of the transit secrets engine")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+	cmd.PersistentFlags().Int64Var(&minDecryption, "min-decryption-version", 0,
+		"The oldest key version which can decrypt ciphertexts")
+	cmd.PersistentFlags().Int64Var(&minEncryption, "min-encryption-version", 0,
+		"The oldest key version allowed for encryption. 0 is the latest version")
+	cmd.PersistentFlags().StringVar(&cfg.AutoRotatePeriod, "auto-rotate-period", "",
+		"The period after which Vault rotates the key automatically, e.g. '720h'. '0' disables it")
+
+	return cmd
+}
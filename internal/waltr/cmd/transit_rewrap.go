@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewTransitRewrapCommand // assure type compatibility
+
+func NewTransitRewrapCommand(app *app.State) *cobra.Command {
+	var (
+		token           string
+		mount           string
+		output          string
+		secrets         []string
+		selector        string
+		secretNamespace string
+		files           []string
+		plan            bool
+		retire          bool
+		yes             bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rewrap [key]",
+		Short: "Rewrap ciphertexts with the latest transit key version",
+		Long: "Rewrap transit ciphertexts stored in Kubernetes Secrets or embedded into files with the latest " +
+			"version of the transit key, without exposing their plaintexts. Defaults to the '" +
+			util.VSOTransitKey + "' key.",
+		Args:             cobra.MaximumNArgs(1),
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(secrets) == 0 && selector == "" && len(files) == 0 {
+				return fmt.Errorf("no ciphertexts to rewrap. Pass the 'secret', 'selector' or 'file' option")
+			}
+
+			if plan && retire {
+				return fmt.Errorf("the 'plan' and 'retire' options are mutually exclusive")
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			key, err := util.ReadTransitKey(app, mount, transitKeyArgs(args)[0])
+			if err != nil {
+				return err
+			}
+
+			s, err := util.TransitSecrets(app, secrets, secretNamespace, selector)
+			if err != nil {
+				return err
+			}
+
+			results, err := util.RewrapSecrets(app, mount, key, s, plan)
+			if err != nil {
+				return err
+			}
+
+			f, err := util.RewrapFiles(app, mount, key, files, plan)
+			if err != nil {
+				return err
+			}
+			results = append(results, f...)
+
+			if err := util.RenderRewrapResults(os.Stdout, output, results); err != nil {
+				return err
+			}
+
+			if plan {
+				return nil
+			}
+
+			app.Log.Infof("rewrapped ciphertexts with version: %d of transit key: %s", key.LatestVersion, key.Name)
+			if !retire {
+				return nil
+			}
+
+			latest := key.LatestVersion
+			app.Log.Warnf("raising min_decryption_version of transit key: %s to: %d makes every ciphertext of an "+
+				"older version undecryptable. Only these ciphertexts were rewrapped:", key.Name, latest)
+			for _, r := range results {
+				app.Log.Warnf("  %s (%d of %d ciphertexts)", r.Target, r.Rewrapped, r.Found)
+			}
+
+			if !yes {
+				ok, err := confirm(cmd, fmt.Sprintf("Retire all versions of transit key: %s below: %d?", key.Name,
+					latest))
+				if err != nil {
+					return fmt.Errorf("%v. Pass the 'yes' option to retire without confirmation", err)
+				}
+
+				if !ok {
+					app.Log.Infof("kept min_decryption_version of transit key: %s", key.Name)
+					return nil
+				}
+			}
+
+			if _, err := util.ConfigureTransitKey(app, mount, key.Name, util.TransitKeyConfig{
+				MinDecryptionVersion: &latest,
+			}); err != nil {
+				return err
+			}
+
+			app.Log.Infof("raised min_decryption_version of transit key: %s to: %d", key.Name, latest)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&mount, "mount", "m", util.DefaultTransitMount,
+		"The mount path of the transit secrets engine")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+	cmd.PersistentFlags().StringSliceVar(&secrets, "secret", nil,
+		"Kubernetes Secrets holding ciphertexts as '<namespace>/<name>'")
+	cmd.PersistentFlags().StringVar(&selector, "selector", "",
+		"A label selector for Kubernetes Secrets holding ciphertexts")
+	cmd.PersistentFlags().StringVar(&secretNamespace, "secret-namespace", util.DefaultVSONamespace,
+		"The namespace of the Secrets matching the label selector")
+	cmd.PersistentFlags().StringSliceVarP(&files, "file", "f", nil, "Files with embedded ciphertexts")
+	cmd.PersistentFlags().BoolVar(&plan, "plan", false, "Only count the outdated ciphertexts instead of rewrapping them")
+	cmd.PersistentFlags().BoolVar(&retire, "retire", false,
+		"Raise the key's min_decryption_version to the latest version after rewrapping. Every other ciphertext "+
+			"of the key, e.g. VSO client-cache Secrets not passed to this command, must already be rewrapped")
+	cmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "Retire older key versions without confirmation")
+
+	return cmd
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewTransitRotateCommand // assure type compatibility
+
+func NewTransitRotateCommand(app *app.State) *cobra.Command {
+	var (
+		token  string
+		mount  string
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rotate [key...]",
+		Short: "Rotate transit keys",
+		Long: "Create a new version of the transit keys. New ciphertexts use the latest version, while existing " +
+			"ciphertexts remain decryptable until they're rewrapped. Defaults to the '" + util.VSOTransitKey +
+			"' key.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names := transitKeyArgs(args)
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			var keys []util.TransitKey
+			for _, n := range names {
+				key, err := util.RotateTransitKey(app, mount, n)
+				if err != nil {
+					return err
+				}
+
+				app.Log.Infof("rotated transit key: %s to version: %d", n, key.LatestVersion)
+				keys = append(keys, *key)
+			}
+
+			return util.RenderTransitKeys(os.Stdout, output, keys)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringVarP(&mount, "mount", "m", util.DefaultTransitMount,
+		"The mount path of the transit secrets engine")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", util.OutputTable,
+		fmt.Sprintf("The output format. One of: %v", util.Outputs))
+
+	return cmd
+}
+
+// transitKeyArgs returns the transit keys given as arguments or defaults to the Vault Secrets Operator's key
+func transitKeyArgs(args []string) []string {
+	if len(args) == 0 {
+		return []string{util.VSOTransitKey}
+	}
+
+	return args
+}
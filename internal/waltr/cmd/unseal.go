@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ app.CLIOpt = NewUnsealCommand // assure type compatibility
+
+func NewUnsealCommand(app *app.State) *cobra.Command {
+	var (
+		podNames []string
+		keys     []string
+		timeout  time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "unseal",
+		Short: "Unseal Vault",
+		Long: "Unseal all (or the selected) Vault Pods concurrently with the unseal keys obtained during " +
+			"initialization or the keys given by their holders",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envF := proc.Must(cmd.Flags().GetString("environment"))
+			label := proc.Must(cmd.Flags().GetString("label"))
+			namespace := proc.Must(cmd.Flags().GetString("namespace"))
+			environment := proc.Must(core.EnvFromString(envF))
+
+			_, keys, err := credentialKeys(app, environment, keys)
+			if err != nil {
+				return err
+			}
+
+			pods, err := util.Pods(app, namespace, label)
+			if err != nil {
+				return fmt.Errorf("could not retrieve Vault pods for label: %s. Error: %v", label, err)
+			}
+
+			if len(podNames) > 0 {
+				var selected []corev1.Pod
+				for _, p := range pods {
+					if helpers.SliceContains(podNames, p.Name) {
+						selected = append(selected, p)
+					}
+				}
+				pods = selected
+			}
+
+			if len(pods) == 0 {
+				return fmt.Errorf("could not find any Vault pods to unseal for label: %s", label)
+			}
+
+			// every Pod is port-forwarded on its own, even if Vault is reachable directly
+			if err := util.ConfigureVaultCA(app, pods[0].Namespace); err != nil {
+				return err
+			}
+
+			var failed int
+			for _, r := range util.UnsealPods(app, pods, keys, timeout) {
+				switch {
+				case r.Err != nil:
+					failed++
+					app.Log.Errorf("failed to unseal Vault Pod: %s. Error: %v", r.Pod, r.Err)
+				case r.AlreadyUnsealed:
+					app.Log.Infof("Vault Pod: %s was already unsealed", r.Pod)
+				default:
+					app.Log.Infof("Vault Pod: %s is unsealed", r.Pod)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("could not unseal %d of %d Vault pods", failed, len(pods))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringSliceVar(&podNames, "pod", nil, "Only unseal the Vault Pods with the given names")
+	cmd.PersistentFlags().StringSliceVar(&keys, "key", nil,
+		"The (decrypted) unseal keys. Defaults to the keys in the credentials store")
+	cmd.PersistentFlags().DurationVar(&timeout, "timeout", util.DefaultUnsealTimeout,
+		"The time a single Pod may take to start and become unsealed")
+
+	return cmd
+}
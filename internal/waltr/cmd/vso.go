@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewVSOCommand // assure type compatibility
+
+func NewVSOCommand(app *app.State) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:              "vso",
+		Short:            "Integrate Vault with the Vault Secrets Operator",
+		Aliases:          []string{"vault-secrets-operator"},
+		Long:             "Generate the Vault Secrets Operator's custom resources for the Vault configured by waltr",
+		TraverseChildren: true,
+	}
+
+	// subcommands
+	for _, subc := range VSOSubcommands {
+		cmd.AddCommand(subc(app))
+	}
+
+	return cmd
+}
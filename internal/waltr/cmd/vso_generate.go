@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/internal/waltr/util"
+	"github.com/fmjstudios/gopskit/pkg/proc"
+	"github.com/spf13/cobra"
+)
+
+var _ app.CLIOpt = NewVSOGenerateCommand // assure type compatibility
+
+func NewVSOGenerateCommand(app *app.State) *cobra.Command {
+	var (
+		token     string
+		releases  []string
+		outputDir string
+		apply     bool
+	)
+	opts := util.DefaultVSOOptions()
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate VaultConnection, VaultAuth and VaultStaticSecret manifests",
+		Long: "Generate a VaultConnection and VaultAuth using the 'vso-auth' role and transit key created by " +
+			"'waltr transit', the Service Account the role binds within every release namespace and a " +
+			"VaultStaticSecret for every KV path of every release. The manifests are written to stdout, to files " +
+			"or applied to the cluster.",
+		TraverseChildren: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			label := proc.Must(cmd.Flags().GetString("label"))
+			namespace := proc.Must(cmd.Flags().GetString("namespace"))
+
+			if len(releases) == 0 {
+				releases = util.Releases
+			}
+
+			if opts.Address == "" {
+				addr, err := util.ServiceAddress(app, namespace, label)
+				if err != nil {
+					return err
+				}
+
+				opts.Address = addr
+			}
+
+			cancel, err := connect(app, cmd, token)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			paths, err := util.VSOReleasePaths(app, opts.KVMount, releases)
+			if err != nil {
+				return err
+			}
+
+			objs := util.VSOManifests(opts, paths)
+
+			unbound, err := util.VSOUnboundNamespaces(app, opts, opts.ReleaseNamespaces(releases))
+			if err != nil {
+				return err
+			}
+
+			if len(unbound) > 0 {
+				app.Log.Warnf("Kubernetes Auth role: %s doesn't bind the namespaces: %v. Their VaultStaticSecrets "+
+					"cannot authenticate until the role binds them, e.g. through a 'waltr apply' specification", opts.Role, unbound)
+			}
+
+			switch {
+			case apply:
+				if err := util.ApplyManifests(app, objs); err != nil {
+					return err
+				}
+
+				app.Log.Infof("successfully applied %d Vault Secrets Operator resources", len(objs))
+			case outputDir != "":
+				files, err := util.WriteManifestFiles(outputDir, objs)
+				if err != nil {
+					return err
+				}
+
+				for _, f := range files {
+					app.Log.Infof("wrote manifest: %s", f)
+				}
+			default:
+				return util.WriteManifests(os.Stdout, objs)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&token, "token", "t", "", "The Vault root token")
+	cmd.PersistentFlags().StringSliceVarP(&releases, "release", "r", nil,
+		"The releases to generate VaultStaticSecrets for. Defaults to the built-in release list")
+	cmd.PersistentFlags().StringVar(&outputDir, "output-dir", "",
+		"Write every manifest to '<output-dir>/<namespace>/<kind>-<name>.yaml' instead of stdout")
+	cmd.PersistentFlags().BoolVar(&apply, "apply", false, "Create or update the resources within the cluster")
+	cmd.PersistentFlags().StringVar(&opts.Namespace, "vso-namespace", opts.Namespace,
+		"The namespace of the Vault Secrets Operator")
+	cmd.PersistentFlags().StringVar(&opts.Address, "connection-address", "",
+		"The in-cluster address of Vault the VaultConnection points to. Defaults to the Service of the discovered "+
+			"Vault release")
+	cmd.PersistentFlags().StringVar(&opts.CACertSecret, "ca-cert-secret", "",
+		"A Secret within the operator's namespace holding Vault's CA certificate as 'ca.crt'")
+	cmd.PersistentFlags().BoolVar(&opts.SkipTLSVerify, "connection-skip-tls-verify", false,
+		"Disable the operator's verification of Vault's certificate within the VaultConnection")
+	cmd.PersistentFlags().StringVar(&opts.KVMount, "kv-mount", opts.KVMount,
+		"The mount path of the KV-v2 secrets engine")
+	cmd.PersistentFlags().StringVar(&opts.RefreshAfter, "refresh-after", opts.RefreshAfter,
+		"The interval in which the operator syncs the secrets")
+	cmd.PersistentFlags().StringToStringVar(&opts.Namespaces, "release-namespace", nil,
+		"Map releases to the namespaces their secrets are synced to as 'release=namespace' pairs. "+
+			"Defaults to a namespace named like the release")
+
+	return cmd
+}
@@ -0,0 +1,790 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Action is the kind of modification a Change performs on a Vault resource
+type Action int
+
+const (
+	Create Action = iota + 1
+	Update
+	Delete
+)
+
+// String implements the fmt.Stringer interface for the Action type
+func (a Action) String() string {
+	return [...]string{"create", "update", "delete"}[a-1]
+}
+
+// Kinds of Vault resources managed by a Spec
+const (
+	KindAuthMethod     = "auth"
+	KindSecretsEngine  = "secrets"
+	KindPolicy         = "policy"
+	KindPasswordPolicy = "password-policy"
+	KindKubernetesRole = "kubernetes-role"
+	KindTransitKey     = "transit-key"
+)
+
+// DefaultKubernetesMount is the mount path of the Kubernetes authentication method if a
+// KubernetesRoleSpec does not specify one
+const DefaultKubernetesMount = "kubernetes"
+
+var (
+	// builtinPolicies are ACL policies Vault manages itself, they are never pruned
+	builtinPolicies = []string{"root", "default", "response-wrapping", "control-group"}
+
+	// builtinAuthMethods are authentication methods Vault mounts itself, they are never pruned
+	builtinAuthMethods = []string{"token"}
+)
+
+// ChangeOptions configure how Changes compares the live Vault with a Spec
+type ChangeOptions struct {
+	// Prune deletes ACL policies, password policies, Kubernetes Auth roles and authentication methods
+	// which are not part of the Spec. Secrets engines and transit keys are never pruned since deleting
+	// them destroys all data stored within or encrypted by them.
+	Prune bool
+}
+
+// Change is a single pending modification of a Vault resource. Before and After are human-readable
+// renderings of the resource, they are empty for created and deleted resources respectively.
+type Change struct {
+	Action Action
+	Kind   string
+	Name   string
+	Before string
+	After  string
+
+	// apply performs the modification against the Vault API
+	apply func(ctx context.Context) error
+}
+
+// String implements the fmt.Stringer interface for the Change type
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Name)
+}
+
+// Changes computes the ordered list of changes required to converge the live Vault to the given Spec.
+// Resources which already match the Spec do not produce a Change, which makes applying the result
+// idempotent.
+func Changes(a *app.State, spec *Spec, opts ChangeOptions) ([]Change, error) {
+	var changes []Change
+	ctx := context.Background()
+
+	steps := []func(ctx context.Context, a *app.State, spec *Spec, opts ChangeOptions) ([]Change, error){
+		authMethodChanges,
+		secretsEngineChanges,
+		policyChanges,
+		passwordPolicyChanges,
+		kubernetesRoleChanges,
+		transitKeyChanges,
+	}
+
+	for _, step := range steps {
+		c, err := step(ctx, a, spec, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, c...)
+	}
+
+	return changes, nil
+}
+
+// ApplyChanges applies the given changes in order and stops at the first failure
+func ApplyChanges(a *app.State, changes []Change) error {
+	for _, c := range changes {
+		if err := c.apply(context.Background()); err != nil {
+			return fmt.Errorf("could not %s: %v", c, err)
+		}
+
+		a.Log.Infof("applied change: %s", c)
+	}
+
+	return nil
+}
+
+func authMethodChanges(ctx context.Context, a *app.State, spec *Spec, opts ChangeOptions) ([]Change, error) {
+	var changes []Change
+
+	live, err := a.VaultClient.System.AuthListEnabledMethods(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list enabled Vault authentication methods: %v", err)
+	}
+
+	wanted := make([]string, 0, len(spec.AuthMethods))
+	for _, m := range spec.AuthMethods {
+		path := strings.Trim(m.Path, "/")
+		wanted = append(wanted, path)
+
+		cur, ok := live.Data[path+"/"].(map[string]interface{})
+		if !ok {
+			changes = append(changes, Change{
+				Action: Create,
+				Kind:   KindAuthMethod,
+				Name:   path,
+				After:  describeMount(m.Type, m.Description, m.Config),
+				apply: func(ctx context.Context) error {
+					_, err := a.VaultClient.System.AuthEnableMethod(ctx, path, schema.AuthEnableMethodRequest{
+						Type:        m.Type,
+						Description: m.Description,
+					})
+					if err != nil {
+						return err
+					}
+
+					return configureAuthMethod(ctx, a, path, m)
+				},
+			})
+			continue
+		}
+
+		if t := toString(cur["type"]); t != m.Type {
+			return nil, fmt.Errorf("authentication method %s is of type %s, cannot change it to %s", path, t, m.Type)
+		}
+
+		if d := toString(cur["description"]); d != m.Description {
+			changes = append(changes, Change{
+				Action: Update,
+				Kind:   KindAuthMethod,
+				Name:   path,
+				Before: describeMount(m.Type, d, nil),
+				After:  describeMount(m.Type, m.Description, nil),
+				apply: func(ctx context.Context) error {
+					_, err := a.VaultClient.System.AuthTuneConfigurationParameters(ctx, path,
+						schema.AuthTuneConfigurationParametersRequest{
+							Description: m.Description,
+						})
+					return err
+				},
+			})
+		}
+
+		if len(m.Config) == 0 {
+			continue
+		}
+
+		cfg, err := a.VaultClient.Read(ctx, fmt.Sprintf("auth/%s/config", path))
+		if err != nil && !vault.IsErrorStatus(err, http.StatusNotFound) {
+			return nil, fmt.Errorf("could not read configuration of authentication method %s: %v", path, err)
+		}
+
+		liveCfg := make(map[string]string)
+		for k := range m.Config {
+			if cfg != nil {
+				liveCfg[k] = toString(cfg.Data[k])
+			}
+		}
+
+		if before, after := describe(liveCfg), describe(m.Config); before != after {
+			changes = append(changes, Change{
+				Action: Update,
+				Kind:   KindAuthMethod,
+				Name:   path + "/config",
+				Before: before,
+				After:  after,
+				apply: func(ctx context.Context) error {
+					return configureAuthMethod(ctx, a, path, m)
+				},
+			})
+		}
+	}
+
+	if !opts.Prune {
+		return changes, nil
+	}
+
+	for _, k := range sortedKeys(live.Data) {
+		path := strings.TrimSuffix(k, "/")
+		if helpers.SliceContains(wanted, path) || helpers.SliceContains(builtinAuthMethods, path) {
+			continue
+		}
+
+		cur, _ := live.Data[k].(map[string]interface{})
+		changes = append(changes, Change{
+			Action: Delete,
+			Kind:   KindAuthMethod,
+			Name:   path,
+			Before: describeMount(toString(cur["type"]), toString(cur["description"]), nil),
+			apply: func(ctx context.Context) error {
+				_, err := a.VaultClient.System.AuthDisableMethod(ctx, path)
+				return err
+			},
+		})
+	}
+
+	return changes, nil
+}
+
+// configureAuthMethod writes the configuration of an authentication method. Kubernetes methods without
+// an explicit 'kubernetes_host' are pointed to the cluster's API server Service.
+func configureAuthMethod(ctx context.Context, a *app.State, path string, m AuthMethodSpec) error {
+	cfg := make(map[string]interface{})
+	for k, v := range m.Config {
+		cfg[k] = v
+	}
+
+	if m.Type == "kubernetes" && cfg["kubernetes_host"] == nil {
+		host, err := KubernetesHost(a)
+		if err != nil {
+			return err
+		}
+
+		cfg["kubernetes_host"] = host
+	}
+
+	if len(cfg) == 0 {
+		return nil
+	}
+
+	_, err := a.VaultClient.Write(ctx, fmt.Sprintf("auth/%s/config", path), cfg)
+	return err
+}
+
+// KubernetesHost builds the in-cluster address of the Kubernetes API server from its Service
+func KubernetesHost(a *app.State) (string, error) {
+	svc, err := a.Kube.Service("default", "kubernetes", metav1.GetOptions{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("could not find Kubernetes API server service: %v", err)
+	}
+
+	return fmt.Sprintf("https://%s:%d", svc.Spec.ClusterIP, svc.Spec.Ports[0].Port), nil
+}
+
+func secretsEngineChanges(ctx context.Context, a *app.State, spec *Spec, _ ChangeOptions) ([]Change, error) {
+	var changes []Change
+
+	live, err := a.VaultClient.System.MountsListSecretsEngines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list secrets engines: %v", err)
+	}
+
+	for _, e := range spec.SecretsEngines {
+		path := strings.Trim(e.Path, "/")
+		typ, opts := normalizeEngine(e)
+
+		cur, ok := live.Data[path+"/"].(map[string]interface{})
+		if !ok {
+			changes = append(changes, Change{
+				Action: Create,
+				Kind:   KindSecretsEngine,
+				Name:   path,
+				After:  describeMount(typ, e.Description, opts),
+				apply: func(ctx context.Context) error {
+					_, err := a.VaultClient.System.MountsEnableSecretsEngine(ctx, path,
+						schema.MountsEnableSecretsEngineRequest{
+							Type:        typ,
+							Description: e.Description,
+							Options:     toInterfaceMap(opts),
+						})
+					return err
+				},
+			})
+			continue
+		}
+
+		if t := toString(cur["type"]); t != typ {
+			return nil, fmt.Errorf("secrets engine %s is of type %s, cannot change it to %s", path, t, typ)
+		}
+
+		curOpts, _ := cur["options"].(map[string]interface{})
+		liveOpts := make(map[string]string)
+		for k := range opts {
+			liveOpts[k] = toString(curOpts[k])
+		}
+
+		before := describeMount(typ, toString(cur["description"]), liveOpts)
+		after := describeMount(typ, e.Description, opts)
+		if before != after {
+			changes = append(changes, Change{
+				Action: Update,
+				Kind:   KindSecretsEngine,
+				Name:   path,
+				Before: before,
+				After:  after,
+				apply: func(ctx context.Context) error {
+					_, err := a.VaultClient.System.MountsTuneConfigurationParameters(ctx, path,
+						schema.MountsTuneConfigurationParametersRequest{
+							Description: e.Description,
+							Options:     toInterfaceMap(opts),
+						})
+					return err
+				},
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// normalizeEngine maps the 'kv-v2' shorthand to the 'kv' type with version 2, which is how Vault
+// reports such mounts
+func normalizeEngine(e SecretsEngineSpec) (string, map[string]string) {
+	opts := make(map[string]string)
+	for k, v := range e.Options {
+		opts[k] = v
+	}
+
+	if e.Type == "kv-v2" {
+		opts["version"] = "2"
+		return "kv", opts
+	}
+
+	return e.Type, opts
+}
+
+func policyChanges(ctx context.Context, a *app.State, spec *Spec, opts ChangeOptions) ([]Change, error) {
+	var changes []Change
+
+	live, err := Policies(a)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := spec.AclPolicies()
+	for _, name := range sortedKeys(policies) {
+		body := policies[name]
+		action, before := Create, ""
+		if helpers.SliceContains(live, name) {
+			cur, err := a.VaultClient.System.PoliciesReadAclPolicy(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("could not read policy %s: %v", name, err)
+			}
+
+			if strings.TrimSpace(cur.Data.Policy) == strings.TrimSpace(body) {
+				continue
+			}
+
+			action, before = Update, cur.Data.Policy
+		}
+
+		changes = append(changes, Change{
+			Action: action,
+			Kind:   KindPolicy,
+			Name:   name,
+			Before: before,
+			After:  body,
+			apply: func(ctx context.Context) error {
+				_, err := a.VaultClient.System.PoliciesWriteAclPolicy(ctx, name, schema.PoliciesWriteAclPolicyRequest{
+					Policy: body,
+				})
+				return err
+			},
+		})
+	}
+
+	if !opts.Prune {
+		return changes, nil
+	}
+
+	for _, name := range live {
+		if _, ok := policies[name]; ok || helpers.SliceContains(builtinPolicies, name) {
+			continue
+		}
+
+		cur, err := a.VaultClient.System.PoliciesReadAclPolicy(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read policy %s: %v", name, err)
+		}
+
+		changes = append(changes, Change{
+			Action: Delete,
+			Kind:   KindPolicy,
+			Name:   name,
+			Before: cur.Data.Policy,
+			apply: func(ctx context.Context) error {
+				_, err := a.VaultClient.System.PoliciesDeleteAclPolicy(ctx, name)
+				return err
+			},
+		})
+	}
+
+	return changes, nil
+}
+
+func passwordPolicyChanges(ctx context.Context, a *app.State, spec *Spec, opts ChangeOptions) ([]Change, error) {
+	var changes []Change
+
+	live, err := PasswordPolicies(a)
+	if err != nil {
+		return nil, err
+	}
+
+	var wanted []string
+	for _, p := range spec.PasswordPolicies {
+		wanted = append(wanted, p.Name)
+
+		action, before := Create, ""
+		if helpers.SliceContains(live, p.Name) {
+			cur, err := a.VaultClient.System.PoliciesReadPasswordPolicy(ctx, p.Name)
+			if err != nil {
+				return nil, fmt.Errorf("could not read password policy %s: %v", p.Name, err)
+			}
+
+			if strings.TrimSpace(cur.Data.Policy) == strings.TrimSpace(p.Policy) {
+				continue
+			}
+
+			action, before = Update, cur.Data.Policy
+		}
+
+		changes = append(changes, Change{
+			Action: action,
+			Kind:   KindPasswordPolicy,
+			Name:   p.Name,
+			Before: before,
+			After:  p.Policy,
+			apply: func(ctx context.Context) error {
+				_, err := a.VaultClient.System.PoliciesWritePasswordPolicy(ctx, p.Name,
+					schema.PoliciesWritePasswordPolicyRequest{
+						Policy: p.Policy,
+					})
+				return err
+			},
+		})
+	}
+
+	if !opts.Prune {
+		return changes, nil
+	}
+
+	for _, name := range live {
+		if helpers.SliceContains(wanted, name) {
+			continue
+		}
+
+		cur, err := a.VaultClient.System.PoliciesReadPasswordPolicy(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read password policy %s: %v", name, err)
+		}
+
+		changes = append(changes, Change{
+			Action: Delete,
+			Kind:   KindPasswordPolicy,
+			Name:   name,
+			Before: cur.Data.Policy,
+			apply: func(ctx context.Context) error {
+				_, err := a.VaultClient.System.PoliciesDeletePasswordPolicy(ctx, name)
+				return err
+			},
+		})
+	}
+
+	return changes, nil
+}
+
+func kubernetesRoleChanges(ctx context.Context, a *app.State, spec *Spec, opts ChangeOptions) ([]Change, error) {
+	var changes []Change
+	live := make(map[string][]string)
+	wanted := make(map[string][]string)
+
+	for _, r := range spec.KubernetesRoles {
+		mount := r.Mount
+		if mount == "" {
+			mount = DefaultKubernetesMount
+		}
+		wanted[mount] = append(wanted[mount], r.Name)
+
+		if _, ok := live[mount]; !ok {
+			roles, err := a.VaultClient.Auth.KubernetesListAuthRoles(ctx, vault.WithMountPath(mount))
+			if err != nil && !vault.IsErrorStatus(err, http.StatusNotFound) {
+				return nil, fmt.Errorf("could not list Kubernetes Auth roles of mount %s: %v", mount, err)
+			}
+
+			live[mount] = []string{}
+			if roles != nil {
+				live[mount] = roles.Data.Keys
+			}
+		}
+
+		req := schema.KubernetesWriteAuthRoleRequest{
+			Audience:                      r.Audience,
+			BoundServiceAccountNames:      r.ServiceAccounts,
+			BoundServiceAccountNamespaces: r.Namespaces,
+			TokenPeriod:                   r.TokenPeriod,
+			TokenPolicies:                 spec.RolePolicies(r),
+			TokenTtl:                      r.TokenTTL,
+		}
+
+		action, before := Create, ""
+		if helpers.SliceContains(live[mount], r.Name) {
+			cur, err := a.VaultClient.Auth.KubernetesReadAuthRole(ctx, r.Name, vault.WithMountPath(mount))
+			if err != nil {
+				return nil, fmt.Errorf("could not read Kubernetes Auth role %s: %v", r.Name, err)
+			}
+
+			if kubernetesRoleMatches(cur.Data, req) {
+				continue
+			}
+
+			action, before = Update, describeLiveKubernetesRole(cur.Data)
+		}
+
+		name := r.Name
+		changes = append(changes, Change{
+			Action: action,
+			Kind:   KindKubernetesRole,
+			Name:   fmt.Sprintf("%s/%s", mount, name),
+			Before: before,
+			After:  describeKubernetesRole(req),
+			apply: func(ctx context.Context) error {
+				_, err := a.VaultClient.Auth.KubernetesWriteAuthRole(ctx, name, req, vault.WithMountPath(mount))
+				return err
+			},
+		})
+	}
+
+	if !opts.Prune {
+		return changes, nil
+	}
+
+	for _, mount := range sortedKeys(live) {
+		for _, name := range live[mount] {
+			if helpers.SliceContains(wanted[mount], name) {
+				continue
+			}
+
+			cur, err := a.VaultClient.Auth.KubernetesReadAuthRole(ctx, name, vault.WithMountPath(mount))
+			if err != nil {
+				return nil, fmt.Errorf("could not read Kubernetes Auth role %s: %v", name, err)
+			}
+
+			changes = append(changes, Change{
+				Action: Delete,
+				Kind:   KindKubernetesRole,
+				Name:   fmt.Sprintf("%s/%s", mount, name),
+				Before: describeLiveKubernetesRole(cur.Data),
+				apply: func(ctx context.Context) error {
+					_, err := a.VaultClient.Auth.KubernetesDeleteAuthRole(ctx, name, vault.WithMountPath(mount))
+					return err
+				},
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// transitKeyChanges creates missing transit keys. Existing keys are kept, their type cannot be changed.
+func transitKeyChanges(ctx context.Context, a *app.State, spec *Spec, _ ChangeOptions) ([]Change, error) {
+	var changes []Change
+
+	for _, k := range spec.TransitKeys {
+		mount := strings.Trim(k.Mount, "/")
+		if mount == "" {
+			mount = DefaultTransitMount
+		}
+
+		p := path.Join(mount, "keys", k.Name)
+		cur, err := a.VaultClient.Read(ctx, p)
+		if err == nil {
+			if t := toString(cur.Data["type"]); k.Type != "" && t != k.Type {
+				return nil, fmt.Errorf("transit key %s is of type %s, cannot change it to %s", p, t, k.Type)
+			}
+
+			continue
+		}
+
+		// Vault answers with 404 for missing keys as well as for transit engines the plan has yet to mount
+		if !vault.IsErrorStatus(err, http.StatusNotFound) {
+			return nil, fmt.Errorf("could not read transit key: %s. Error: %v", p, err)
+		}
+
+		data := make(map[string]interface{})
+		if k.Type != "" {
+			data["type"] = k.Type
+		}
+
+		changes = append(changes, Change{
+			Action: Create,
+			Kind:   KindTransitKey,
+			Name:   p,
+			After:  describe(map[string]string{"type": orDash(k.Type)}),
+			apply: func(ctx context.Context) error {
+				_, err := a.VaultClient.Write(ctx, p, data)
+				return err
+			},
+		})
+	}
+
+	return changes, nil
+}
+
+// kubernetesRoleMatches compares the live data of a Kubernetes Auth role with the request that would
+// be written for it
+func kubernetesRoleMatches(cur map[string]interface{}, req schema.KubernetesWriteAuthRoleRequest) bool {
+	if toString(cur["audience"]) != req.Audience {
+		return false
+	}
+
+	if !helpers.SameElements(toStrings(cur["bound_service_account_names"]), req.BoundServiceAccountNames) ||
+		!helpers.SameElements(toStrings(cur["bound_service_account_namespaces"]), req.BoundServiceAccountNamespaces) ||
+		!helpers.SameElements(toStrings(cur["token_policies"]), req.TokenPolicies) {
+		return false
+	}
+
+	return seconds(cur["token_period"]) == seconds(req.TokenPeriod) && seconds(cur["token_ttl"]) == seconds(req.TokenTtl)
+}
+
+// describeKubernetesRole renders the request for a Kubernetes Auth role for display within a plan
+func describeKubernetesRole(req schema.KubernetesWriteAuthRoleRequest) string {
+	return describe(map[string]string{
+		"audience":                         req.Audience,
+		"bound_service_account_names":      sortedList(req.BoundServiceAccountNames),
+		"bound_service_account_namespaces": sortedList(req.BoundServiceAccountNamespaces),
+		"token_policies":                   sortedList(req.TokenPolicies),
+		"token_period":                     fmt.Sprintf("%ds", seconds(req.TokenPeriod)),
+		"token_ttl":                        fmt.Sprintf("%ds", seconds(req.TokenTtl)),
+	})
+}
+
+// describeLiveKubernetesRole renders the live data of a Kubernetes Auth role for display within a plan
+func describeLiveKubernetesRole(cur map[string]interface{}) string {
+	return describe(map[string]string{
+		"audience":                         toString(cur["audience"]),
+		"bound_service_account_names":      sortedList(toStrings(cur["bound_service_account_names"])),
+		"bound_service_account_namespaces": sortedList(toStrings(cur["bound_service_account_namespaces"])),
+		"token_policies":                   sortedList(toStrings(cur["token_policies"])),
+		"token_period":                     fmt.Sprintf("%ds", seconds(cur["token_period"])),
+		"token_ttl":                        fmt.Sprintf("%ds", seconds(cur["token_ttl"])),
+	})
+}
+
+// describeMount renders an authentication method or secrets engine for display within a plan
+func describeMount(typ, description string, options map[string]string) string {
+	fields := map[string]string{
+		"type":        typ,
+		"description": description,
+	}
+
+	for k, v := range options {
+		fields[k] = v
+	}
+
+	return describe(fields)
+}
+
+// describe renders a set of fields as sorted 'key = value' lines
+func describe(fields map[string]string) string {
+	var sb strings.Builder
+	for _, k := range sortedKeys(fields) {
+		sb.WriteString(fmt.Sprintf("%s = %q\n", k, fields[k]))
+	}
+
+	return sb.String()
+}
+
+// sortedList renders a string slice in lexical order
+func sortedList(s []string) string {
+	cp := append([]string{}, s...)
+	sort.Strings(cp)
+	return "[" + strings.Join(cp, ", ") + "]"
+}
+
+// toInterfaceMap converts a map of strings into the map type expected by the Vault API
+func toInterfaceMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+
+	return out
+}
+
+// toString converts a decoded JSON value into a string, mapping nil to an empty string
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	return fmt.Sprint(v)
+}
+
+// toStrings converts a decoded JSON array into a string slice
+func toStrings(v interface{}) []string {
+	var out []string
+	if arr, ok := v.([]interface{}); ok {
+		for _, e := range arr {
+			out = append(out, fmt.Sprint(e))
+		}
+	}
+
+	return out
+}
+
+// seconds converts a Vault duration, given either as a number of seconds, a Go duration string or, like
+// Vault accepts them, a number of days with a 'd' suffix, to seconds. Unparseable values result in -1.
+func seconds(v interface{}) int64 {
+	switch d := v.(type) {
+	case nil:
+		return 0
+	case json.Number:
+		n, err := d.Int64()
+		if err != nil {
+			return -1
+		}
+		return n
+	case float64:
+		return int64(d)
+	case int:
+		return int64(d)
+	case string:
+		if d == "" {
+			return 0
+		}
+
+		if n, err := strconv.ParseInt(d, 10, 64); err == nil {
+			return n
+		}
+
+		if days, ok := strings.CutSuffix(d, "d"); ok {
+			n, err := strconv.ParseInt(days, 10, 64)
+			if err != nil {
+				return -1
+			}
+			return n * int64((24 * time.Hour).Seconds())
+		}
+
+		dur, err := time.ParseDuration(d)
+		if err != nil {
+			return -1
+		}
+		return int64(dur.Seconds())
+	default:
+		return -1
+	}
+}
+
+// sortedKeys returns the keys of a map in lexical order
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
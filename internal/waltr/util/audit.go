@@ -0,0 +1,229 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+)
+
+const (
+	AuditFile   = "file"
+	AuditSyslog = "syslog"
+	AuditSocket = "socket"
+
+	// DefaultAuditFilePath is the log file of file audit devices. The official chart mounts the audit
+	// storage volume at '/vault/audit' if 'server.auditStorage.enabled' is set.
+	DefaultAuditFilePath = "/vault/audit/audit.log"
+)
+
+// AuditTypes are the supported types of audit devices
+var AuditTypes = []string{AuditFile, AuditSyslog, AuditSocket}
+
+// AuditDevice describes an audit device enabled at Path
+type AuditDevice struct {
+	Path        string            `json:"path" yaml:"path"`
+	Type        string            `json:"type" yaml:"type"`
+	Description string            `json:"description" yaml:"description"`
+	Local       bool              `json:"local" yaml:"local"`
+	Options     map[string]string `json:"options" yaml:"options"`
+}
+
+// NewAuditDevice creates an AuditDevice of the given type. The path defaults to the type and file
+// devices default to DefaultAuditFilePath.
+func NewAuditDevice(typ, path string, options map[string]string) AuditDevice {
+	if path == "" {
+		path = typ
+	}
+
+	opts := make(map[string]string, len(options))
+	for k, v := range options {
+		if v != "" {
+			opts[k] = v
+		}
+	}
+
+	if _, ok := opts["file_path"]; typ == AuditFile && !ok {
+		opts["file_path"] = DefaultAuditFilePath
+	}
+
+	return AuditDevice{
+		Path:    strings.Trim(path, "/"),
+		Type:    typ,
+		Options: opts,
+	}
+}
+
+// Validate checks the AuditDevice for an unsupported type and missing required options
+func (d AuditDevice) Validate() error {
+	if d.Path == "" {
+		return fmt.Errorf("invalid audit device: path is required")
+	}
+
+	switch d.Type {
+	case AuditFile:
+		if d.Options["file_path"] == "" {
+			return fmt.Errorf("invalid file audit device %q: the 'file_path' option is required", d.Path)
+		}
+	case AuditSocket:
+		if d.Options["address"] == "" {
+			return fmt.Errorf("invalid socket audit device %q: the 'address' option is required", d.Path)
+		}
+	case AuditSyslog:
+	default:
+		return fmt.Errorf("invalid audit device %q: unsupported type %q. Must be one of: %v", d.Path, d.Type,
+			AuditTypes)
+	}
+
+	return nil
+}
+
+// Equal reports whether two AuditDevices are configured identically
+func (d AuditDevice) Equal(o AuditDevice) bool {
+	return d.Path == o.Path && d.Type == o.Type && d.Local == o.Local && maps.Equal(d.Options, o.Options)
+}
+
+// AuditDevices retrieves the enabled audit devices of the current Vault instance sorted by path
+func AuditDevices(a *app.State) ([]AuditDevice, error) {
+	res, err := a.VaultClient.Read(context.Background(), "sys/audit")
+	if err != nil {
+		return nil, fmt.Errorf("could not list audit devices: %v", err)
+	}
+
+	var devices []AuditDevice
+	for path, v := range res.Data {
+		raw, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var d struct {
+			Type        string                 `json:"type"`
+			Description string                 `json:"description"`
+			Local       bool                   `json:"local"`
+			Options     map[string]interface{} `json:"options"`
+		}
+		if err := decodeResponse(raw, &d); err != nil {
+			return nil, fmt.Errorf("could not decode audit device: %s. Error: %v", path, err)
+		}
+
+		options := make(map[string]string, len(d.Options))
+		for k, o := range d.Options {
+			options[k] = fmt.Sprint(o)
+		}
+
+		devices = append(devices, AuditDevice{
+			Path:        strings.Trim(path, "/"),
+			Type:        d.Type,
+			Description: d.Description,
+			Local:       d.Local,
+			Options:     options,
+		})
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Path < devices[j].Path
+	})
+
+	return devices, nil
+}
+
+// EnableAudit enables the AuditDevice unless an identical device is enabled already. Vault cannot
+// reconfigure audit devices, so a differing device at the same path is only replaced if replace is set.
+// It returns true if the device was (re-)enabled.
+func EnableAudit(a *app.State, device AuditDevice, replace bool) (bool, error) {
+	if err := device.Validate(); err != nil {
+		return false, err
+	}
+
+	devices, err := AuditDevices(a)
+	if err != nil {
+		return false, err
+	}
+
+	for _, d := range devices {
+		if d.Path != device.Path {
+			continue
+		}
+
+		if d.Equal(device) {
+			a.Log.Infof("audit device: %s is already enabled - skipping", device.Path)
+			return false, nil
+		}
+
+		if !replace {
+			return false, fmt.Errorf("audit device: %s is enabled with a different configuration. "+
+				"Use the 'replace' option to disable and re-enable it", device.Path)
+		}
+
+		a.Log.Warnf("replacing audit device: %s - requests are not audited by it until it is re-enabled",
+			device.Path)
+		if _, err := DisableAudit(a, device.Path); err != nil {
+			return false, err
+		}
+	}
+
+	_, err = a.VaultClient.Write(context.Background(), "sys/audit/"+device.Path, map[string]interface{}{
+		"type":        device.Type,
+		"description": device.Description,
+		"local":       device.Local,
+		"options":     device.Options,
+	})
+	if err != nil {
+		return false, fmt.Errorf("could not enable audit device: %s. Error: %v", device.Path, err)
+	}
+
+	return true, nil
+}
+
+// DisableAudit disables the audit device at path. It returns false if no such device is enabled.
+func DisableAudit(a *app.State, path string) (bool, error) {
+	path = strings.Trim(path, "/")
+
+	devices, err := AuditDevices(a)
+	if err != nil {
+		return false, err
+	}
+
+	enabled := make([]string, 0, len(devices))
+	for _, d := range devices {
+		enabled = append(enabled, d.Path)
+	}
+
+	if !helpers.SliceContains(enabled, path) {
+		return false, nil
+	}
+
+	if _, err := a.VaultClient.Delete(context.Background(), "sys/audit/"+path); err != nil {
+		return false, fmt.Errorf("could not disable audit device: %s. Error: %v", path, err)
+	}
+
+	return true, nil
+}
+
+// RenderAuditDevices writes the given audit devices to w in the given output format
+func RenderAuditDevices(w io.Writer, format string, devices []AuditDevice) error {
+	return RenderOutput(w, format, devices, func(tw io.Writer) {
+		fmt.Fprintln(tw, "PATH\tTYPE\tLOCAL\tDESCRIPTION\tOPTIONS")
+		for _, d := range devices {
+			keys := make([]string, 0, len(d.Options))
+			for k := range d.Options {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			opts := make([]string, 0, len(keys))
+			for _, k := range keys {
+				opts = append(opts, fmt.Sprintf("%s=%s", k, d.Options[k]))
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%t\t%s\t%s\n", d.Path, d.Type, d.Local, orDash(d.Description),
+				orDash(strings.Join(opts, ",")))
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuditDevice(t *testing.T) {
+	file := NewAuditDevice(AuditFile, "", map[string]string{"log_raw": "true", "format": ""})
+	assert.Equal(t, "file", file.Path)
+	assert.Equal(t, map[string]string{"file_path": DefaultAuditFilePath, "log_raw": "true"}, file.Options)
+	assert.NoError(t, file.Validate())
+
+	socket := NewAuditDevice(AuditSocket, "/siem/", nil)
+	assert.Equal(t, "siem", socket.Path)
+	assert.Error(t, socket.Validate())
+
+	socket.Options["address"] = "siem.logging:9090"
+	assert.NoError(t, socket.Validate())
+	assert.False(t, socket.Equal(file))
+
+	other := NewAuditDevice(AuditSocket, "siem", map[string]string{"address": "siem.logging:9090"})
+	assert.True(t, socket.Equal(other))
+
+	assert.Error(t, NewAuditDevice("kafka", "", nil).Validate())
+}
@@ -0,0 +1,203 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	"github.com/fmjstudios/gopskit/pkg/kube"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VaultCAKey is the key of Vault's CA certificate within the Vault TLS Secret
+const VaultCAKey = "ca.crt"
+
+// forwardTimeout is the maximum duration Forward waits for the port-forward to become ready
+const forwardTimeout = 30 * time.Second
+
+// ConnectOptions configure how Connect discovers and authenticates against Vault
+type ConnectOptions struct {
+	// Namespace is the Kubernetes namespace to search for Vault Pods. An empty value searches the
+	// entire cluster, in which case all Vault Pods are required to reside in a single namespace.
+	Namespace string
+
+	// Label is the Kubernetes label selector matching Vault Pods
+	Label string
+
+	// Token is the Vault token to authenticate with. If it is unset, the token is read from the
+	// credentials written during initialization for the Environment.
+	Token string
+
+	// Environment is the execution environment to read credentials for
+	Environment core.Environment
+}
+
+// Connect makes Vault reachable with Forward and authenticates the State's VaultClient. The returned
+// context.CancelFunc shuts down the port-forward and must be called once the caller is done with Vault.
+func Connect(a *app.State, opts ConnectOptions) (context.CancelFunc, error) {
+	token := opts.Token
+	if token == "" {
+		a.Log.Debug("'token' option is unset, falling back to credentials in cache path!")
+		creds, err := ReadCredentials(a, opts.Environment)
+		if err != nil {
+			return nil, fmt.Errorf("token option is unset and could not read credentials: %w", err)
+		}
+
+		token = creds.Token
+	}
+
+	cancel, err := Forward(a, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.VaultClient.SetToken(token); err != nil {
+		cancel()
+		return nil, fmt.Errorf("could not set token: %v", err)
+	}
+
+	return cancel, nil
+}
+
+// Forward makes Vault reachable for the State's VaultClient without authenticating it. Unless Vault is
+// reachable directly, it discovers the Vault leader Pod and port-forwards it to the local machine. Either
+// way Vault's TLS certificate is verified, unless disabled. The returned context.CancelFunc shuts down
+// the port-forward and must be called once the caller is done with Vault.
+func Forward(a *app.State, opts ConnectOptions) (context.CancelFunc, error) {
+	if a.Vault.Direct() {
+		if err := ConfigureVaultCA(a, opts.Namespace); err != nil {
+			return nil, err
+		}
+
+		a.Log.Debugf("connecting to Vault at: %s", a.Vault.Address)
+		return func() {}, verifyTLS(context.Background(), a)
+	}
+
+	pods, err := Pods(a, opts.Namespace, opts.Label)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve Vault pods for label: %s. Error: %v", opts.Label, err)
+	}
+
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("could not find any Vault pods for label: %s", opts.Label)
+	}
+
+	namespace, err := kube.ResolveNamespace(pods, opts.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("found multiple possible Vault pods. the namespace option is unset and %v", err)
+	}
+
+	if err := ConfigureVaultCA(a, namespace); err != nil {
+		return nil, err
+	}
+
+	leader, err := LeaderPod(a, pods)
+	if err != nil {
+		return nil, err
+	}
+
+	leader, err = WaitForRunning(context.Background(), a, *leader)
+	if err != nil {
+		return nil, err
+	}
+
+	// the certificate is issued for the Service rather than the local end of the port-forward
+	if a.Vault.ServerName == "" {
+		opts := a.Vault
+		opts.ServerName = ServiceHost(*leader)
+		if err := a.ConfigureVault(opts); err != nil {
+			return nil, err
+		}
+
+		a.Log.Debugf("verifying Vault's TLS certificate against: %s", opts.ServerName)
+	}
+
+	// port-forward the (leader)
+	a.Log.Infof("Port-forwarding Vault instance: %s", leader.Name)
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{})
+	failed := make(chan error, 1)
+	go func() {
+		failed <- a.Kube.PortForward(ctx, *leader, kube.WithReadyChannel(ready))
+	}()
+
+	select {
+	case <-ready:
+	case err := <-failed:
+		cancel()
+		return nil, fmt.Errorf("could not port-forward Vault instance: %s. Error: %v", leader.Name, err)
+	case <-time.After(forwardTimeout):
+		cancel()
+		return nil, fmt.Errorf("timed out port-forwarding Vault instance: %s", leader.Name)
+	}
+
+	if err := verifyTLS(ctx, a); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return cancel, nil
+}
+
+// ConfigureVaultCA reads Vault's CA certificate from the Kubernetes Secret configured as CASecret of
+// the State's VaultOptions and reconfigures the VaultClient to verify Vault's TLS certificate with it.
+// Secrets given without namespace are read from the given namespace.
+func ConfigureVaultCA(a *app.State, namespace string) error {
+	if a.Vault.CASecret == "" || len(a.Vault.CACertBytes) > 0 {
+		return nil
+	}
+
+	name := a.Vault.CASecret
+	if ns, n, ok := strings.Cut(name, "/"); ok {
+		namespace, name = ns, n
+	}
+
+	if namespace == "" {
+		return fmt.Errorf("cannot read Vault CA Secret: %s without namespace. Pass it as <namespace>/<name>",
+			a.Vault.CASecret)
+	}
+
+	secret, err := a.Kube.Secret(namespace, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not read Vault CA Secret: %s/%s. Error: %v", namespace, name, err)
+	}
+
+	ca, ok := secret.Data[VaultCAKey]
+	if !ok || len(ca) == 0 {
+		return fmt.Errorf("vault CA Secret: %s/%s does not contain: %s", namespace, name, VaultCAKey)
+	}
+
+	opts := a.Vault
+	opts.CACertBytes = ca
+	if err := a.ConfigureVault(opts); err != nil {
+		return err
+	}
+
+	a.Log.Debugf("verifying Vault's TLS certificate with the CA of Secret: %s/%s", namespace, name)
+	return nil
+}
+
+// verifyTLS requests Vault's seal status, which doesn't require authentication, to fail early with a
+// descriptive error if Vault's TLS certificate cannot be verified. Other errors are left to the caller's
+// requests.
+func verifyTLS(ctx context.Context, a *app.State) error {
+	if a.Vault.SkipVerify {
+		return nil
+	}
+
+	_, err := a.VaultClient.System.SealStatus(ctx)
+	if err == nil {
+		return nil
+	}
+
+	if strings.Contains(err.Error(), "x509:") || strings.Contains(err.Error(), "tls:") {
+		return fmt.Errorf("could not verify Vault's TLS certificate. Configure its CA with the 'vault-cacert' or "+
+			"'vault-ca-secret' option or disable the verification with 'tls-skip-verify'. Error: %v", err)
+	}
+
+	a.Log.Debugf("could not request Vault seal status: %v", err)
+	return nil
+}
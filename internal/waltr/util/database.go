@@ -0,0 +1,460 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of database secrets engine resources
+const (
+	KindDatabaseConnection = "database-connection"
+	KindDatabaseRole       = "database-role"
+)
+
+// DefaultDatabaseMount is the mount path of the database secrets engine if a DatabaseConfig does not
+// specify one
+const DefaultDatabaseMount = "database"
+
+// databasePlugins maps the supported plugin shorthands to Vault's database plugins
+var databasePlugins = map[string]string{
+	"postgres":   "postgresql-database-plugin",
+	"postgresql": "postgresql-database-plugin",
+	"mysql":      "mysql-database-plugin",
+	"mariadb":    "mysql-database-plugin",
+}
+
+// DatabaseConfig is the declarative description of a database secrets engine, its connections and the
+// roles generating dynamic credentials
+type DatabaseConfig struct {
+	Mount       string               `yaml:"mount"`
+	Connections []DatabaseConnection `yaml:"connections"`
+	Roles       []DatabaseRole       `yaml:"roles"`
+}
+
+// DatabaseConnection describes a connection to a PostgreSQL or MySQL database. The ConnectionURL
+// defaults to one built from Host, Database and Options. The password is only written when the
+// connection is created, since the root credentials are rotated afterwards unless SkipRootRotation
+// is set.
+type DatabaseConnection struct {
+	Name             string            `yaml:"name"`
+	Plugin           string            `yaml:"plugin"`
+	ConnectionURL    string            `yaml:"connectionURL"`
+	Host             string            `yaml:"host"`
+	Database         string            `yaml:"database"`
+	Options          map[string]string `yaml:"options"`
+	Username         string            `yaml:"username"`
+	Password         string            `yaml:"password"`
+	PasswordEnv      string            `yaml:"passwordEnv"`
+	AllowedRoles     []string          `yaml:"allowedRoles"`
+	SkipRootRotation bool              `yaml:"skipRootRotation"`
+}
+
+// DatabaseRole describes a role generating dynamic credentials with the connection's root credentials
+type DatabaseRole struct {
+	Name                 string   `yaml:"name"`
+	Connection           string   `yaml:"connection"`
+	CreationStatements   []string `yaml:"creationStatements"`
+	RevocationStatements []string `yaml:"revocationStatements"`
+	DefaultTTL           string   `yaml:"defaultTTL"`
+	MaxTTL               string   `yaml:"maxTTL"`
+}
+
+// LoadDatabaseConfig reads and, if required, decrypts a DatabaseConfig from the YAML file at path
+func LoadDatabaseConfig(path string) (*DatabaseConfig, error) {
+	raw, err := readSecretsFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseDatabaseConfig(raw)
+}
+
+// ParseDatabaseConfig parses and validates a DatabaseConfig. Connections without allowed roles are
+// restricted to the roles referencing them.
+func ParseDatabaseConfig(raw []byte) (*DatabaseConfig, error) {
+	var cfg DatabaseConfig
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse database configuration: %v", err)
+	}
+
+	if cfg.Mount == "" {
+		cfg.Mount = DefaultDatabaseMount
+	}
+	cfg.Mount = strings.Trim(cfg.Mount, "/")
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	for i, c := range cfg.Connections {
+		if len(c.AllowedRoles) > 0 {
+			continue
+		}
+
+		for _, r := range cfg.Roles {
+			if r.Connection == c.Name {
+				cfg.Connections[i].AllowedRoles = append(cfg.Connections[i].AllowedRoles, r.Name)
+			}
+		}
+	}
+
+	return &cfg, nil
+}
+
+// Validate checks the DatabaseConfig for missing fields, unsupported plugins and dangling references
+func (c *DatabaseConfig) Validate() error {
+	var connections []string
+	for _, conn := range c.Connections {
+		if conn.Name == "" {
+			return fmt.Errorf("database connection without name")
+		}
+
+		if helpers.SliceContains(connections, conn.Name) {
+			return fmt.Errorf("duplicate database connection: %s", conn.Name)
+		}
+		connections = append(connections, conn.Name)
+
+		if _, err := conn.PluginName(); err != nil {
+			return err
+		}
+
+		if conn.ConnectionURL == "" && conn.Host == "" {
+			return fmt.Errorf("database connection: %s requires either a connection URL or a host", conn.Name)
+		}
+
+		if conn.Username == "" {
+			return fmt.Errorf("database connection: %s requires a username", conn.Name)
+		}
+	}
+
+	var roles []string
+	for _, r := range c.Roles {
+		if r.Name == "" {
+			return fmt.Errorf("database role without name")
+		}
+
+		if helpers.SliceContains(roles, r.Name) {
+			return fmt.Errorf("duplicate database role: %s", r.Name)
+		}
+		roles = append(roles, r.Name)
+
+		if !helpers.SliceContains(connections, r.Connection) {
+			return fmt.Errorf("database role: %s references unknown connection: %s", r.Name, r.Connection)
+		}
+
+		if len(r.CreationStatements) == 0 {
+			return fmt.Errorf("database role: %s requires creation statements", r.Name)
+		}
+	}
+
+	return nil
+}
+
+// PluginName returns the Vault database plugin of the connection
+func (c DatabaseConnection) PluginName() (string, error) {
+	if p, ok := databasePlugins[strings.ToLower(c.Plugin)]; ok {
+		return p, nil
+	}
+
+	if strings.HasSuffix(c.Plugin, "-database-plugin") {
+		return c.Plugin, nil
+	}
+
+	return "", fmt.Errorf("database connection: %s uses unsupported plugin: %q", c.Name, c.Plugin)
+}
+
+// URL returns the connection URL template of the connection. Vault substitutes the '{{username}}' and
+// '{{password}}' placeholders with the root credentials.
+func (c DatabaseConnection) URL() string {
+	if c.ConnectionURL != "" {
+		return c.ConnectionURL
+	}
+
+	query := url.Values{}
+	for k, v := range c.Options {
+		query.Set(k, v)
+	}
+
+	var u string
+	if plugin, _ := c.PluginName(); plugin == "mysql-database-plugin" {
+		u = fmt.Sprintf("{{username}}:{{password}}@tcp(%s)/%s", c.Host, c.Database)
+	} else {
+		u = fmt.Sprintf("postgresql://{{username}}:{{password}}@%s/%s", c.Host, c.Database)
+	}
+
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	return u
+}
+
+// password returns the root password of the connection, falling back to the PasswordEnv variable
+func (c DatabaseConnection) password() (string, error) {
+	if c.Password != "" {
+		return c.Password, nil
+	}
+
+	if c.PasswordEnv != "" {
+		if p := os.Getenv(c.PasswordEnv); p != "" {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("no password for database connection: %s. Set password or passwordEnv", c.Name)
+}
+
+// DatabaseChanges computes the Changes required to converge the database secrets engine to the given
+// DatabaseConfig. Connections are rotated to Vault-managed root credentials right after their creation.
+func DatabaseChanges(a *app.State, cfg *DatabaseConfig) ([]Change, error) {
+	ctx := context.Background()
+	var changes []Change
+
+	engines, err := SecretsEngines(a)
+	if err != nil {
+		return nil, err
+	}
+
+	mounted := helpers.SliceContains(engines, cfg.Mount+"/")
+	if !mounted {
+		changes = append(changes, Change{
+			Action: Create,
+			Kind:   KindSecretsEngine,
+			Name:   cfg.Mount,
+			After:  describeMount("database", "dynamic database credentials", nil),
+			apply: func(ctx context.Context) error {
+				_, err := a.VaultClient.System.MountsEnableSecretsEngine(ctx, cfg.Mount,
+					schema.MountsEnableSecretsEngineRequest{
+						Type:        "database",
+						Description: "dynamic database credentials",
+					})
+				return err
+			},
+		})
+	}
+
+	for _, c := range cfg.Connections {
+		name := fmt.Sprintf("%s/config/%s", cfg.Mount, c.Name)
+		plugin, _ := c.PluginName()
+		req := map[string]interface{}{
+			"plugin_name":    plugin,
+			"connection_url": c.URL(),
+			"username":       c.Username,
+			"allowed_roles":  c.AllowedRoles,
+		}
+
+		var cur map[string]interface{}
+		if mounted {
+			cur, err = readDatabaseResource(ctx, a, name)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if cur == nil {
+			password, err := c.password()
+			if err != nil {
+				return nil, err
+			}
+
+			after := describeDatabaseConnection(req)
+			if !c.SkipRootRotation {
+				after += describe(map[string]string{"rotate_root": "true"})
+			}
+
+			changes = append(changes, Change{
+				Action: Create,
+				Kind:   KindDatabaseConnection,
+				Name:   name,
+				After:  after,
+				apply: func(ctx context.Context) error {
+					data := map[string]interface{}{"password": password}
+					for k, v := range req {
+						data[k] = v
+					}
+
+					if _, err := a.VaultClient.Write(ctx, name, data); err != nil {
+						return err
+					}
+
+					if c.SkipRootRotation {
+						return nil
+					}
+
+					return RotateDatabaseRoot(ctx, a, cfg.Mount, c.Name)
+				},
+			})
+			continue
+		}
+
+		details, _ := cur["connection_details"].(map[string]interface{})
+		live := map[string]interface{}{
+			"plugin_name":    cur["plugin_name"],
+			"connection_url": details["connection_url"],
+			"username":       details["username"],
+			"allowed_roles":  cur["allowed_roles"],
+		}
+
+		before, after := describeDatabaseConnection(live), describeDatabaseConnection(req)
+		if before == after {
+			continue
+		}
+
+		// the password is omitted, so that Vault keeps the rotated root credentials
+		changes = append(changes, Change{
+			Action: Update,
+			Kind:   KindDatabaseConnection,
+			Name:   name,
+			Before: before,
+			After:  after,
+			apply: func(ctx context.Context) error {
+				_, err := a.VaultClient.Write(ctx, name, req)
+				return err
+			},
+		})
+	}
+
+	for _, r := range cfg.Roles {
+		name := fmt.Sprintf("%s/roles/%s", cfg.Mount, r.Name)
+		req := map[string]interface{}{
+			"db_name":               r.Connection,
+			"creation_statements":   r.CreationStatements,
+			"revocation_statements": r.RevocationStatements,
+			"default_ttl":           r.DefaultTTL,
+			"max_ttl":               r.MaxTTL,
+		}
+
+		var cur map[string]interface{}
+		if mounted {
+			cur, err = readDatabaseResource(ctx, a, name)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		c := Change{
+			Action: Create,
+			Kind:   KindDatabaseRole,
+			Name:   name,
+			After:  describeDatabaseRole(req),
+			apply: func(ctx context.Context) error {
+				_, err := a.VaultClient.Write(ctx, name, req)
+				return err
+			},
+		}
+
+		if cur != nil {
+			c.Action = Update
+			c.Before = describeDatabaseRole(cur)
+			if c.Before == c.After {
+				continue
+			}
+		}
+
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+// RotatableConnections returns the names of the DatabaseConfig's connections whose root credentials
+// '--rotate-root' may rotate. Connections with SkipRootRotation are left alone, as are connections the
+// given Changes create, since their root credentials are already rotated right after their creation.
+func (c *DatabaseConfig) RotatableConnections(changes []Change) []string {
+	var names []string
+	for _, conn := range c.Connections {
+		if conn.SkipRootRotation {
+			continue
+		}
+
+		created := false
+		name := fmt.Sprintf("%s/config/%s", c.Mount, conn.Name)
+		for _, ch := range changes {
+			if ch.Action == Create && ch.Kind == KindDatabaseConnection && ch.Name == name {
+				created = true
+				break
+			}
+		}
+
+		if !created {
+			names = append(names, conn.Name)
+		}
+	}
+
+	return names
+}
+
+// RotateDatabaseRoot rotates the root credentials of a database connection. Afterwards only Vault knows
+// the root password.
+func RotateDatabaseRoot(ctx context.Context, a *app.State, mount, connection string) error {
+	_, err := a.VaultClient.Write(ctx, fmt.Sprintf("%s/rotate-root/%s", strings.Trim(mount, "/"), connection), nil)
+	if err != nil {
+		return fmt.Errorf("could not rotate root credentials of database connection: %s. Error: %v", connection, err)
+	}
+
+	return nil
+}
+
+// readDatabaseResource reads a connection or role of the database secrets engine. It returns nil if it
+// doesn't exist.
+func readDatabaseResource(ctx context.Context, a *app.State, p string) (map[string]interface{}, error) {
+	res, err := a.VaultClient.Read(ctx, p)
+	if err != nil {
+		if vault.IsErrorStatus(err, http.StatusNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("could not read %s: %v", p, err)
+	}
+
+	if res == nil {
+		return nil, nil
+	}
+
+	return res.Data, nil
+}
+
+// describeDatabaseConnection renders a database connection for display within a plan
+func describeDatabaseConnection(data map[string]interface{}) string {
+	roles, ok := data["allowed_roles"].([]string)
+	if !ok {
+		roles = toStrings(data["allowed_roles"])
+	}
+
+	return describe(map[string]string{
+		"plugin_name":    toString(data["plugin_name"]),
+		"connection_url": toString(data["connection_url"]),
+		"username":       toString(data["username"]),
+		"allowed_roles":  sortedList(roles),
+	})
+}
+
+// describeDatabaseRole renders a database role for display within a plan
+func describeDatabaseRole(data map[string]interface{}) string {
+	statements := func(v interface{}) string {
+		s, ok := v.([]string)
+		if !ok {
+			s = toStrings(v)
+		}
+
+		return strings.Join(s, "\n")
+	}
+
+	return describe(map[string]string{
+		"db_name":               toString(data["db_name"]),
+		"creation_statements":   statements(data["creation_statements"]),
+		"revocation_statements": statements(data["revocation_statements"]),
+		"default_ttl":           fmt.Sprintf("%ds", seconds(data["default_ttl"])),
+		"max_ttl":               fmt.Sprintf("%ds", seconds(data["max_ttl"])),
+	})
+}
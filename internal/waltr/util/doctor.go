@@ -0,0 +1,360 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Severity is the impact of a doctor Finding
+type Severity string
+
+const (
+	SeverityOK       Severity = "ok"
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+
+	// SeverityError is the severity of checks which could not be completed. It exceeds every other
+	// severity, so that incomplete checks always fail.
+	SeverityError Severity = "error"
+)
+
+// Severities are the severities of doctor Findings in ascending order
+var Severities = []Severity{SeverityOK, SeverityInfo, SeverityWarning, SeverityCritical, SeverityError}
+
+// rank returns the position of the Severity within Severities
+func (s Severity) rank() int {
+	for i, v := range Severities {
+		if v == s {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// AtLeast reports whether the Severity is as high as or higher than o
+func (s Severity) AtLeast(o Severity) bool {
+	return s.rank() >= o.rank()
+}
+
+// SeverityFromString converts a string into a Severity
+func SeverityFromString(s string) (Severity, error) {
+	for _, v := range Severities {
+		if string(v) == strings.ToLower(s) {
+			return v, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown severity: %s. Must be one of: %v", s, Severities)
+}
+
+// Finding is the result of a single doctor check. Checks without issues report a single Finding with
+// SeverityOK.
+type Finding struct {
+	Check    string   `json:"check" yaml:"check"`
+	Severity Severity `json:"severity" yaml:"severity"`
+	Message  string   `json:"message" yaml:"message"`
+}
+
+// DoctorOptions configure the checks of Doctor
+type DoctorOptions struct {
+	// Pods are the Vault Pods whose mounted configuration is checked
+	Pods []corev1.Pod
+
+	// MinThreshold is the lowest acceptable Shamir key threshold
+	MinThreshold int
+
+	// RootToken is the initial root token within the credentials store, which is checked for
+	// revocation. It's empty if the credentials store doesn't hold a root token.
+	RootToken string
+
+	// CredentialsError is the error which prevented reading the credentials store. The root token
+	// check fails with it, since it cannot tell whether a root token is stored.
+	CredentialsError error
+}
+
+// doctorCheck inspects a single aspect of Vault's posture
+type doctorCheck struct {
+	name string
+	run  func(ctx context.Context, a *app.State, opts DoctorOptions) ([]Finding, error)
+}
+
+// doctorChecks are the checks Doctor runs in order
+var doctorChecks = []doctorCheck{
+	{"root-token", checkRootToken},
+	{"audit", checkAudit},
+	{"client-tls", checkClientTLS},
+	{"listener", checkListeners},
+	{"seal", checkSeal},
+	{"kv-versions", checkKVVersions},
+	{"sudo-policies", checkSudoPolicies},
+}
+
+// Doctor inspects the running Vault and returns the Findings of all checks. Checks which cannot be
+// completed result in a Finding with SeverityError.
+func Doctor(a *app.State, opts DoctorOptions) []Finding {
+	ctx := context.Background()
+	var findings []Finding
+
+	for _, c := range doctorChecks {
+		f, err := c.run(ctx, a, opts)
+		switch {
+		case err != nil:
+			a.Log.Debugf("doctor check: %s failed. Error: %v", c.name, err)
+			f = []Finding{{Severity: SeverityError, Message: fmt.Sprintf("could not complete check: %v", err)}}
+		case len(f) == 0:
+			f = []Finding{{Severity: SeverityOK, Message: "no issues found"}}
+		}
+
+		for i := range f {
+			f[i].Check = c.name
+		}
+
+		findings = append(findings, f...)
+	}
+
+	return findings
+}
+
+// CountFindings returns the number of Findings with at least the given Severity
+func CountFindings(findings []Finding, min Severity) int {
+	var n int
+	for _, f := range findings {
+		if f.Severity.AtLeast(min) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// RenderFindings writes the Findings to w in the given format
+func RenderFindings(w io.Writer, format string, findings []Finding) error {
+	return RenderOutput(w, format, findings, func(tw io.Writer) {
+		fmt.Fprintln(tw, "CHECK\tSEVERITY\tMESSAGE")
+		for _, f := range findings {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Check, f.Severity, f.Message)
+		}
+	})
+}
+
+// checkRootToken reports if the initial root token within the credentials store is still a valid root
+// token, regardless of the token waltr authenticated with
+func checkRootToken(_ context.Context, a *app.State, opts DoctorOptions) ([]Finding, error) {
+	if opts.CredentialsError != nil {
+		return nil, fmt.Errorf("could not read Vault credentials: %v", opts.CredentialsError)
+	}
+
+	if opts.RootToken == "" {
+		return []Finding{{
+			Severity: SeverityInfo,
+			Message:  "the credentials store holds no root token. Verify manually that the initial root token was revoked",
+		}}, nil
+	}
+
+	t, err := LookupToken(a, opts.RootToken, "")
+	if err != nil {
+		// Vault rejects lookups of revoked tokens, whereas missing permissions are reported as error
+		if strings.Contains(err.Error(), "bad token") || strings.Contains(err.Error(), "invalid token") {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if !helpers.SliceContains(t.Policies, "root") {
+		return nil, nil
+	}
+
+	return []Finding{{
+		Severity: SeverityCritical,
+		Message: fmt.Sprintf("root token with accessor: %s is still valid. Revoke it and use 'waltr generate-root' "+
+			"when required", t.Accessor),
+	}}, nil
+}
+
+// checkAudit reports if no audit device is enabled
+func checkAudit(_ context.Context, a *app.State, _ DoctorOptions) ([]Finding, error) {
+	devices, err := AuditDevices(a)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(devices) > 0 {
+		return nil, nil
+	}
+
+	return []Finding{{
+		Severity: SeverityCritical,
+		Message:  "no audit device is enabled. Enable one with 'waltr audit enable'",
+	}}, nil
+}
+
+// checkClientTLS reports if waltr skips the verification of Vault's certificate, e.g. due to the
+// 'tls-skip-verify' option or VAULT_SKIP_VERIFY
+func checkClientTLS(_ context.Context, a *app.State, _ DoctorOptions) ([]Finding, error) {
+	if !a.VaultClient.Configuration().TLS.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	return []Finding{{
+		Severity: SeverityWarning,
+		Message:  "waltr does not verify Vault's TLS certificate",
+	}}, nil
+}
+
+// checkListeners reports listeners of the mounted Vault configuration which serve plain HTTP or allow
+// outdated TLS versions
+func checkListeners(_ context.Context, a *app.State, opts DoctorOptions) ([]Finding, error) {
+	configs, err := VaultConfigs(a, opts.Pods)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(configs) == 0 {
+		return []Finding{{
+			Severity: SeverityInfo,
+			Message:  "found no Vault configuration ConfigMap mounted into the Vault Pods",
+		}}, nil
+	}
+
+	var findings []Finding
+	for _, name := range sortedKeys(configs) {
+		for _, l := range configs[name].Listeners {
+			if l.Type != "tcp" {
+				continue
+			}
+
+			switch {
+			case l.TLSDisabled():
+				findings = append(findings, Finding{
+					Severity: SeverityCritical,
+					Message:  fmt.Sprintf("listener: %s of %s has TLS disabled", orDash(l.Address), name),
+				})
+			case l.TLSMinVersion == "tls10" || l.TLSMinVersion == "tls11":
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Message: fmt.Sprintf("listener: %s of %s allows outdated TLS version: %s", orDash(l.Address),
+						name, l.TLSMinVersion),
+				})
+			}
+		}
+	}
+
+	return findings, nil
+}
+
+// checkSeal reports Shamir key thresholds below the configured minimum
+func checkSeal(ctx context.Context, a *app.State, opts DoctorOptions) ([]Finding, error) {
+	seal, err := a.VaultClient.System.SealStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get Vault seal status: %v", err)
+	}
+
+	if seal.Data.Type != "shamir" || int(seal.Data.T) >= opts.MinThreshold {
+		return nil, nil
+	}
+
+	severity := SeverityWarning
+	if seal.Data.T <= 1 {
+		severity = SeverityCritical
+	}
+
+	return []Finding{{
+		Severity: severity,
+		Message: fmt.Sprintf("%d of %d key shares unseal Vault, the minimum threshold is %d. Use 'waltr rekey' "+
+			"to raise it", seal.Data.T, seal.Data.N, opts.MinThreshold),
+	}}, nil
+}
+
+// checkKVVersions reports KV-v2 mounts without configured 'max_versions'
+func checkKVVersions(ctx context.Context, a *app.State, _ DoctorOptions) ([]Finding, error) {
+	mounts, err := a.VaultClient.System.MountsListSecretsEngines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list secrets engines: %v", err)
+	}
+
+	var findings []Finding
+	for _, k := range sortedKeys(mounts.Data) {
+		m, _ := mounts.Data[k].(map[string]interface{})
+		if toString(m["type"]) != "kv" {
+			continue
+		}
+
+		options, _ := m["options"].(map[string]interface{})
+		if toString(options["version"]) != "2" {
+			continue
+		}
+
+		mount := strings.TrimSuffix(k, "/")
+		res, err := a.VaultClient.Read(ctx, mount+"/config")
+		if err != nil {
+			return nil, fmt.Errorf("could not read configuration of KV-v2 mount: %s. Error: %v", mount, err)
+		}
+
+		if n, err := strconv.Atoi(toString(res.Data["max_versions"])); err == nil && n > 0 {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("KV-v2 mount: %s does not configure max_versions", mount),
+		})
+	}
+
+	return findings, nil
+}
+
+// checkSudoPolicies reports ACL policies granting 'sudo' on broad wildcard paths
+func checkSudoPolicies(ctx context.Context, a *app.State, _ DoctorOptions) ([]Finding, error) {
+	names, err := Policies(a)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var findings []Finding
+	for _, name := range names {
+		if name == "root" {
+			continue
+		}
+
+		res, err := a.VaultClient.System.PoliciesReadAclPolicy(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read policy %s: %v", name, err)
+		}
+
+		policy, err := ParsePolicy(name, res.Data.Policy)
+		if err != nil {
+			findings = append(findings, Finding{Severity: SeverityWarning, Message: err.Error()})
+			continue
+		}
+
+		for _, p := range policy.Paths {
+			if !p.Has("sudo") || !p.Broad() {
+				continue
+			}
+
+			severity := SeverityWarning
+			if p.Path == "*" {
+				severity = SeverityCritical
+			}
+
+			findings = append(findings, Finding{
+				Severity: severity,
+				Message:  fmt.Sprintf("policy: %s grants sudo on broad path: %s", name, p.Path),
+			})
+		}
+	}
+
+	return findings, nil
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverity(t *testing.T) {
+	s, err := SeverityFromString("Warning")
+	assert.NoError(t, err)
+	assert.Equal(t, SeverityWarning, s)
+
+	_, err = SeverityFromString("fatal")
+	assert.Error(t, err)
+
+	assert.True(t, SeverityCritical.AtLeast(SeverityWarning))
+	assert.True(t, SeverityWarning.AtLeast(SeverityWarning))
+	assert.False(t, SeverityInfo.AtLeast(SeverityWarning))
+
+	// incomplete checks fail regardless of the threshold
+	assert.True(t, SeverityError.AtLeast(SeverityCritical))
+}
+
+func TestCountFindings(t *testing.T) {
+	findings := []Finding{
+		{Check: "audit", Severity: SeverityCritical},
+		{Check: "seal", Severity: SeverityWarning},
+		{Check: "listener", Severity: SeverityOK},
+	}
+
+	assert.Equal(t, 1, CountFindings(findings, SeverityCritical))
+	assert.Equal(t, 2, CountFindings(findings, SeverityInfo))
+
+	var buf bytes.Buffer
+	assert.NoError(t, RenderFindings(&buf, OutputTable, findings))
+	assert.Contains(t, buf.String(), "CHECK")
+	assert.Contains(t, buf.String(), "critical")
+}
+
+func TestParseVaultConfigListeners(t *testing.T) {
+	cfg, err := ParseVaultConfig(`
+ui = true
+
+listener "tcp" {
+  address     = "[::]:8200"
+  tls_disable = 1
+}
+
+storage "raft" {
+  path = "/vault/data"
+}
+`, "extraconfig-from-values.hcl")
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Listeners, 1)
+	assert.Equal(t, "tcp", cfg.Listeners[0].Type)
+	assert.True(t, cfg.Listeners[0].TLSDisabled())
+}
+
+func TestCheckRootTokenCredentialsError(t *testing.T) {
+	// unreadable credentials must not pass as a store without a root token
+	_, err := checkRootToken(context.Background(), nil, DoctorOptions{
+		CredentialsError: errors.New("could not decrypt credentials"),
+	})
+	assert.Error(t, err)
+
+	findings, err := checkRootToken(context.Background(), nil, DoctorOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, SeverityInfo, findings[0].Severity)
+}
@@ -0,0 +1,141 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/hashicorp/vault-client-go"
+)
+
+// IdentityGroup describes an external Vault identity group whose membership is determined by the group
+// alias Alias on an authentication method
+type IdentityGroup struct {
+	Name     string   `json:"name" yaml:"name"`
+	Alias    string   `json:"alias" yaml:"alias"`
+	Policies []string `json:"policies" yaml:"policies"`
+}
+
+// identityGroup is the subset of Vault's identity group response waltr reads
+type identityGroup struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Policies []string `json:"policies"`
+	Alias    struct {
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		MountAccessor string `json:"mount_accessor"`
+	} `json:"alias"`
+}
+
+// AuthMountAccessor returns the accessor of the authentication method mounted at mount
+func AuthMountAccessor(a *app.State, mount string) (string, error) {
+	res, err := a.VaultClient.System.AuthListEnabledMethods(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("could not list enabled Vault authentication methods: %v", err)
+	}
+
+	raw, ok := res.Data[strings.Trim(mount, "/")+"/"].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("authentication method: %s is not enabled", mount)
+	}
+
+	accessor, _ := raw["accessor"].(string)
+	if accessor == "" {
+		return "", fmt.Errorf("could not determine accessor of authentication method: %s", mount)
+	}
+
+	return accessor, nil
+}
+
+// readIdentityGroup reads the identity group with the given name. It returns nil if it doesn't exist.
+func readIdentityGroup(a *app.State, name string) (*identityGroup, error) {
+	res, err := a.VaultClient.Read(context.Background(), "identity/group/name/"+name)
+	if err != nil {
+		if vault.IsErrorStatus(err, http.StatusNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("could not read identity group: %s. Error: %v", name, err)
+	}
+
+	if res == nil || res.Data == nil {
+		return nil, nil
+	}
+
+	var g identityGroup
+	if err := decodeResponse(res.Data, &g); err != nil {
+		return nil, fmt.Errorf("could not decode identity group: %s. Error: %v", name, err)
+	}
+
+	return &g, nil
+}
+
+// EnsureExternalGroup creates or updates an external identity group with the IdentityGroup's policies
+// and points its alias at the authentication method with the given accessor. It returns true if
+// anything was changed.
+func EnsureExternalGroup(a *app.State, group IdentityGroup, accessor string) (bool, error) {
+	ctx := context.Background()
+	policies := removeEmpty(group.Policies)
+
+	g, err := readIdentityGroup(a, group.Name)
+	if err != nil {
+		return false, err
+	}
+
+	if g != nil && g.Type != "external" {
+		return false, fmt.Errorf("identity group: %s exists as %s group. Group aliases require external groups",
+			group.Name, g.Type)
+	}
+
+	var changed bool
+	if g == nil || !helpers.SameElements(g.Policies, policies) {
+		_, err := a.VaultClient.Write(ctx, "identity/group/name/"+group.Name, map[string]interface{}{
+			"type":     "external",
+			"policies": policies,
+		})
+		if err != nil {
+			return false, fmt.Errorf("could not write identity group: %s. Error: %v", group.Name, err)
+		}
+		changed = true
+
+		g, err = readIdentityGroup(a, group.Name)
+		if err != nil {
+			return false, err
+		}
+
+		if g == nil {
+			return false, fmt.Errorf("identity group: %s does not exist after writing it", group.Name)
+		}
+	}
+
+	alias := group.Alias
+	if alias == "" {
+		alias = group.Name
+	}
+
+	if g.Alias.Name == alias && g.Alias.MountAccessor == accessor {
+		return changed, nil
+	}
+
+	path := "identity/group-alias"
+	if g.Alias.ID != "" {
+		path = "identity/group-alias/id/" + g.Alias.ID
+	}
+
+	_, err = a.VaultClient.Write(ctx, path, map[string]interface{}{
+		"name":           alias,
+		"mount_accessor": accessor,
+		"canonical_id":   g.ID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("could not write alias: %s of identity group: %s. Error: %v", alias, group.Name,
+			err)
+	}
+
+	return true, nil
+}
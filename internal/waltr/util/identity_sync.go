@@ -0,0 +1,330 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of Vault identity resources
+const (
+	KindIdentityGroup  = "identity-group"
+	KindIdentityEntity = "identity-entity"
+)
+
+// IdentityUserClaims map the OIDC user claims supported by 'waltr identity sync' to the attribute of
+// Keycloak users they carry. Entity aliases must be named like the user claim to match OIDC logins.
+var IdentityUserClaims = map[string]func(u KeycloakUser) string{
+	"sub":                func(u KeycloakUser) string { return u.ID },
+	"preferred_username": func(u KeycloakUser) string { return u.Username },
+}
+
+// IdentityMapping is the file format of 'waltr identity sync'. It maps Keycloak groups to external Vault
+// identity groups and their policies.
+type IdentityMapping struct {
+	Mount  string                 `yaml:"mount"`
+	Groups []IdentityGroupMapping `yaml:"groups"`
+}
+
+// IdentityGroupMapping maps the Keycloak group with the given name or, if it starts with '/', path to an
+// external identity group. The identity group is named like the Keycloak group unless Name is set.
+type IdentityGroupMapping struct {
+	Keycloak string   `yaml:"keycloak"`
+	Name     string   `yaml:"name"`
+	Policies []string `yaml:"policies"`
+}
+
+// IdentitySyncOptions configure how IdentitySyncChanges maps Keycloak users to Vault identity entities
+type IdentitySyncOptions struct {
+	// UserClaim is the user claim of the OIDC role users log in with. It must be one of the
+	// IdentityUserClaims.
+	UserClaim string
+
+	// SkipEntities only syncs identity groups. Entities are then created by Vault on the users' first login.
+	SkipEntities bool
+}
+
+// identityEntity is the subset of Vault's identity entity response waltr reads
+type identityEntity struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Aliases []struct {
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		MountAccessor string `json:"mount_accessor"`
+	} `json:"aliases"`
+}
+
+// LoadIdentityMapping reads an IdentityMapping from the YAML file at path
+func LoadIdentityMapping(path string) (*IdentityMapping, error) {
+	raw, err := fs.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read identity mapping: %s. Error: %v", path, err)
+	}
+
+	return ParseIdentityMapping(raw)
+}
+
+// ParseIdentityMapping parses and validates a YAML IdentityMapping. The mount defaults to
+// DefaultOIDCMount.
+func ParseIdentityMapping(raw []byte) (*IdentityMapping, error) {
+	var m IdentityMapping
+	if err := yaml.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("cannot parse identity mapping: %v", err)
+	}
+
+	if m.Mount == "" {
+		m.Mount = DefaultOIDCMount
+	}
+	m.Mount = strings.Trim(m.Mount, "/")
+
+	seen := make(map[string]bool, len(m.Groups))
+	for _, g := range m.Groups {
+		if g.Keycloak == "" {
+			return nil, fmt.Errorf("identity group mapping without Keycloak group")
+		}
+
+		if seen[g.Keycloak] {
+			return nil, fmt.Errorf("duplicate identity group mapping of Keycloak group: %s", g.Keycloak)
+		}
+		seen[g.Keycloak] = true
+	}
+
+	return &m, nil
+}
+
+// ResolveIdentityGroups matches the mapped Keycloak groups with the groups of the realm. It returns the
+// resulting identity groups, whose aliases are the names of the Keycloak groups as carried by the
+// KeycloakGroupsClaim, and the members of each identity group.
+func ResolveIdentityGroups(m *IdentityMapping, groups []KeycloakGroup) ([]IdentityGroup,
+	map[string][]KeycloakUser, error) {
+	var resolved []IdentityGroup
+	members := make(map[string][]KeycloakUser, len(m.Groups))
+	aliases := make(map[string]string, len(m.Groups))
+
+	for _, mapping := range m.Groups {
+		var matches []KeycloakGroup
+		for _, g := range groups {
+			byName := !strings.HasPrefix(mapping.Keycloak, "/") && g.Name == mapping.Keycloak
+			if g.Path == mapping.Keycloak || byName {
+				matches = append(matches, g)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			return nil, nil, fmt.Errorf("keycloak group: %s does not exist", mapping.Keycloak)
+		case 1:
+		default:
+			return nil, nil, fmt.Errorf("keycloak group name: %s is ambiguous. Map it by its path instead",
+				mapping.Keycloak)
+		}
+
+		g := matches[0]
+		name := mapping.Name
+		if name == "" {
+			name = g.Name
+		}
+
+		if other, ok := aliases[g.Name]; ok {
+			return nil, nil, fmt.Errorf("identity groups: %s and %s share the group alias: %s. Keycloak group "+
+				"names must be unique to be distinguishable within the '%s' claim", other, name, g.Name,
+				KeycloakGroupsClaim)
+		}
+		aliases[g.Name] = name
+
+		if _, ok := members[name]; ok {
+			return nil, nil, fmt.Errorf("duplicate identity group: %s", name)
+		}
+
+		resolved = append(resolved, IdentityGroup{Name: name, Alias: g.Name, Policies: mapping.Policies})
+		members[name] = g.Members
+	}
+
+	return resolved, members, nil
+}
+
+// IdentitySyncChanges computes the Changes required to create or update the external identity groups of
+// the mapping, whose aliases live on the OIDC mount, and, unless skipped, the identity entities of their
+// members. Existing entities are found by their alias, so entities Vault created on login are kept.
+func IdentitySyncChanges(a *app.State, m *IdentityMapping, groups []KeycloakGroup,
+	opts IdentitySyncOptions) ([]Change, error) {
+	aliasName, ok := IdentityUserClaims[opts.UserClaim]
+	if !ok {
+		return nil, fmt.Errorf("unsupported user claim: %s. Must be one of: %v", opts.UserClaim,
+			sortedKeys(IdentityUserClaims))
+	}
+
+	resolved, members, err := ResolveIdentityGroups(m, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	accessor, err := AuthMountAccessor(a, m.Mount)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := Policies(a)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, group := range resolved {
+		for _, p := range group.Policies {
+			if !helpers.SliceContains(policies, p) {
+				a.Log.Warnf("policy: %s of identity group: %s does not exist", p, group.Name)
+			}
+		}
+
+		cur, err := readIdentityGroup(a, group.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		c := Change{
+			Action: Create,
+			Kind:   KindIdentityGroup,
+			Name:   group.Name,
+			After:  describeIdentityGroup("external", group.Alias+" on "+m.Mount, group.Policies),
+			apply: func(ctx context.Context) error {
+				_, err := EnsureExternalGroup(a, group, accessor)
+				return err
+			},
+		}
+
+		if cur != nil {
+			if cur.Type != "external" {
+				return nil, fmt.Errorf("identity group: %s exists as %s group. Group aliases require external "+
+					"groups", group.Name, cur.Type)
+			}
+
+			if helpers.SameElements(cur.Policies, removeEmpty(group.Policies)) && cur.Alias.Name == group.Alias &&
+				cur.Alias.MountAccessor == accessor {
+				continue
+			}
+
+			alias := orDash(cur.Alias.Name)
+			switch cur.Alias.MountAccessor {
+			case "":
+			case accessor:
+				alias += " on " + m.Mount
+			default:
+				alias += " on " + cur.Alias.MountAccessor
+			}
+
+			c.Action = Update
+			c.Before = describeIdentityGroup(cur.Type, alias, cur.Policies)
+		}
+
+		changes = append(changes, c)
+	}
+
+	if opts.SkipEntities {
+		return changes, nil
+	}
+
+	users := make(map[string]KeycloakUser)
+	for _, group := range resolved {
+		for _, u := range members[group.Name] {
+			users[u.Username] = u
+		}
+	}
+
+	for _, username := range sortedKeys(users) {
+		alias := aliasName(users[username])
+		if alias == "" {
+			return nil, fmt.Errorf("keycloak user: %s has no value for user claim: %s", username, opts.UserClaim)
+		}
+
+		exists, err := entityAliasExists(a, alias, accessor)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			continue
+		}
+
+		changes = append(changes, Change{
+			Action: Create,
+			Kind:   KindIdentityEntity,
+			Name:   username,
+			After:  describe(map[string]string{"alias": alias + " on " + m.Mount}),
+			apply: func(ctx context.Context) error {
+				return ensureEntity(ctx, a, username, alias, accessor)
+			},
+		})
+	}
+
+	return changes, nil
+}
+
+// describeIdentityGroup renders the attributes of an identity group for plans
+func describeIdentityGroup(typ, alias string, policies []string) string {
+	p := removeEmpty(policies)
+	sort.Strings(p)
+
+	return describe(map[string]string{
+		"type":     typ,
+		"alias":    alias,
+		"policies": fmt.Sprint(p),
+	})
+}
+
+// entityAliasExists reports whether an identity entity with the given alias on the authentication method
+// with the given accessor exists
+func entityAliasExists(a *app.State, alias, accessor string) (bool, error) {
+	res, err := a.VaultClient.Write(context.Background(), "identity/lookup/entity", map[string]interface{}{
+		"alias_name":           alias,
+		"alias_mount_accessor": accessor,
+	})
+	if err != nil {
+		return false, fmt.Errorf("could not look up identity entity with alias: %s. Error: %v", alias, err)
+	}
+
+	return res != nil && len(res.Data) > 0, nil
+}
+
+// ensureEntity creates the identity entity with the given name unless it exists and points its alias on
+// the authentication method with the given accessor at alias
+func ensureEntity(ctx context.Context, a *app.State, name, alias, accessor string) error {
+	if _, err := a.VaultClient.Write(ctx, "identity/entity/name/"+name, map[string]interface{}{}); err != nil {
+		return fmt.Errorf("could not write identity entity: %s. Error: %v", name, err)
+	}
+
+	res, err := a.VaultClient.Read(ctx, "identity/entity/name/"+name)
+	if err != nil {
+		return fmt.Errorf("could not read identity entity: %s. Error: %v", name, err)
+	}
+
+	var e identityEntity
+	if err := decodeResponse(res.Data, &e); err != nil {
+		return fmt.Errorf("could not decode identity entity: %s. Error: %v", name, err)
+	}
+
+	path := "identity/entity-alias"
+	for _, al := range e.Aliases {
+		if al.MountAccessor == accessor {
+			path = "identity/entity-alias/id/" + al.ID
+		}
+	}
+
+	_, err = a.VaultClient.Write(ctx, path, map[string]interface{}{
+		"name":           alias,
+		"mount_accessor": accessor,
+		"canonical_id":   e.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("could not write alias: %s of identity entity: %s. Error: %v", alias, name, err)
+	}
+
+	return nil
+}
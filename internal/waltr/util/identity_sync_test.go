@@ -0,0 +1,62 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveIdentityGroups(t *testing.T) {
+	mapping, err := ParseIdentityMapping([]byte(`
+groups:
+  - keycloak: vault-admins
+    policies: [admin]
+  - keycloak: /engineering/gitlab
+    name: gitlab-maintainers
+    policies: [gitlab]
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultOIDCMount, mapping.Mount)
+
+	alice := KeycloakUser{ID: "4f1c", Username: "alice"}
+	groups := []KeycloakGroup{
+		{Name: "vault-admins", Path: "/vault-admins", Members: []KeycloakUser{alice}},
+		{Name: "engineering", Path: "/engineering"},
+		{Name: "gitlab", Path: "/engineering/gitlab", Members: []KeycloakUser{alice, {ID: "9a2e", Username: "bob"}}},
+		{Name: "gitlab", Path: "/operations/gitlab"},
+	}
+
+	resolved, members, err := ResolveIdentityGroups(mapping, groups)
+	assert.NoError(t, err)
+	assert.Equal(t, []IdentityGroup{
+		{Name: "vault-admins", Alias: "vault-admins", Policies: []string{"admin"}},
+		{Name: "gitlab-maintainers", Alias: "gitlab", Policies: []string{"gitlab"}},
+	}, resolved)
+	assert.Len(t, members["gitlab-maintainers"], 2)
+
+	// group names are ambiguous across parents
+	ambiguous, err := ParseIdentityMapping([]byte("groups: [{keycloak: gitlab}]"))
+	assert.NoError(t, err)
+	_, _, err = ResolveIdentityGroups(ambiguous, groups)
+	assert.Error(t, err)
+
+	// both groups would share the alias 'gitlab' within the groups claim
+	shared, err := ParseIdentityMapping([]byte(`
+groups:
+  - {keycloak: /engineering/gitlab, name: a}
+  - {keycloak: /operations/gitlab, name: b}
+`))
+	assert.NoError(t, err)
+	_, _, err = ResolveIdentityGroups(shared, groups)
+	assert.Error(t, err)
+
+	missing, err := ParseIdentityMapping([]byte("groups: [{keycloak: grafana-admins}]"))
+	assert.NoError(t, err)
+	_, _, err = ResolveIdentityGroups(missing, groups)
+	assert.Error(t, err)
+}
+
+func TestParseIdentityMappingDuplicate(t *testing.T) {
+	_, err := ParseIdentityMapping([]byte("groups: [{keycloak: a}, {keycloak: a}]"))
+	assert.Error(t, err)
+}
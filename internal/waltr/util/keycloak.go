@@ -0,0 +1,268 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+)
+
+const (
+	// KeycloakPasswordEnv is the environment variable the Keycloak admin password is read from if the
+	// corresponding flag is unset
+	KeycloakPasswordEnv = "WALTR_KEYCLOAK_PASSWORD"
+
+	// KeycloakGroupsClaim is the claim the Keycloak group memberships are mapped to
+	KeycloakGroupsClaim = "groups"
+
+	// keycloakPageSize is the maximum amount of groups and members retrieved at once
+	keycloakPageSize = 10000
+)
+
+// KeycloakOptions are the connection details of the Keycloak instance managing an OIDC realm
+type KeycloakOptions struct {
+	// URL is the base URL of Keycloak, which must be reachable by waltr, Vault and users' browsers
+	URL string
+
+	// Username and Password are the credentials of a Keycloak administrator within AdminRealm
+	Username   string
+	Password   string
+	AdminRealm string
+
+	// Realm is the realm Vault users authenticate with
+	Realm string
+}
+
+// Keycloak is a Keycloak admin API client which is logged into the administrator's realm
+type Keycloak struct {
+	Client *gocloak.GoCloak
+	Realm  string
+	URL    string
+
+	token string
+}
+
+// KeycloakGroup is a (sub-)group of a Keycloak realm and its direct members
+type KeycloakGroup struct {
+	ID      string
+	Name    string
+	Path    string
+	Members []KeycloakUser
+}
+
+// KeycloakUser is a user of a Keycloak realm
+type KeycloakUser struct {
+	ID       string
+	Username string
+}
+
+// NewKeycloak logs into Keycloak with the given KeycloakOptions. The password falls back to
+// KeycloakPasswordEnv.
+func NewKeycloak(ctx context.Context, opts KeycloakOptions) (*Keycloak, error) {
+	if opts.URL == "" || opts.Realm == "" {
+		return nil, fmt.Errorf("the Keycloak URL and realm are required")
+	}
+
+	if opts.Password == "" {
+		opts.Password = os.Getenv(KeycloakPasswordEnv)
+	}
+
+	if opts.Password == "" {
+		return nil, fmt.Errorf("can't login to Keycloak without password. Set the password option or %s",
+			KeycloakPasswordEnv)
+	}
+
+	url := strings.TrimSuffix(opts.URL, "/")
+	client := gocloak.NewClient(url)
+	jwt, err := client.LoginAdmin(ctx, opts.Username, opts.Password, opts.AdminRealm)
+	if err != nil {
+		return nil, fmt.Errorf("could not login to Keycloak as: %s. Error: %v", opts.Username, err)
+	}
+
+	return &Keycloak{
+		Client: client,
+		Realm:  opts.Realm,
+		URL:    url,
+		token:  jwt.AccessToken,
+	}, nil
+}
+
+// IssuerURL returns the OIDC issuer (and discovery) URL of the Keycloak realm
+func (k *Keycloak) IssuerURL() string {
+	return fmt.Sprintf("%s/realms/%s", k.URL, k.Realm)
+}
+
+// EnsureClient creates a confidential OpenID Connect client with the given redirect URIs or adds
+// missing redirect URIs to an existing one. Group memberships are mapped to the KeycloakGroupsClaim.
+// It returns the client's secret.
+func (k *Keycloak) EnsureClient(ctx context.Context, clientID string, redirectURIs []string) (string, error) {
+	clients, err := k.Client.GetClients(ctx, k.token, k.Realm, gocloak.GetClientsParams{
+		ClientID: gocloak.StringP(clientID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not list Keycloak clients: %v", err)
+	}
+
+	var id string
+	switch len(clients) {
+	case 0:
+		id, err = k.Client.CreateClient(ctx, k.token, k.Realm, gocloak.Client{
+			ClientID:                  gocloak.StringP(clientID),
+			Name:                      gocloak.StringP("HashiCorp Vault"),
+			Enabled:                   gocloak.BoolP(true),
+			Protocol:                  gocloak.StringP("openid-connect"),
+			PublicClient:              gocloak.BoolP(false),
+			StandardFlowEnabled:       gocloak.BoolP(true),
+			DirectAccessGrantsEnabled: gocloak.BoolP(false),
+			RedirectURIs:              &redirectURIs,
+			ProtocolMappers: &[]gocloak.ProtocolMapperRepresentation{
+				groupsMapper(),
+			},
+		})
+		if err != nil {
+			return "", fmt.Errorf("could not create Keycloak client: %s. Error: %v", clientID, err)
+		}
+	default:
+		client := clients[0]
+		id = gocloak.PString(client.ID)
+
+		var uris []string
+		if client.RedirectURIs != nil {
+			uris = *client.RedirectURIs
+		}
+
+		merged := helpers.RemoveDuplicates(append(append([]string{}, uris...), redirectURIs...))
+		if !helpers.SameElements(uris, merged) {
+			client.RedirectURIs = &merged
+			if err := k.Client.UpdateClient(ctx, k.token, k.Realm, *client); err != nil {
+				return "", fmt.Errorf("could not update Keycloak client: %s. Error: %v", clientID, err)
+			}
+		}
+
+		if !hasGroupsMapper(client.ProtocolMappers) {
+			_, err := k.Client.CreateClientProtocolMapper(ctx, k.token, k.Realm, id, groupsMapper())
+			if err != nil {
+				return "", fmt.Errorf("could not add groups mapper to Keycloak client: %s. Error: %v", clientID,
+					err)
+			}
+		}
+	}
+
+	cred, err := k.Client.GetClientSecret(ctx, k.token, k.Realm, id)
+	if err != nil {
+		return "", fmt.Errorf("could not read secret of Keycloak client: %s. Error: %v", clientID, err)
+	}
+
+	return gocloak.PString(cred.Value), nil
+}
+
+// EnsureGroup creates a top-level group within the realm unless it exists. It returns true if the
+// group was created.
+func (k *Keycloak) EnsureGroup(ctx context.Context, name string) (bool, error) {
+	groups, err := k.Groups(ctx, false)
+	if err != nil {
+		return false, err
+	}
+
+	for _, g := range groups {
+		if g.Name == name {
+			return false, nil
+		}
+	}
+
+	_, err = k.Client.CreateGroup(ctx, k.token, k.Realm, gocloak.Group{Name: gocloak.StringP(name)})
+	if err != nil {
+		return false, fmt.Errorf("could not create Keycloak group: %s. Error: %v", name, err)
+	}
+
+	return true, nil
+}
+
+// Groups lists all groups of the realm including their sub-groups. If members is set every group's
+// direct members are retrieved as well.
+func (k *Keycloak) Groups(ctx context.Context, members bool) ([]KeycloakGroup, error) {
+	groups, err := k.Client.GetGroups(ctx, k.token, k.Realm, gocloak.GetGroupsParams{
+		Max: gocloak.IntP(keycloakPageSize),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list Keycloak groups of realm: %s. Error: %v", k.Realm, err)
+	}
+
+	var flat []KeycloakGroup
+	var walk func(groups []gocloak.Group)
+	walk = func(groups []gocloak.Group) {
+		for _, g := range groups {
+			flat = append(flat, KeycloakGroup{
+				ID:   gocloak.PString(g.ID),
+				Name: gocloak.PString(g.Name),
+				Path: gocloak.PString(g.Path),
+			})
+
+			if g.SubGroups != nil {
+				walk(*g.SubGroups)
+			}
+		}
+	}
+
+	top := make([]gocloak.Group, 0, len(groups))
+	for _, g := range groups {
+		top = append(top, *g)
+	}
+	walk(top)
+
+	if !members {
+		return flat, nil
+	}
+
+	for i, g := range flat {
+		users, err := k.Client.GetGroupMembers(ctx, k.token, k.Realm, g.ID, gocloak.GetGroupsParams{
+			Max: gocloak.IntP(keycloakPageSize),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not list members of Keycloak group: %s. Error: %v", g.Path, err)
+		}
+
+		for _, u := range users {
+			flat[i].Members = append(flat[i].Members, KeycloakUser{
+				ID:       gocloak.PString(u.ID),
+				Username: gocloak.PString(u.Username),
+			})
+		}
+	}
+
+	return flat, nil
+}
+
+// groupsMapper maps the group memberships of users to the KeycloakGroupsClaim of their tokens
+func groupsMapper() gocloak.ProtocolMapperRepresentation {
+	return gocloak.ProtocolMapperRepresentation{
+		Name:           gocloak.StringP(KeycloakGroupsClaim),
+		Protocol:       gocloak.StringP("openid-connect"),
+		ProtocolMapper: gocloak.StringP("oidc-group-membership-mapper"),
+		Config: &map[string]string{
+			"claim.name":           KeycloakGroupsClaim,
+			"full.path":            "false",
+			"id.token.claim":       "true",
+			"access.token.claim":   "true",
+			"userinfo.token.claim": "true",
+		},
+	}
+}
+
+// hasGroupsMapper reports whether the given protocol mappers contain a groupsMapper
+func hasGroupsMapper(mappers *[]gocloak.ProtocolMapperRepresentation) bool {
+	if mappers == nil {
+		return false
+	}
+
+	for _, m := range *mappers {
+		if gocloak.PString(m.ProtocolMapper) == "oidc-group-membership-mapper" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,395 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/tools"
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultKVMount is the mount path of the KV-v2 secrets engine 'waltr mounts' enables
+const DefaultKVMount = "kv"
+
+// KVExport is the file format of 'waltr kv export'. It holds a subtree of a KV-v2 secrets engine.
+type KVExport struct {
+	Mount    string     `yaml:"mount"`
+	Path     string     `yaml:"path"`
+	Exported time.Time  `yaml:"exported"`
+	Secrets  []KVSecret `yaml:"secrets"`
+}
+
+// KVSecret is a single secret within a KVExport. Versions are sorted in ascending order.
+type KVSecret struct {
+	Path     string      `yaml:"path"`
+	Versions []KVVersion `yaml:"versions"`
+}
+
+// KVVersion is a single version of a KVSecret
+type KVVersion struct {
+	Version int                    `yaml:"version"`
+	Created string                 `yaml:"created,omitempty"`
+	Data    map[string]interface{} `yaml:"data"`
+}
+
+// Latest returns the most recent version of the KVSecret
+func (s KVSecret) Latest() KVVersion {
+	if len(s.Versions) == 0 {
+		return KVVersion{}
+	}
+
+	return s.Versions[len(s.Versions)-1]
+}
+
+// ListKV recursively lists the paths of all secrets below prefix within the KV-v2 mount
+func ListKV(a *app.State, mount, prefix string) ([]string, error) {
+	prefix = strings.Trim(prefix, "/")
+
+	res, err := a.VaultClient.Secrets.KvV2List(context.Background(), prefix, vault.WithMountPath(mount))
+	if err != nil {
+		if vault.IsErrorStatus(err, http.StatusNotFound) {
+			return []string{}, nil
+		}
+
+		return nil, fmt.Errorf("could not list KV secrets at: %s/%s. Error: %v", mount, prefix, err)
+	}
+
+	var paths []string
+	for _, k := range res.Data.Keys {
+		p := path.Join(prefix, k)
+		if !strings.HasSuffix(k, "/") {
+			paths = append(paths, p)
+			continue
+		}
+
+		children, err := ListKV(a, mount, p)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, children...)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ReadKV reads the latest version of the secret at p within the KV-v2 mount. It returns false if the
+// secret doesn't exist or its latest version is deleted.
+func ReadKV(a *app.State, mount, p string) (map[string]interface{}, bool, error) {
+	v, err := readKVVersion(a, mount, p, 0)
+	if err != nil || v == nil {
+		return nil, false, err
+	}
+
+	return v.Data, true, nil
+}
+
+// WriteKV writes data as a new version of the secret at p within the KV-v2 mount
+func WriteKV(a *app.State, mount, p string, data map[string]interface{}) error {
+	_, err := a.VaultClient.Secrets.KvV2Write(context.Background(), p, schema.KvV2WriteRequest{
+		Data: data,
+	}, vault.WithMountPath(mount))
+	if err != nil {
+		return fmt.Errorf("could not write KV secret: %s/%s. Error: %v", mount, p, err)
+	}
+
+	return nil
+}
+
+// readKVVersion reads a single version of the secret at p. Version 0 reads the latest version. It
+// returns nil if the version doesn't exist or is deleted.
+func readKVVersion(a *app.State, mount, p string, version int) (*KVVersion, error) {
+	opts := []vault.RequestOption{vault.WithMountPath(mount)}
+	if version > 0 {
+		opts = append(opts, vault.WithQueryParameters(url.Values{"version": {strconv.Itoa(version)}}))
+	}
+
+	res, err := a.VaultClient.Secrets.KvV2Read(context.Background(), p, opts...)
+	if err != nil {
+		if vault.IsErrorStatus(err, http.StatusNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("could not read KV secret: %s/%s. Error: %v", mount, p, err)
+	}
+
+	if res.Data.Data == nil {
+		return nil, nil
+	}
+
+	var meta struct {
+		Version int    `json:"version"`
+		Created string `json:"created_time"`
+	}
+	if err := decodeResponse(res.Data.Metadata, &meta); err != nil {
+		return nil, fmt.Errorf("could not decode KV metadata: %s/%s. Error: %v", mount, p, err)
+	}
+
+	return &KVVersion{Version: meta.Version, Created: meta.Created, Data: res.Data.Data}, nil
+}
+
+// kvVersions returns the readable versions of the secret at p in ascending order. Deleted and
+// destroyed versions are omitted, since their data cannot be read.
+func kvVersions(a *app.State, mount, p string) ([]KVVersion, error) {
+	res, err := a.VaultClient.Secrets.KvV2ReadMetadata(context.Background(), p, vault.WithMountPath(mount))
+	if err != nil {
+		return nil, fmt.Errorf("could not read KV metadata: %s/%s. Error: %v", mount, p, err)
+	}
+
+	var versions []KVVersion
+	for k, v := range res.Data.Versions {
+		n, err := strconv.Atoi(k)
+		if err != nil {
+			continue
+		}
+
+		var meta struct {
+			Created   string `json:"created_time"`
+			Deleted   string `json:"deletion_time"`
+			Destroyed bool   `json:"destroyed"`
+		}
+		if raw, ok := v.(map[string]interface{}); ok {
+			if err := decodeResponse(raw, &meta); err != nil {
+				return nil, err
+			}
+		}
+
+		if meta.Destroyed || meta.Deleted != "" {
+			a.Log.Debugf("skipping deleted version %d of KV secret: %s/%s", n, mount, p)
+			continue
+		}
+
+		versions = append(versions, KVVersion{Version: n, Created: meta.Created})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+
+	return versions, nil
+}
+
+// ExportKV reads all secrets below prefix within the KV-v2 mount. If allVersions is unset only the
+// latest version of every secret is exported.
+func ExportKV(a *app.State, mount, prefix string, allVersions bool) (*KVExport, error) {
+	paths, err := ListKV(a, mount, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// the prefix may be a secret itself
+	if len(paths) == 0 && prefix != "" {
+		paths = []string{strings.Trim(prefix, "/")}
+	}
+
+	export := &KVExport{
+		Mount:    mount,
+		Path:     strings.Trim(prefix, "/"),
+		Exported: time.Now().UTC(),
+	}
+
+	for _, p := range paths {
+		versions := []KVVersion{{}}
+		if allVersions {
+			versions, err = kvVersions(a, mount, p)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		secret := KVSecret{Path: p}
+		for _, v := range versions {
+			version, err := readKVVersion(a, mount, p, v.Version)
+			if err != nil {
+				return nil, err
+			}
+
+			if version != nil {
+				secret.Versions = append(secret.Versions, *version)
+			}
+		}
+
+		if len(secret.Versions) == 0 {
+			a.Log.Debugf("skipping KV secret without readable versions: %s/%s", mount, p)
+			continue
+		}
+
+		export.Secrets = append(export.Secrets, secret)
+	}
+
+	return export, nil
+}
+
+// ImportKV writes the secrets of a KVExport to the KV-v2 mount, relocating them from the export's path
+// to prefix if it is set. Secrets which don't exist yet receive every exported version in order, existing
+// secrets only receive the latest version and only if it differs. It returns the imported paths.
+func ImportKV(a *app.State, export *KVExport, mount, prefix string) ([]string, error) {
+	var imported []string
+	for _, s := range export.Secrets {
+		p := RelocateKVPath(s.Path, export.Path, prefix)
+
+		current, exists, err := ReadKV(a, mount, p)
+		if err != nil {
+			return nil, err
+		}
+
+		versions := s.Versions
+		if exists {
+			if EqualKVData(current, s.Latest().Data) {
+				a.Log.Debugf("KV secret: %s/%s is up-to-date - skipping", mount, p)
+				continue
+			}
+
+			versions = []KVVersion{s.Latest()}
+		}
+
+		for _, v := range versions {
+			if err := WriteKV(a, mount, p, v.Data); err != nil {
+				return nil, err
+			}
+		}
+
+		imported = append(imported, p)
+	}
+
+	return imported, nil
+}
+
+// RelocateKVPath moves the secret path p from the from prefix to the to prefix. An empty to keeps p.
+func RelocateKVPath(p, from, to string) string {
+	if to == "" {
+		return p
+	}
+
+	from = strings.Trim(from, "/")
+	rel := strings.Trim(strings.TrimPrefix(p, from), "/")
+	if from == "" {
+		rel = p
+	}
+
+	return path.Join(strings.Trim(to, "/"), rel)
+}
+
+// EqualKVData compares secret data independent of the types YAML and JSON decode numbers to
+func EqualKVData(a, b map[string]interface{}) bool {
+	return reflect.DeepEqual(normalizeKVData(a), normalizeKVData(b))
+}
+
+// normalizeKVData converts data to the types the Vault API returns
+func normalizeKVData(data map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return data
+	}
+
+	return out
+}
+
+// WriteKVExport writes a KVExport as YAML to path. Unless unencrypted is set the file is encrypted with
+// the Helm Secrets Plugin afterwards, so that the '.sops.yaml' creation rules for path apply.
+func WriteKVExport(path string, export *KVExport, unencrypted bool) error {
+	yml, err := yaml.Marshal(export)
+	if err != nil {
+		return fmt.Errorf("cannot marshal KV export: %v", err)
+	}
+
+	if err := fs.Write(path, yml); err != nil {
+		return fmt.Errorf("cannot write KV export to file: %s. Error: %v", path, err)
+	}
+
+	if unencrypted {
+		return nil
+	}
+
+	if err := tools.EncryptFile(path); err != nil {
+		// never leave the plaintext secrets behind
+		_ = os.Remove(path)
+		return fmt.Errorf("could not encrypt KV export: %s. Error: %v", path, err)
+	}
+
+	return nil
+}
+
+// ReadKVExport reads a KVExport from path, decrypting it if required
+func ReadKVExport(path string) (*KVExport, error) {
+	raw, err := readSecretsFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var export KVExport
+	if err := yaml.Unmarshal(raw, &export); err != nil {
+		return nil, fmt.Errorf("cannot parse KV export: %s. Error: %v", path, err)
+	}
+
+	return &export, nil
+}
+
+// readSecretsFile reads the file at path. SOPS-encrypted files are decrypted within a temporary copy,
+// so the plaintext never replaces the original file.
+func readSecretsFile(path string) ([]byte, error) {
+	encrypted, err := tools.IsEncryptedFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if encrypted {
+		path, err = decryptedCopy(path)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(path)
+	}
+
+	return fs.Read(path)
+}
+
+// decryptedCopy copies the SOPS-encrypted file at path to a temporary file with the same extension
+// and decrypts it. The caller is responsible for removing the copy.
+func decryptedCopy(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	tmp, err := fs.TempFile("waltr-*" + filepath.Ext(path))
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if err := tools.DecryptFile(tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("could not decrypt file: %s. Error: %v", path, err)
+	}
+
+	return tmp.Name(), nil
+}
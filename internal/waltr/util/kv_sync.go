@@ -0,0 +1,220 @@
+package util
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// KindKVSecret is the kind of Changes to secrets within a KV-v2 secrets engine
+	KindKVSecret = "kv-secret"
+
+	// ReleaseValuesSecret is the secret scalar values directly below 'secrets.<release>' are synced to
+	ReleaseValuesSecret = "values"
+)
+
+// ReservedSecretKeys are the keys below 'secrets' which waltr itself writes to helm-secrets files, i.e. the
+// root token and issued tokens below 'secrets.vault' and the credential store below 'secrets.credentials'.
+// They are never synced to Vault.
+var ReservedSecretKeys = []string{"vault", "credentials"}
+
+// SyncOptions configure how KVSyncChanges compares the live KV secrets with a helm-secrets file
+type SyncOptions struct {
+	// Mount is the mount path of the KV-v2 secrets engine
+	Mount string
+
+	// Prune removes keys from live secrets which are not part of the helm-secrets file
+	Prune bool
+}
+
+// ReleaseSecrets maps the 'secrets.<release>' subtrees of helm-secrets values to KV secrets below
+// '<release>/', matching the paths ConfigReleasePolicyTemplate grants access to. Every map becomes a
+// secret holding its non-map values, nested maps become nested secrets and scalar values directly below
+// the release are collected within the ReleaseValuesSecret. The ReservedSecretKeys are skipped and cannot be
+// requested as releases.
+func ReleaseSecrets(values map[string]interface{}, releases []string) (map[string]map[string]interface{}, error) {
+	root, ok := values["secrets"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("values do not contain a 'secrets' map")
+	}
+
+	for _, r := range releases {
+		if helpers.SliceContains(ReservedSecretKeys, r) {
+			return nil, fmt.Errorf("'secrets.%s' is reserved for waltr's own credentials and cannot be synced", r)
+		}
+	}
+
+	secrets := make(map[string]map[string]interface{})
+	for release, v := range root {
+		if helpers.SliceContains(ReservedSecretKeys, release) {
+			continue
+		}
+
+		if len(releases) > 0 && !helpers.SliceContains(releases, release) {
+			continue
+		}
+
+		tree, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid value of 'secrets.%s': expected a map", release)
+		}
+
+		if err := collectSecrets(secrets, release, tree, true); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range releases {
+		if _, ok := root[r]; !ok {
+			return nil, fmt.Errorf("values do not contain secrets for release: %s", r)
+		}
+	}
+
+	return secrets, nil
+}
+
+// collectSecrets adds the secret at p to secrets and recurses into its nested maps
+func collectSecrets(secrets map[string]map[string]interface{}, p string, tree map[string]interface{},
+	release bool) error {
+	data := make(map[string]interface{})
+	for k, v := range tree {
+		if nested, ok := v.(map[string]interface{}); ok {
+			if err := collectSecrets(secrets, path.Join(p, k), nested, false); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data[k] = v
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	if release {
+		if _, ok := tree[ReleaseValuesSecret].(map[string]interface{}); ok {
+			return fmt.Errorf("'secrets.%s.%s' collides with the secret: %s/%s holding the scalar values of "+
+				"the release. Rename it or move the scalar values into a map", p, ReleaseValuesSecret, p,
+				ReleaseValuesSecret)
+		}
+
+		p = path.Join(p, ReleaseValuesSecret)
+	}
+
+	secrets[p] = data
+	return nil
+}
+
+// ReadReleaseSecrets reads and, if required, decrypts the helm-secrets file at path and maps it to KV
+// secrets with ReleaseSecrets
+func ReadReleaseSecrets(path string, releases []string) (map[string]map[string]interface{}, error) {
+	raw, err := readSecretsFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(raw, &values); err != nil {
+		return nil, fmt.Errorf("cannot parse secrets file: %s. Error: %v", path, err)
+	}
+
+	return ReleaseSecrets(values, releases)
+}
+
+// KVSyncChanges computes the Changes required to write the secrets of a helm-secrets file to the KV-v2
+// mount. Secrets whose data already matches don't produce a Change, so applying the result writes only
+// differences. Keys which only exist in Vault are kept unless SyncOptions.Prune is set.
+func KVSyncChanges(a *app.State, secrets map[string]map[string]interface{}, opts SyncOptions) ([]Change, error) {
+	key, err := fingerprintKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+
+	for _, p := range sortedKeys(secrets) {
+		current, exists, err := ReadKV(a, opts.Mount, p)
+		if err != nil {
+			return nil, err
+		}
+
+		wanted := make(map[string]interface{})
+		if exists && !opts.Prune {
+			for k, v := range current {
+				wanted[k] = v
+			}
+		}
+
+		for k, v := range secrets[p] {
+			wanted[k] = v
+		}
+
+		if exists && EqualKVData(current, wanted) {
+			continue
+		}
+
+		c := Change{
+			Action: Create,
+			Kind:   KindKVSecret,
+			Name:   path.Join(opts.Mount, p),
+			After:  describeSecret(wanted, key),
+			apply: func(ctx context.Context) error {
+				return WriteKV(a, opts.Mount, p, wanted)
+			},
+		}
+
+		if exists {
+			c.Action = Update
+			c.Before = describeSecret(current, key)
+		}
+
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+// describeSecret renders secret data as sorted 'key = fingerprint' lines, so that plans show which
+// values change without revealing them
+func describeSecret(data map[string]interface{}, key []byte) string {
+	fields := make(map[string]string, len(data))
+	for k, v := range normalizeKVData(data) {
+		fields[k] = fingerprint(v, key)
+	}
+
+	return describe(fields)
+}
+
+// fingerprintKey generates a random key for fingerprint, valid for a single plan
+func fingerprintKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("could not generate fingerprint key: %v", err)
+	}
+
+	return key, nil
+}
+
+// fingerprint returns a short HMAC-SHA-256 of the JSON encoding of v. It's keyed with a random
+// fingerprintKey, so fingerprints only compare values within a single plan and cannot be used to confirm
+// guesses of the values offline.
+func fingerprint(v interface{}, key []byte) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		raw = []byte(fmt.Sprint(v))
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(raw)
+	return fmt.Sprintf("hmac:%x", mac.Sum(nil))[:13]
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const releaseSecretsYAML = `
+secrets:
+  gitlab:
+    rootPassword: hunter2
+    postgresql:
+      password: s3cr3t
+      port: 5432
+      replication:
+        password: r3pl
+  harbor:
+    adminPassword: harbor
+  vault:
+    token: hvs.root
+    tokens:
+      ci: hvs.ci
+  credentials:
+    vault: ZW5jcnlwdGVk
+`
+
+func TestReleaseSecrets(t *testing.T) {
+	var values map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal([]byte(releaseSecretsYAML), &values))
+
+	secrets, err := ReleaseSecrets(values, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"gitlab/values":                 {"rootPassword": "hunter2"},
+		"gitlab/postgresql":             {"password": "s3cr3t", "port": 5432},
+		"gitlab/postgresql/replication": {"password": "r3pl"},
+		"harbor/values":                 {"adminPassword": "harbor"},
+	}, secrets)
+
+	// waltr's own credentials are never synced
+	for _, r := range ReservedSecretKeys {
+		_, err = ReleaseSecrets(values, []string{r})
+		assert.Error(t, err)
+	}
+
+	secrets, err = ReleaseSecrets(values, []string{"harbor"})
+	assert.NoError(t, err)
+	assert.Len(t, secrets, 1)
+
+	_, err = ReleaseSecrets(values, []string{"keycloak"})
+	assert.Error(t, err)
+
+	// a nested 'values' map collides with the scalar values of the release
+	_, err = ReleaseSecrets(map[string]interface{}{
+		"secrets": map[string]interface{}{
+			"gitlab": map[string]interface{}{
+				"rootPassword": "hunter2",
+				"values":       map[string]interface{}{"password": "s3cr3t"},
+			},
+		},
+	}, nil)
+	assert.Error(t, err)
+}
+
+func TestDescribeSecret(t *testing.T) {
+	key, err := fingerprintKey()
+	assert.NoError(t, err)
+
+	described := describeSecret(map[string]interface{}{"password": "s3cr3t", "port": 5432}, key)
+	assert.NotContains(t, described, "s3cr3t")
+	assert.Contains(t, described, "password = \"hmac:")
+	assert.NotContains(t, described, "sha256:")
+	assert.Equal(t, described, describeSecret(map[string]interface{}{"password": "s3cr3t", "port": float64(5432)}, key))
+
+	other, err := fingerprintKey()
+	assert.NoError(t, err)
+	assert.NotEqual(t, described, describeSecret(map[string]interface{}{"password": "s3cr3t", "port": 5432}, other))
+}
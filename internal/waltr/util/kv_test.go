@@ -0,0 +1,22 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelocateKVPath(t *testing.T) {
+	assert.Equal(t, "gitlab/db", RelocateKVPath("gitlab/db", "gitlab", ""))
+	assert.Equal(t, "staging/gitlab/db", RelocateKVPath("gitlab/db", "gitlab", "staging/gitlab"))
+	assert.Equal(t, "staging/gitlab", RelocateKVPath("gitlab", "gitlab", "/staging/gitlab/"))
+	assert.Equal(t, "staging/gitlab/db", RelocateKVPath("gitlab/db", "", "staging"))
+}
+
+func TestEqualKVData(t *testing.T) {
+	fromYAML := map[string]interface{}{"port": 5432, "nested": map[string]interface{}{"user": "gitlab"}}
+	fromVault := map[string]interface{}{"port": float64(5432), "nested": map[string]interface{}{"user": "gitlab"}}
+
+	assert.True(t, EqualKVData(fromYAML, fromVault))
+	assert.False(t, EqualKVData(fromYAML, map[string]interface{}{"port": "5432"}))
+}
@@ -0,0 +1,134 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ManifestResources are the resources of the kinds waltr generates manifests for
+var ManifestResources = map[string]schema.GroupVersionResource{
+	"ServiceAccount":    {Group: "", Version: "v1", Resource: "serviceaccounts"},
+	"Role":              {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"},
+	"RoleBinding":       {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"},
+	"VaultConnection":   {Group: VSOGroup, Version: VSOVersion, Resource: "vaultconnections"},
+	"VaultAuth":         {Group: VSOGroup, Version: VSOVersion, Resource: "vaultauths"},
+	"VaultStaticSecret": {Group: VSOGroup, Version: VSOVersion, Resource: "vaultstaticsecrets"},
+	"Issuer":            {Group: CertManagerGroup, Version: "v1", Resource: "issuers"},
+	"ClusterIssuer":     {Group: CertManagerGroup, Version: "v1", Resource: "clusterissuers"},
+}
+
+// manifestObject builds a Kubernetes object labeled as managed by waltr. The fields, e.g. 'spec', are
+// added to the object's top-level. Cluster-scoped objects have an empty namespace.
+func manifestObject(apiVersion, kind, name, namespace string,
+	fields map[string]interface{}) *unstructured.Unstructured {
+	metadata := map[string]interface{}{
+		"name": name,
+		"labels": map[string]interface{}{
+			"app.kubernetes.io/managed-by": app.Name,
+		},
+	}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+
+	obj := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   metadata,
+	}
+	for k, v := range fields {
+		obj[k] = v
+	}
+
+	return &unstructured.Unstructured{Object: obj}
+}
+
+// invalidNameChars matches characters which are not allowed within Kubernetes resource names
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// manifestName converts a Vault path to a valid Kubernetes resource name
+func manifestName(p string) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(p), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-")
+	}
+
+	return name
+}
+
+// WriteManifests writes the given objects to w as a multi-document YAML stream
+func WriteManifests(w io.Writer, objs []*unstructured.Unstructured) error {
+	for i, o := range objs {
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+
+		raw, err := yaml.Marshal(o.Object)
+		if err != nil {
+			return fmt.Errorf("cannot marshal %s: %s. Error: %v", o.GetKind(), o.GetName(), err)
+		}
+
+		if _, err := w.Write(raw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteManifestFiles writes every object to '<dir>/<namespace>/<kind>-<name>.yaml' and returns the
+// written paths in order. Cluster-scoped objects are written to '<dir>/cluster'.
+func WriteManifestFiles(dir string, objs []*unstructured.Unstructured) ([]string, error) {
+	var paths []string
+	for _, o := range objs {
+		raw, err := yaml.Marshal(o.Object)
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal %s: %s. Error: %v", o.GetKind(), o.GetName(), err)
+		}
+
+		ns := o.GetNamespace()
+		if ns == "" {
+			ns = "cluster"
+		}
+
+		p := filepath.Join(dir, ns, fmt.Sprintf("%s-%s.yaml", strings.ToLower(o.GetKind()), o.GetName()))
+		if err := fs.Write(p, raw); err != nil {
+			return nil, fmt.Errorf("cannot write manifest: %s. Error: %v", p, err)
+		}
+
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ApplyManifests creates or updates the given objects within the cluster
+func ApplyManifests(a *app.State, objs []*unstructured.Unstructured) error {
+	for _, o := range objs {
+		gvr, ok := ManifestResources[o.GetKind()]
+		if !ok {
+			return fmt.Errorf("unsupported resource kind: %s", o.GetKind())
+		}
+
+		if err := a.Kube.Apply(gvr, o, nil); err != nil {
+			return fmt.Errorf("could not apply %s: %s/%s. Error: %v", o.GetKind(), o.GetNamespace(),
+				o.GetName(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/hashicorp/vault-client-go/schema"
+)
+
+const (
+	// DefaultOIDCMount is the mount path of the OIDC authentication method 'waltr mounts' enables
+	DefaultOIDCMount = "oidc"
+
+	// OIDCCLICallback is the redirect URI 'vault login -method=oidc' listens on
+	OIDCCLICallback = "http://localhost:8250/oidc/callback"
+)
+
+// OIDCOptions configure the OIDC authentication method and its default role
+type OIDCOptions struct {
+	Mount        string
+	DiscoveryURL string
+	ClientID     string
+	ClientSecret string
+
+	// Role is the name of the default role users log in with
+	Role         string
+	RedirectURIs []string
+	UserClaim    string
+	GroupsClaim  string
+	Scopes       []string
+	Policies     []string
+}
+
+// OIDCRedirectURIs returns the redirect URIs of the Vault CLI and, if the external URL of Vault is
+// known, the Vault UI for the OIDC authentication method at mount
+func OIDCRedirectURIs(vaultURL, mount string) []string {
+	uris := []string{OIDCCLICallback}
+	if vaultURL != "" {
+		uris = append(uris, fmt.Sprintf("%s/ui/vault/auth/%s/oidc/callback", strings.TrimSuffix(vaultURL, "/"),
+			strings.Trim(mount, "/")))
+	}
+
+	return uris
+}
+
+// ConfigureOIDC enables the OIDC authentication method unless it is enabled already, configures its
+// provider and writes its default role
+func ConfigureOIDC(a *app.State, opts OIDCOptions) error {
+	ctx := context.Background()
+	mount := strings.Trim(opts.Mount, "/")
+
+	methods, err := AuthMethods(a)
+	if err != nil {
+		return err
+	}
+
+	if !helpers.SliceContains(methods, mount+"/") {
+		_, err := a.VaultClient.System.AuthEnableMethod(ctx, mount, schema.AuthEnableMethodRequest{
+			Type:        "oidc",
+			Description: "authenticate with OpenID Connect",
+		})
+		if err != nil {
+			return fmt.Errorf("could not enable OIDC authentication method: %s. Error: %v", mount, err)
+		}
+
+		a.Log.Infof("enabled OIDC authentication method: %s", mount)
+	}
+
+	_, err = a.VaultClient.Write(ctx, fmt.Sprintf("auth/%s/config", mount), map[string]interface{}{
+		"oidc_discovery_url": opts.DiscoveryURL,
+		"oidc_client_id":     opts.ClientID,
+		"oidc_client_secret": opts.ClientSecret,
+		"default_role":       opts.Role,
+	})
+	if err != nil {
+		return fmt.Errorf("could not configure OIDC authentication method: %s. Error: %v", mount, err)
+	}
+
+	_, err = a.VaultClient.Write(ctx, fmt.Sprintf("auth/%s/role/%s", mount, opts.Role), map[string]interface{}{
+		"role_type":             "oidc",
+		"bound_audiences":       []string{opts.ClientID},
+		"allowed_redirect_uris": opts.RedirectURIs,
+		"user_claim":            opts.UserClaim,
+		"groups_claim":          opts.GroupsClaim,
+		"oidc_scopes":           opts.Scopes,
+		"token_policies":        removeEmpty(opts.Policies),
+	})
+	if err != nil {
+		return fmt.Errorf("could not write OIDC role: %s. Error: %v", opts.Role, err)
+	}
+
+	return nil
+}
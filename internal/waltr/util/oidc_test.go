@@ -0,0 +1,15 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOIDCRedirectURIs(t *testing.T) {
+	assert.Equal(t, []string{OIDCCLICallback}, OIDCRedirectURIs("", "oidc"))
+	assert.Equal(t, []string{
+		OIDCCLICallback,
+		"https://vault.example.com/ui/vault/auth/keycloak/oidc/callback",
+	}, OIDCRedirectURIs("https://vault.example.com/", "/keycloak/"))
+}
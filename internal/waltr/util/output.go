@@ -0,0 +1,41 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// Outputs are the output formats supported by reporting subcommands
+var Outputs = []string{OutputTable, OutputJSON, OutputYAML}
+
+// RenderOutput writes v to w in the given format. For the table format the rows are produced by the
+// table function, whose tab-separated columns are aligned before they're written.
+func RenderOutput(w io.Writer, format string, v interface{}, table func(tw io.Writer)) error {
+	switch format {
+	case OutputTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(v)
+	default:
+		return fmt.Errorf("unsupported output format: %s. Must be one of: %v", format, Outputs)
+	}
+}
@@ -0,0 +1,279 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+)
+
+const (
+	armorBegin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	armorEnd   = "-----END PGP PUBLIC KEY BLOCK-----"
+
+	// OpenPGP packet tags, see RFC 4880 section 4.3
+	pgpSecretKeyTag = 5
+	pgpPublicKeyTag = 6
+)
+
+// PGPKeyExtensions are the file extensions of PGP public keys within a key directory
+var PGPKeyExtensions = []string{".asc", ".gpg", ".pub", ".b64"}
+
+// PGPKeyFiles lists the PGP public key files within dir in lexical order. Only files with one of the
+// PGPKeyExtensions are considered. Files whose names result in the same KeyHolder are rejected, since
+// the key shares of both holders would be written to the same file.
+func PGPKeyFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read PGP public key directory: %s. Error: %v", dir, err)
+	}
+
+	var files []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || !helpers.SliceContains(PGPKeyExtensions, ext) {
+			continue
+		}
+
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("found no PGP public keys with one of the extensions: %v in: %s", PGPKeyExtensions,
+			dir)
+	}
+
+	if _, err := KeyHolders(files); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// ReadPGPKeys reads the PGP public keys at the given paths and returns them in the base64-encoded
+// binary format Vault expects for 'pgp_keys' and 'root_token_pgp_key'. Keys may be stored binary,
+// base64-encoded or ASCII-armored.
+func ReadPGPKeys(paths []string) ([]string, error) {
+	keys := make([]string, 0, len(paths))
+	for _, p := range paths {
+		raw, err := fs.Read(p)
+		if err != nil {
+			return nil, fmt.Errorf("could not read PGP public key: %s. Error: %v", p, err)
+		}
+
+		key, err := EncodePGPKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PGP public key: %s. Error: %v", p, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// EncodePGPKey converts a binary, base64-encoded or ASCII-armored PGP public key into its
+// base64-encoded binary form. The key is validated with ValidatePGPPublicKey, so that invalid keys are
+// rejected before they're sent to Vault.
+func EncodePGPKey(raw []byte) (string, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return "", fmt.Errorf("key is empty")
+	}
+
+	var key string
+	switch {
+	case bytes.HasPrefix(trimmed, []byte(armorBegin)):
+		k, err := dearmor(string(trimmed))
+		if err != nil {
+			return "", err
+		}
+		key = k
+	default:
+		key = base64.StdEncoding.EncodeToString(raw)
+
+		// already base64-encoded
+		if _, err := base64.StdEncoding.DecodeString(string(trimmed)); err == nil {
+			key = string(trimmed)
+		}
+	}
+
+	binary, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := ValidatePGPPublicKey(binary); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// ValidatePGPPublicKey checks that binary is a sequence of well-formed OpenPGP packets starting with a
+// version 4 or later public key packet. It doesn't verify signatures, Vault does when encrypting with it.
+func ValidatePGPPublicKey(binary []byte) error {
+	for i := 0; len(binary) > 0; i++ {
+		tag, body, rest, err := readPGPPacket(binary)
+		if err != nil {
+			return fmt.Errorf("not an OpenPGP public key: %v", err)
+		}
+
+		if i == 0 {
+			switch {
+			case tag == pgpSecretKeyTag:
+				return fmt.Errorf("key is an OpenPGP secret key, export the public key instead")
+			case tag != pgpPublicKeyTag:
+				return fmt.Errorf("not an OpenPGP public key: first packet has tag %d", tag)
+			case len(body) == 0 || body[0] < 4:
+				return fmt.Errorf("unsupported OpenPGP public key version, version 4 or later is required")
+			}
+		}
+
+		binary = rest
+	}
+
+	return nil
+}
+
+// readPGPPacket splits the first OpenPGP packet off data and returns its tag and body, see RFC 4880
+// section 4.2. Partial and indeterminate body lengths aren't used for keys and are rejected.
+func readPGPPacket(data []byte) (byte, []byte, []byte, error) {
+	if len(data) < 2 || data[0]&0x80 == 0 {
+		return 0, nil, nil, fmt.Errorf("invalid packet header")
+	}
+
+	var (
+		tag    byte
+		length int
+		n      int
+	)
+
+	if data[0]&0x40 != 0 {
+		// new format
+		tag = data[0] & 0x3f
+		switch l := data[1]; {
+		case l < 192:
+			length, n = int(l), 2
+		case l < 224:
+			if len(data) < 3 {
+				return 0, nil, nil, fmt.Errorf("truncated packet header")
+			}
+			length, n = (int(l)-192)<<8+int(data[2])+192, 3
+		case l == 255:
+			if len(data) < 6 {
+				return 0, nil, nil, fmt.Errorf("truncated packet header")
+			}
+			length, n = int(data[2])<<24|int(data[3])<<16|int(data[4])<<8|int(data[5]), 6
+		default:
+			return 0, nil, nil, fmt.Errorf("unsupported partial body length")
+		}
+	} else {
+		// old format
+		tag = (data[0] >> 2) & 0x0f
+		size := [...]int{1, 2, 4, 0}[data[0]&0x03]
+		if size == 0 {
+			return 0, nil, nil, fmt.Errorf("unsupported indeterminate body length")
+		}
+
+		if len(data) < 1+size {
+			return 0, nil, nil, fmt.Errorf("truncated packet header")
+		}
+
+		for _, b := range data[1 : 1+size] {
+			length = length<<8 | int(b)
+		}
+		n = 1 + size
+	}
+
+	if length < 0 || len(data)-n < length {
+		return 0, nil, nil, fmt.Errorf("truncated packet of tag %d", tag)
+	}
+
+	return tag, data[n : n+length], data[n+length:], nil
+}
+
+// dearmor extracts the base64-encoded body of an ASCII-armored public key block, dropping the armor
+// headers and the CRC24 checksum line
+func dearmor(armored string) (string, error) {
+	end := strings.Index(armored, armorEnd)
+	if end < 0 {
+		return "", fmt.Errorf("missing armor end line")
+	}
+
+	block := strings.TrimSpace(strings.ReplaceAll(armored[len(armorBegin):end], "\r\n", "\n"))
+	lines := strings.Split(block, "\n")
+
+	var body strings.Builder
+	inHeaders := true
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		switch {
+		case inHeaders && strings.Contains(l, ": "):
+			continue
+		case l == "":
+			inHeaders = false
+			continue
+		case strings.HasPrefix(l, "="):
+			// CRC24 checksum
+			continue
+		}
+
+		inHeaders = false
+		body.WriteString(l)
+	}
+
+	key := body.String()
+	if _, err := base64.StdEncoding.DecodeString(key); err != nil {
+		return "", fmt.Errorf("invalid armored key body: %v", err)
+	}
+
+	return key, nil
+}
+
+// KeyHolder returns the name of a key holder derived from the file name of their PGP public key
+func KeyHolder(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// KeyHolders returns the KeyHolder of every PGP public key path and rejects duplicate key holders
+func KeyHolders(paths []string) ([]string, error) {
+	holders := make([]string, 0, len(paths))
+	for _, p := range paths {
+		h := KeyHolder(p)
+		if helpers.SliceContains(holders, h) {
+			return nil, fmt.Errorf("found multiple PGP public keys for key holder: %s. Every key holder may only "+
+				"have one key", h)
+		}
+
+		holders = append(holders, h)
+	}
+
+	return holders, nil
+}
+
+// WriteKeyShares writes every PGP-encrypted key share to its own file within dir. The share at index i
+// is written for holders[i], so that it can be handed to the person owning the private key.
+func WriteKeyShares(dir string, holders, shares []string) ([]string, error) {
+	if len(holders) != len(shares) {
+		return nil, fmt.Errorf("received %d key shares for %d key holders", len(shares), len(holders))
+	}
+
+	paths := make([]string, 0, len(shares))
+	for i, s := range shares {
+		p := filepath.Join(dir, fmt.Sprintf("%s.unseal-key.b64", holders[i]))
+		if err := fs.Write(p, []byte(s+"\n")); err != nil {
+			return paths, fmt.Errorf("could not write key share for: %s. Error: %v", holders[i], err)
+		}
+
+		paths = append(paths, p)
+	}
+
+	return paths, nil
+}
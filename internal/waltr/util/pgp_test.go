@@ -0,0 +1,63 @@
+package util
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pgpPublicKey is a minimal OpenPGP public key: an old-format version 4 public key packet followed by a
+// new-format user ID packet
+var pgpPublicKey = []byte{0x98, 0x06, 0x04, 0x65, 0x10, 0xff, 0x00, 0x01, 0xcd, 0x05, 'a', 'l', 'i', 'c', 'e'}
+
+func TestEncodePGPKey(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(pgpPublicKey)
+	armored := "-----BEGIN PGP PUBLIC KEY BLOCK-----\n" +
+		"Comment: key holder\n\n" +
+		encoded + "\n" +
+		"=abcd\n" +
+		"-----END PGP PUBLIC KEY BLOCK-----\n"
+
+	for name, raw := range map[string][]byte{
+		"binary":  pgpPublicKey,
+		"base64":  []byte(encoded + "\n"),
+		"armored": []byte(armored),
+	} {
+		key, err := EncodePGPKey(raw)
+		assert.NoError(t, err, name)
+		assert.Equal(t, encoded, key, name)
+	}
+
+	for name, raw := range map[string][]byte{
+		"empty":     []byte("  "),
+		"text":      []byte("# Key holders\n\nPut the public keys of the key holders here.\n"),
+		"truncated": pgpPublicKey[:5],
+		"secret":    append([]byte{0x94}, pgpPublicKey[1:]...),
+		"version 3": append([]byte{0x98, 0x06, 0x03}, pgpPublicKey[3:]...),
+	} {
+		_, err := EncodePGPKey(raw)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestPGPKeyFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"alice.asc", "bob.gpg", "README.md", ".gitkeep"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), pgpPublicKey, 0o600))
+	}
+
+	files, err := PGPKeyFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "alice.asc"), filepath.Join(dir, "bob.gpg")}, files)
+
+	// both keys would result in the same key share file
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "alice.pub"), pgpPublicKey, 0o600))
+	_, err = PGPKeyFiles(dir)
+	assert.Error(t, err)
+
+	_, err = PGPKeyFiles(t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,422 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// DefaultPKIRootMount is the mount path of the PKI secrets engine holding the root CA
+	DefaultPKIRootMount = "pki"
+
+	// DefaultPKIIntermediateMount is the mount path of the PKI secrets engine holding the intermediate CA
+	DefaultPKIIntermediateMount = "pki_int"
+
+	// CertManagerGroup is the API group of cert-manager's custom resources
+	CertManagerGroup = "cert-manager.io"
+
+	// CertManagerNamespace is the namespace cert-manager is installed to and its cluster resource namespace
+	CertManagerNamespace = "cert-manager"
+
+	// CertManagerServiceAccount is the Service Account of the cert-manager controller
+	CertManagerServiceAccount = "cert-manager"
+)
+
+// PKIOptions configure the root and intermediate CA created by ConfigurePKI
+type PKIOptions struct {
+	RootMount         string
+	IntermediateMount string
+
+	// CommonName is the common name of the root CA. The intermediate CA appends 'Intermediate Authority'.
+	CommonName string
+
+	RootTTL         string
+	IntermediateTTL string
+	KeyType         string
+	KeyBits         int
+
+	// Address is the address of Vault the issuing certificate and CRL distribution URLs point to
+	Address string
+}
+
+// DefaultPKIOptions returns PKIOptions for a ten-year root CA and a five-year intermediate CA
+func DefaultPKIOptions() PKIOptions {
+	return PKIOptions{
+		RootMount:         DefaultPKIRootMount,
+		IntermediateMount: DefaultPKIIntermediateMount,
+		RootTTL:           "87600h",
+		IntermediateTTL:   "43800h",
+		KeyType:           "rsa",
+		KeyBits:           4096,
+	}
+}
+
+// PKIRole is a role of the intermediate CA issuing certificates for the allowed domains
+type PKIRole struct {
+	Name             string
+	AllowedDomains   []string
+	AllowSubdomains  bool
+	AllowBareDomains bool
+	MaxTTL           string
+}
+
+// PKIURLs returns the issuing certificate and CRL distribution URLs of the PKI secrets engine at mount
+func PKIURLs(address, mount string) (string, string) {
+	base := fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(address, "/"), strings.Trim(mount, "/"))
+	return base + "/ca", base + "/crl"
+}
+
+// ConfigurePKI enables the root and intermediate PKI secrets engines, generates the root CA, signs the
+// intermediate CA with it and configures the URLs of both. CAs which have been generated before are kept,
+// which makes it safe to run repeatedly.
+func ConfigurePKI(a *app.State, opts PKIOptions) error {
+	ctx := context.Background()
+	root := strings.Trim(opts.RootMount, "/")
+	intermediate := strings.Trim(opts.IntermediateMount, "/")
+
+	if err := ensurePKIMount(a, root, "root certificate authority", opts.RootTTL); err != nil {
+		return err
+	}
+
+	if err := ensurePKIMount(a, intermediate, "intermediate certificate authority", opts.IntermediateTTL); err != nil {
+		return err
+	}
+
+	exists, err := hasPKIIssuer(a, root)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		_, err := a.VaultClient.Write(ctx, root+"/root/generate/internal", map[string]interface{}{
+			"common_name": opts.CommonName,
+			"issuer_name": "root",
+			"ttl":         opts.RootTTL,
+			"key_type":    opts.KeyType,
+			"key_bits":    opts.KeyBits,
+		})
+		if err != nil {
+			return fmt.Errorf("could not generate root CA within: %s. Error: %v", root, err)
+		}
+
+		a.Log.Infof("generated root CA: %s within: %s", opts.CommonName, root)
+	} else {
+		a.Log.Infof("root CA within: %s already exists", root)
+	}
+
+	exists, err = hasPKIIssuer(a, intermediate)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if err := signIntermediate(ctx, a, opts, root, intermediate); err != nil {
+			return err
+		}
+
+		a.Log.Infof("generated intermediate CA within: %s signed by: %s", intermediate, root)
+	} else {
+		a.Log.Infof("intermediate CA within: %s already exists", intermediate)
+	}
+
+	if opts.Address == "" {
+		a.Log.Warnf("no Vault address given - skipping configuration of issuing and CRL distribution URLs")
+		return nil
+	}
+
+	for _, m := range []string{root, intermediate} {
+		ca, crl := PKIURLs(opts.Address, m)
+		_, err := a.VaultClient.Write(ctx, m+"/config/urls", map[string]interface{}{
+			"issuing_certificates":    []string{ca},
+			"crl_distribution_points": []string{crl},
+		})
+		if err != nil {
+			return fmt.Errorf("could not configure URLs of PKI secrets engine: %s. Error: %v", m, err)
+		}
+	}
+
+	return nil
+}
+
+// signIntermediate generates the intermediate CA's CSR, signs it with the root CA and imports the
+// signed certificate including the CA chain
+func signIntermediate(ctx context.Context, a *app.State, opts PKIOptions, root, intermediate string) error {
+	res, err := a.VaultClient.Write(ctx, intermediate+"/intermediate/generate/internal", map[string]interface{}{
+		"common_name": opts.CommonName + " Intermediate Authority",
+		"issuer_name": "intermediate",
+		"key_type":    opts.KeyType,
+		"key_bits":    opts.KeyBits,
+	})
+	if err != nil {
+		return fmt.Errorf("could not generate CSR of intermediate CA within: %s. Error: %v", intermediate, err)
+	}
+
+	csr, _ := res.Data["csr"].(string)
+	if csr == "" {
+		return fmt.Errorf("vault did not return a CSR for the intermediate CA within: %s", intermediate)
+	}
+
+	res, err = a.VaultClient.Write(ctx, root+"/root/sign-intermediate", map[string]interface{}{
+		"csr":    csr,
+		"format": "pem_bundle",
+		"ttl":    opts.IntermediateTTL,
+	})
+	if err != nil {
+		return fmt.Errorf("could not sign intermediate CA with root CA of: %s. Error: %v", root, err)
+	}
+
+	cert, _ := res.Data["certificate"].(string)
+	if cert == "" {
+		return fmt.Errorf("vault did not return a signed certificate for the intermediate CA")
+	}
+
+	_, err = a.VaultClient.Write(ctx, intermediate+"/intermediate/set-signed", map[string]interface{}{
+		"certificate": cert,
+	})
+	if err != nil {
+		return fmt.Errorf("could not import signed intermediate CA into: %s. Error: %v", intermediate, err)
+	}
+
+	return nil
+}
+
+// ensurePKIMount enables a PKI secrets engine at mount unless it is enabled already and sets its
+// maximum lease TTL
+func ensurePKIMount(a *app.State, mount, description, maxTTL string) error {
+	ctx := context.Background()
+
+	engines, err := SecretsEngines(a)
+	if err != nil {
+		return err
+	}
+
+	if !helpers.SliceContains(engines, mount+"/") {
+		_, err := a.VaultClient.System.MountsEnableSecretsEngine(ctx, mount, schema.MountsEnableSecretsEngineRequest{
+			Type:        "pki",
+			Description: description,
+			Config: map[string]interface{}{
+				"max_lease_ttl": maxTTL,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("could not enable PKI secrets engine: %s. Error: %v", mount, err)
+		}
+
+		a.Log.Infof("enabled PKI secrets engine: %s", mount)
+		return nil
+	}
+
+	_, err = a.VaultClient.Write(ctx, fmt.Sprintf("sys/mounts/%s/tune", mount), map[string]interface{}{
+		"max_lease_ttl": maxTTL,
+	})
+	if err != nil {
+		return fmt.Errorf("could not tune PKI secrets engine: %s. Error: %v", mount, err)
+	}
+
+	return nil
+}
+
+// hasPKIIssuer reports whether the PKI secrets engine at mount holds at least one issuer
+func hasPKIIssuer(a *app.State, mount string) (bool, error) {
+	res, err := a.VaultClient.List(context.Background(), mount+"/issuers")
+	if err != nil {
+		if vault.IsErrorStatus(err, http.StatusNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("could not list issuers of PKI secrets engine: %s. Error: %v", mount, err)
+	}
+
+	if res == nil || res.Data == nil {
+		return false, nil
+	}
+
+	keys, _ := res.Data["keys"].([]interface{})
+	return len(keys) > 0, nil
+}
+
+// WritePKIRole creates or updates a role of the PKI secrets engine at mount
+func WritePKIRole(a *app.State, mount string, role PKIRole) error {
+	_, err := a.VaultClient.Write(context.Background(), fmt.Sprintf("%s/roles/%s", strings.Trim(mount, "/"),
+		role.Name), map[string]interface{}{
+		"allowed_domains":    removeEmpty(role.AllowedDomains),
+		"allow_subdomains":   role.AllowSubdomains,
+		"allow_bare_domains": role.AllowBareDomains,
+		"max_ttl":            role.MaxTTL,
+	})
+	if err != nil {
+		return fmt.Errorf("could not write PKI role: %s. Error: %v", role.Name, err)
+	}
+
+	return nil
+}
+
+// IssuerOptions configure the cert-manager Issuer or ClusterIssuer which requests certificates from a
+// PKI role with Vault's Kubernetes authentication
+type IssuerOptions struct {
+	// Name is the name of the Issuer as well as the Vault ACL policy and Kubernetes Auth role
+	Name string
+
+	// Cluster creates a ClusterIssuer instead of an Issuer
+	Cluster bool
+
+	// Namespace is the namespace of the Issuer and its Service Account. ClusterIssuers require it to be
+	// cert-manager's cluster resource namespace.
+	Namespace string
+
+	// ServiceAccount is the Service Account cert-manager requests tokens for to authenticate with Vault
+	ServiceAccount string
+
+	// Server is the in-cluster address of Vault
+	Server string
+
+	// CABundleSecret is the name of a Secret within Namespace holding Vault's CA certificate as 'ca.crt'
+	CABundleSecret string
+
+	AuthMount string
+	PKIMount  string
+	Role      string
+}
+
+// DefaultIssuerOptions returns IssuerOptions for a ClusterIssuer named 'vault-issuer'
+func DefaultIssuerOptions() IssuerOptions {
+	return IssuerOptions{
+		Name:           "vault-issuer",
+		Cluster:        true,
+		Namespace:      CertManagerNamespace,
+		ServiceAccount: "vault-issuer",
+		AuthMount:      DefaultKubernetesMount,
+		PKIMount:       DefaultPKIIntermediateMount,
+	}
+}
+
+// Audience returns the audience of the Service Account tokens cert-manager requests for the issuer
+func (o IssuerOptions) Audience() string {
+	if o.Cluster {
+		return "vault://" + o.Name
+	}
+
+	return fmt.Sprintf("vault://%s/%s", o.Namespace, o.Name)
+}
+
+// Kind returns the kind of the issuer resource
+func (o IssuerOptions) Kind() string {
+	if o.Cluster {
+		return "ClusterIssuer"
+	}
+
+	return "Issuer"
+}
+
+// PKIIssuerPolicy returns the ACL policy allowing to sign and issue certificates with a PKI role
+func PKIIssuerPolicy(mount, role string) string {
+	mount = strings.Trim(mount, "/")
+	return fmt.Sprintf(`path "%[1]s/sign/%[2]s" {
+  capabilities = ["create", "update"]
+}
+
+path "%[1]s/issue/%[2]s" {
+  capabilities = ["create", "update"]
+}
+`, mount, role)
+}
+
+// ConfigureIssuerAuth writes the ACL policy and the Kubernetes Auth role the issuer's Service Account
+// authenticates with
+func ConfigureIssuerAuth(a *app.State, opts IssuerOptions) error {
+	ctx := context.Background()
+
+	_, err := a.VaultClient.System.PoliciesWriteAclPolicy(ctx, opts.Name, schema.PoliciesWriteAclPolicyRequest{
+		Policy: PKIIssuerPolicy(opts.PKIMount, opts.Role),
+	})
+	if err != nil {
+		return fmt.Errorf("could not write ACL policy: %s. Error: %v", opts.Name, err)
+	}
+
+	_, err = a.VaultClient.Write(ctx, fmt.Sprintf("auth/%s/role/%s", strings.Trim(opts.AuthMount, "/"), opts.Name),
+		map[string]interface{}{
+			"bound_service_account_names":      []string{opts.ServiceAccount},
+			"bound_service_account_namespaces": []string{opts.Namespace},
+			"audience":                         opts.Audience(),
+			"token_policies":                   []string{opts.Name},
+			"token_ttl":                        "20m",
+		})
+	if err != nil {
+		return fmt.Errorf("could not write Kubernetes Auth role: %s. Error: %v", opts.Name, err)
+	}
+
+	return nil
+}
+
+// IssuerManifests builds the issuer's Service Account, the Role and RoleBinding allowing cert-manager to
+// request tokens for it and the Issuer or ClusterIssuer itself
+func IssuerManifests(opts IssuerOptions) []*unstructured.Unstructured {
+	const rbac = "rbac.authorization.k8s.io"
+	binding := opts.ServiceAccount + "-token"
+
+	vaultSpec := map[string]interface{}{
+		"server": opts.Server,
+		"path":   fmt.Sprintf("%s/sign/%s", strings.Trim(opts.PKIMount, "/"), opts.Role),
+		"auth": map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"role":      opts.Name,
+				"mountPath": fmt.Sprintf("/v1/auth/%s", strings.Trim(opts.AuthMount, "/")),
+				"serviceAccountRef": map[string]interface{}{
+					"name": opts.ServiceAccount,
+				},
+			},
+		},
+	}
+	if opts.CABundleSecret != "" {
+		vaultSpec["caBundleSecretRef"] = map[string]interface{}{
+			"name": opts.CABundleSecret,
+			"key":  "ca.crt",
+		}
+	}
+
+	issuerNamespace := opts.Namespace
+	if opts.Cluster {
+		issuerNamespace = ""
+	}
+
+	return []*unstructured.Unstructured{
+		manifestObject("v1", "ServiceAccount", opts.ServiceAccount, opts.Namespace, nil),
+		manifestObject(rbac+"/v1", "Role", binding, opts.Namespace, map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"apiGroups":     []interface{}{""},
+					"resources":     []interface{}{"serviceaccounts/token"},
+					"resourceNames": []interface{}{opts.ServiceAccount},
+					"verbs":         []interface{}{"create"},
+				},
+			},
+		}),
+		manifestObject(rbac+"/v1", "RoleBinding", binding, opts.Namespace, map[string]interface{}{
+			"roleRef": map[string]interface{}{
+				"apiGroup": rbac,
+				"kind":     "Role",
+				"name":     binding,
+			},
+			"subjects": []interface{}{
+				map[string]interface{}{
+					"kind":      "ServiceAccount",
+					"name":      CertManagerServiceAccount,
+					"namespace": CertManagerNamespace,
+				},
+			},
+		}),
+		manifestObject(CertManagerGroup+"/v1", opts.Kind(), opts.Name, issuerNamespace, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"vault": vaultSpec,
+			},
+		}),
+	}
+}
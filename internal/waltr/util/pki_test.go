@@ -0,0 +1,58 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPKIURLs(t *testing.T) {
+	ca, crl := PKIURLs("https://vault.vault.svc:8200/", "/pki_int/")
+	assert.Equal(t, "https://vault.vault.svc:8200/v1/pki_int/ca", ca)
+	assert.Equal(t, "https://vault.vault.svc:8200/v1/pki_int/crl", crl)
+}
+
+func TestIssuerAudience(t *testing.T) {
+	opts := DefaultIssuerOptions()
+	assert.Equal(t, "vault://vault-issuer", opts.Audience())
+	assert.Equal(t, "ClusterIssuer", opts.Kind())
+
+	opts.Cluster = false
+	opts.Namespace = "apps"
+	assert.Equal(t, "vault://apps/vault-issuer", opts.Audience())
+	assert.Equal(t, "Issuer", opts.Kind())
+}
+
+func TestIssuerManifests(t *testing.T) {
+	opts := DefaultIssuerOptions()
+	opts.Server = "https://vault.vault.svc:8200"
+	opts.Role = "default"
+	opts.CABundleSecret = "vault-tls"
+
+	objs := IssuerManifests(opts)
+	assert.Len(t, objs, 4)
+
+	for _, o := range objs[:3] {
+		assert.Equal(t, CertManagerNamespace, o.GetNamespace())
+		_, ok := ManifestResources[o.GetKind()]
+		assert.True(t, ok, o.GetKind())
+	}
+
+	issuer := objs[3]
+	assert.Equal(t, "ClusterIssuer", issuer.GetKind())
+	assert.Empty(t, issuer.GetNamespace())
+
+	p, _, _ := unstructured.NestedString(issuer.Object, "spec", "vault", "path")
+	assert.Equal(t, "pki_int/sign/default", p)
+
+	mount, _, _ := unstructured.NestedString(issuer.Object, "spec", "vault", "auth", "kubernetes", "mountPath")
+	assert.Equal(t, "/v1/auth/kubernetes", mount)
+
+	sa, _, _ := unstructured.NestedString(issuer.Object, "spec", "vault", "auth", "kubernetes", "serviceAccountRef",
+		"name")
+	assert.Equal(t, opts.ServiceAccount, sa)
+
+	subject, _, _ := unstructured.NestedSlice(objs[2].Object, "subjects")
+	assert.Equal(t, CertManagerServiceAccount, subject[0].(map[string]interface{})["name"])
+}
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+)
+
+// planSymbols are the prefixes for every Action within a rendered plan
+var planSymbols = map[Action]string{
+	Create: "+",
+	Update: "~",
+	Delete: "-",
+}
+
+// RenderPlan writes a human-readable diff of the given changes to w. Updated resources show a
+// line-based diff of their previous and new state, e.g. the body of an ACL policy.
+func RenderPlan(w io.Writer, changes []Change) error {
+	var sb strings.Builder
+	counts := make(map[Action]int)
+
+	for _, c := range changes {
+		counts[c.Action]++
+		sb.WriteString(fmt.Sprintf("%s %s\n", planSymbols[c.Action], c))
+
+		for _, line := range helpers.DiffLines(c.Before, c.After) {
+			sb.WriteString(fmt.Sprintf("    %s\n", line))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("Plan: %d to create, %d to update, %d to delete.\n",
+		counts[Create], counts[Update], counts[Delete]))
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
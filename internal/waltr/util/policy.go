@@ -0,0 +1,253 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// PolicyCapabilities are the capabilities Vault accepts within ACL policy paths
+var PolicyCapabilities = []string{
+	"create", "read", "update", "patch", "delete", "list", "sudo", "deny", "subscribe", "recover",
+}
+
+// hcl1BlockBraces matches block headers whose opening brace is on the next line. Vault parses policies
+// with HCL 1, which allows this style, while HCL 2 requires the brace on the header's line.
+var hcl1BlockBraces = regexp.MustCompile(`(?m)^(\s*path\s+"[^"\n]*")\s*\n\s*\{`)
+
+// policyTestTTL is the TTL of the tokens TestPolicyCapabilities creates to evaluate policies
+const policyTestTTL = "1m"
+
+// ACLPolicy is the decoded body of a Vault ACL policy
+type ACLPolicy struct {
+	Name   string
+	Paths  []PolicyPath `hcl:"path,block"`
+	Remain hcl.Body     `hcl:",remain"`
+}
+
+// PolicyPath is a single 'path' stanza of an ACLPolicy
+type PolicyPath struct {
+	Path         string   `hcl:"path,label"`
+	Capabilities []string `hcl:"capabilities,optional"`
+	Remain       hcl.Body `hcl:",remain"`
+}
+
+// ParsePolicy decodes the HCL or JSON body of the ACL policy with the given name
+func ParsePolicy(name, body string) (*ACLPolicy, error) {
+	hcp := hclparse.NewParser()
+
+	var (
+		f     *hcl.File
+		diags hcl.Diagnostics
+	)
+	if strings.HasPrefix(strings.TrimSpace(body), "{") {
+		f, diags = hcp.ParseJSON([]byte(body), name+".json")
+	} else {
+		body = hcl1BlockBraces.ReplaceAllString(body, "$1 {")
+		f, diags = hcp.ParseHCL([]byte(body), name+".hcl")
+	}
+
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("cannot parse ACL policy: %s. Error: %v", name, diags)
+	}
+
+	policy := ACLPolicy{Name: name}
+	if diags := gohcl.DecodeBody(f.Body, nil, &policy); diags.HasErrors() {
+		return nil, fmt.Errorf("invalid ACL policy: %s. Error: %v", name, diags)
+	}
+
+	return &policy, nil
+}
+
+// Has reports whether the path grants the given capability
+func (p PolicyPath) Has(capability string) bool {
+	return helpers.SliceContains(p.Capabilities, capability)
+}
+
+// Glob reports whether the path contains a '*' or '+' wildcard
+func (p PolicyPath) Glob() bool {
+	return strings.ContainsAny(p.Path, "*+")
+}
+
+// Broad reports whether the path's wildcard matches within its first two segments, e.g. '*', 'sys/*'
+// or '+/config', so that it covers entire mounts or API areas
+func (p PolicyPath) Broad() bool {
+	i := strings.IndexAny(p.Path, "*+")
+	if i < 0 {
+		return false
+	}
+
+	return strings.Count(strings.Trim(p.Path[:i], "/"), "/") < 1
+}
+
+// PathCapabilities are the effective capabilities of a token on a path
+type PathCapabilities struct {
+	Path         string   `json:"path" yaml:"path"`
+	Capabilities []string `json:"capabilities" yaml:"capabilities"`
+}
+
+// LoadPolicyFiles reads the ACL policies from the files at paths and maps them by their file name
+// without extension
+func LoadPolicyFiles(paths []string) (map[string]string, error) {
+	policies := make(map[string]string, len(paths))
+	for _, p := range paths {
+		raw, err := fs.Read(p)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ACL policy: %s. Error: %v", p, err)
+		}
+
+		name := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+		if _, ok := policies[name]; ok {
+			return nil, fmt.Errorf("duplicate ACL policy: %s", name)
+		}
+
+		policies[name] = string(raw)
+	}
+
+	return policies, nil
+}
+
+// LintPolicies parses and statically checks the given ACL policies, which are mapped by name. Policies
+// which cannot be parsed result in a critical Finding.
+func LintPolicies(policies map[string]string) []Finding {
+	var findings []Finding
+	for _, name := range sortedKeys(policies) {
+		policy, err := ParsePolicy(name, policies[name])
+		if err != nil {
+			findings = append(findings, Finding{Check: "parse", Severity: SeverityCritical, Message: err.Error()})
+			continue
+		}
+
+		findings = append(findings, LintPolicy(policy)...)
+	}
+
+	return findings
+}
+
+// LintPolicy statically checks an ACLPolicy for unknown or missing capabilities, overly broad wildcard
+// paths and the use of 'sudo'. Policies without issues result in a single Finding with SeverityOK.
+func LintPolicy(p *ACLPolicy) []Finding {
+	var findings []Finding
+	add := func(check string, severity Severity, format string, args ...interface{}) {
+		findings = append(findings, Finding{
+			Check:    check,
+			Severity: severity,
+			Message:  "policy: " + p.Name + " " + fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, path := range p.Paths {
+		for _, c := range path.Capabilities {
+			if !helpers.SliceContains(PolicyCapabilities, c) {
+				add("capabilities", SeverityCritical, "grants unknown capability: %s on path: %s", c, path.Path)
+			}
+		}
+
+		switch {
+		case len(path.Capabilities) == 0:
+			add("capabilities", SeverityWarning, "grants no capabilities on path: %s", path.Path)
+		case path.Has("deny") && len(path.Capabilities) > 1:
+			add("capabilities", SeverityInfo, "denies path: %s, which overrides its other capabilities", path.Path)
+		}
+
+		switch {
+		case path.Has("deny"):
+		case path.Path == "*":
+			add("broad-glob", SeverityCritical, "matches every path with: %s", path.Path)
+		case path.Broad():
+			add("broad-glob", SeverityWarning, "matches entire mounts or API areas with path: %s", path.Path)
+		}
+
+		switch {
+		case !path.Has("sudo"):
+		case path.Glob():
+			add("sudo", SeverityWarning, "grants sudo on wildcard path: %s", path.Path)
+		default:
+			add("sudo", SeverityInfo, "grants sudo on path: %s", path.Path)
+		}
+	}
+
+	if len(findings) == 0 {
+		add("lint", SeverityOK, "has no issues")
+	}
+
+	return findings
+}
+
+// TestCapabilities returns the effective capabilities of the token on the given paths. An empty token
+// tests the token waltr is authenticated with.
+func TestCapabilities(a *app.State, token string, paths []string) ([]PathCapabilities, error) {
+	p, data := "sys/capabilities-self", map[string]interface{}{"paths": paths}
+	if token != "" {
+		p = "sys/capabilities"
+		data["token"] = token
+	}
+
+	res, err := a.VaultClient.Write(context.Background(), p, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not query capabilities: %v", err)
+	}
+
+	caps := make([]PathCapabilities, 0, len(paths))
+	for _, path := range paths {
+		var c []string
+		if res != nil {
+			c = toStrings(res.Data[path])
+		}
+
+		// older Vault versions only return the capabilities of a single path
+		if c == nil && len(paths) == 1 && res != nil {
+			c = toStrings(res.Data["capabilities"])
+		}
+		sort.Strings(c)
+
+		caps = append(caps, PathCapabilities{Path: path, Capabilities: c})
+	}
+
+	return caps, nil
+}
+
+// TestPolicyCapabilities returns the effective capabilities the given ACL policies grant on the given
+// paths. They're evaluated with a short-lived orphan token, which only holds these policies and is
+// revoked afterwards.
+func TestPolicyCapabilities(a *app.State, policies, paths []string) ([]PathCapabilities, error) {
+	t, err := CreateToken(a, TokenOptions{
+		DisplayName:     "waltr-policy-test",
+		Policies:        policies,
+		NoDefaultPolicy: true,
+		TTL:             policyTestTTL,
+		Orphan:          true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := RevokeToken(a, "", t.Accessor, false); err != nil {
+			a.Log.Warnf("could not revoke policy test token with accessor: %s. Error: %v", t.Accessor, err)
+		}
+	}()
+
+	return TestCapabilities(a, t.Token, paths)
+}
+
+// RenderCapabilities writes the capabilities to w in the given format
+func RenderCapabilities(w io.Writer, format string, caps []PathCapabilities) error {
+	return RenderOutput(w, format, caps, func(tw io.Writer) {
+		fmt.Fprintln(tw, "PATH\tCAPABILITIES")
+		for _, c := range caps {
+			fmt.Fprintf(tw, "%s\t%s\n", c.Path, orDash(strings.Join(c.Capabilities, ",")))
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("ops", `
+path "sys/*" {
+  capabilities = ["read", "sudo"]
+}
+
+path "secret/data/ops/+/config" {
+  capabilities = ["read"]
+}
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, "ops", p.Name)
+	assert.Len(t, p.Paths, 2)
+	assert.True(t, p.Paths[0].Has("sudo"))
+	assert.False(t, p.Paths[1].Has("sudo"))
+
+	p, err = ParsePolicy("json", `{"path": {"secret/*": {"capabilities": ["list"]}}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret/*", p.Paths[0].Path)
+	assert.True(t, p.Paths[0].Has("list"))
+
+	_, err = ParsePolicy("broken", `path "a" {`)
+	assert.Error(t, err)
+}
+
+func TestPolicyPathBroad(t *testing.T) {
+	for path, broad := range map[string]bool{
+		"*":                     true,
+		"sys/*":                 true,
+		"+/config":              true,
+		"sys/policies/acl/*":    false,
+		"secret/data/ops/+/cfg": false,
+		"sys/mounts":            false,
+	} {
+		p := PolicyPath{Path: path}
+		assert.Equal(t, broad, p.Broad(), path)
+		assert.Equal(t, path != "sys/mounts", p.Glob(), path)
+	}
+}
+
+func TestLintPolicies(t *testing.T) {
+	findings := LintPolicies(map[string]string{
+		"ops": `
+path "*" {
+  capabilities = ["read"]
+}
+
+path "sys/*" {
+  capabilities = ["read", "sudo"]
+}
+
+path "sys/raw/*" {
+  capabilities = ["deny"]
+}
+
+path "secret/data/ops" {
+  capabilities = ["raed"]
+}
+
+path "sys/rotate" {
+  capabilites = ["update", "sudo"]
+}
+`,
+		"broken": `path "a" {`,
+	})
+
+	checks := make(map[string]int)
+	for _, f := range findings {
+		checks[f.Check]++
+	}
+
+	assert.Equal(t, map[string]int{"parse": 1, "capabilities": 2, "broad-glob": 2, "sudo": 1}, checks)
+	assert.Equal(t, 3, CountFindings(findings, SeverityCritical))
+
+	for _, f := range LintPolicies(ConfigAclPolicies) {
+		assert.NotEqual(t, "parse", f.Check, f.Message)
+	}
+}
+
+func TestLintPolicyOK(t *testing.T) {
+	p, err := ParsePolicy("gitlab", `path "kv/data/gitlab/*" { capabilities = ["read"] }`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Finding{{Check: "lint", Severity: SeverityOK, Message: "policy: gitlab has no issues"}},
+		LintPolicy(p))
+}
+
+func TestParsePolicyHCL1Braces(t *testing.T) {
+	p, err := ParsePolicy("admin", ConfigAclPolicies["admin"])
+	assert.NoError(t, err)
+	assert.NotEmpty(t, p.Paths)
+}
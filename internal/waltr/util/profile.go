@@ -0,0 +1,223 @@
+package util
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPasswordPolicy is the password policy generated credentials use if a ReleaseCredential does not
+// specify one
+const DefaultPasswordPolicy = "alphanumeric-password"
+
+// ReleaseProfile describes how a release authenticates with Vault. 'waltr prepare' creates the release's
+// ACL policy and a Kubernetes Auth role named like the release, which is bound to its Service Accounts
+// within its namespaces and grants the release policy plus any extra Policies.
+type ReleaseProfile struct {
+	Release         string              `yaml:"release"`
+	Mount           string              `yaml:"mount"`
+	ServiceAccounts []string            `yaml:"serviceAccounts"`
+	Namespaces      []string            `yaml:"namespaces"`
+	Audience        string              `yaml:"audience"`
+	TokenPeriod     string              `yaml:"tokenPeriod"`
+	TokenTTL        string              `yaml:"tokenTTL"`
+	Policies        []string            `yaml:"policies"`
+	Credentials     []ReleaseCredential `yaml:"credentials"`
+}
+
+// ReleaseCredential is a username and generated password stored at '<release>/<path>' within the KV-v2
+// mount. Existing credentials are never overwritten.
+type ReleaseCredential struct {
+	Path           string `yaml:"path"`
+	Username       string `yaml:"username"`
+	PasswordPolicy string `yaml:"passwordPolicy"`
+}
+
+// GeneratedSecret returns the credential of the release as a GeneratedSecret with a static username
+// and a generated password
+func (c ReleaseCredential) GeneratedSecret(release string) GeneratedSecret {
+	return GeneratedSecret{
+		Release: release,
+		Path:    c.Path,
+		Policy:  c.PasswordPolicy,
+		Keys:    map[string]string{"password": ""},
+		Values:  map[string]string{"username": c.Username},
+	}
+}
+
+// builtinProfiles are the profiles of releases which deviate from the DefaultReleaseProfile
+var builtinProfiles = map[string]ReleaseProfile{
+	"keycloak": {
+		Credentials: []ReleaseCredential{
+			{Path: "credentials/postgresql", Username: "keycloak"},
+		},
+	},
+}
+
+// DefaultReleaseProfile returns the profile of a release whose Service Account and namespace are named
+// like the release itself
+func DefaultReleaseProfile(release string) ReleaseProfile {
+	return ReleaseProfile{
+		Release:         release,
+		Mount:           DefaultKubernetesMount,
+		ServiceAccounts: []string{release},
+		Namespaces:      []string{release},
+		Audience:        "vault",
+		TokenPeriod:     "24h",
+		TokenTTL:        "0",
+	}
+}
+
+// ReleaseProfileFor returns the profile of the given release. Unset fields of the profile are filled from
+// the built-in profile and the DefaultReleaseProfile.
+func ReleaseProfileFor(release string, profiles map[string]ReleaseProfile) ReleaseProfile {
+	p := DefaultReleaseProfile(release)
+	p.Merge(builtinProfiles[release])
+	p.Merge(profiles[release])
+
+	return p
+}
+
+// Merge overwrites the fields of the profile with the set fields of o
+func (p *ReleaseProfile) Merge(o ReleaseProfile) {
+	if o.Mount != "" {
+		p.Mount = o.Mount
+	}
+
+	if len(o.ServiceAccounts) > 0 {
+		p.ServiceAccounts = o.ServiceAccounts
+	}
+
+	if len(o.Namespaces) > 0 {
+		p.Namespaces = o.Namespaces
+	}
+
+	if o.Audience != "" {
+		p.Audience = o.Audience
+	}
+
+	if o.TokenPeriod != "" {
+		p.TokenPeriod = o.TokenPeriod
+	}
+
+	if o.TokenTTL != "" {
+		p.TokenTTL = o.TokenTTL
+	}
+
+	if len(o.Policies) > 0 {
+		p.Policies = o.Policies
+	}
+
+	if len(o.Credentials) > 0 {
+		p.Credentials = o.Credentials
+	}
+}
+
+// KubernetesRole returns the KubernetesRoleSpec of the release's Kubernetes Auth role
+func (p ReleaseProfile) KubernetesRole() KubernetesRoleSpec {
+	return KubernetesRoleSpec{
+		Name:            p.Release,
+		Mount:           p.Mount,
+		ServiceAccounts: p.ServiceAccounts,
+		Namespaces:      p.Namespaces,
+		Audience:        p.Audience,
+		Policies:        append([]string{p.Release}, p.Policies...),
+		TokenPeriod:     p.TokenPeriod,
+		TokenTTL:        p.TokenTTL,
+	}
+}
+
+// LoadReleaseProfiles reads release profiles from a YAML file containing a list of ReleaseProfiles
+func LoadReleaseProfiles(path string) (map[string]ReleaseProfile, error) {
+	raw, err := fs.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read release profiles: %s. Error: %v", path, err)
+	}
+
+	return ParseReleaseProfiles(raw)
+}
+
+// ParseReleaseProfiles parses a YAML list of ReleaseProfiles and maps them by release
+func ParseReleaseProfiles(raw []byte) (map[string]ReleaseProfile, error) {
+	var list []ReleaseProfile
+	if err := yaml.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("cannot parse release profiles: %v", err)
+	}
+
+	profiles := make(map[string]ReleaseProfile, len(list))
+	for _, p := range list {
+		if p.Release == "" {
+			return nil, fmt.Errorf("release profile without release")
+		}
+
+		if _, ok := profiles[p.Release]; ok {
+			return nil, fmt.Errorf("duplicate release profile: %s", p.Release)
+		}
+
+		profiles[p.Release] = p
+	}
+
+	return profiles, nil
+}
+
+// PrepareChanges computes the Changes required to prepare Vault for the given releases: their ACL
+// policies, their Kubernetes Auth roles and their generated credentials
+func PrepareChanges(a *app.State, profiles []ReleaseProfile, kvMount string) ([]Change, error) {
+	ctx := context.Background()
+	spec := &Spec{}
+	for _, p := range profiles {
+		spec.Releases = append(spec.Releases, p.Release)
+		spec.KubernetesRoles = append(spec.KubernetesRoles, p.KubernetesRole())
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, step := range []func(ctx context.Context, a *app.State, spec *Spec, opts ChangeOptions) ([]Change, error){
+		policyChanges,
+		kubernetesRoleChanges,
+	} {
+		c, err := step(ctx, a, spec, ChangeOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, c...)
+	}
+
+	var secrets []GeneratedSecret
+	for _, p := range profiles {
+		for _, c := range p.Credentials {
+			secrets = append(secrets, c.GeneratedSecret(p.Release))
+		}
+	}
+
+	if len(secrets) == 0 {
+		return changes, nil
+	}
+
+	c, err := GenerateSecretsChanges(a, secrets, GenerateOptions{Mount: kvMount})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(changes, c...), nil
+}
+
+// ProfileReleases returns the built-in releases followed by any additional releases with a profile
+func ProfileReleases(profiles map[string]ReleaseProfile) []string {
+	releases := append([]string{}, Releases...)
+	for _, r := range sortedKeys(profiles) {
+		if !helpers.SliceContains(releases, r) {
+			releases = append(releases, r)
+		}
+	}
+
+	return releases
+}
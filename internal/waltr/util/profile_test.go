@@ -0,0 +1,45 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseProfileFor(t *testing.T) {
+	profiles, err := ParseReleaseProfiles([]byte(`
+- release: gitlab
+  serviceAccounts: [gitlab-webservice, gitlab-sidekiq]
+  tokenPeriod: 1h
+  policies: [database]
+- release: grafana
+  namespaces: [monitoring]
+`))
+	assert.NoError(t, err)
+
+	gitlab := ReleaseProfileFor("gitlab", profiles)
+	assert.Equal(t, []string{"gitlab-webservice", "gitlab-sidekiq"}, gitlab.ServiceAccounts)
+	assert.Equal(t, []string{"gitlab"}, gitlab.Namespaces)
+	assert.Equal(t, "1h", gitlab.TokenPeriod)
+	assert.Equal(t, "vault", gitlab.Audience)
+
+	role := gitlab.KubernetesRole()
+	assert.Equal(t, "gitlab", role.Name)
+	assert.Equal(t, []string{"gitlab", "database"}, role.Policies)
+
+	keycloak := ReleaseProfileFor("keycloak", profiles)
+	assert.Len(t, keycloak.Credentials, 1)
+	assert.Equal(t, []string{"keycloak"}, keycloak.ServiceAccounts)
+
+	keycloak.Merge(ReleaseProfile{Audience: "https://kubernetes.default.svc"})
+	assert.Equal(t, "https://kubernetes.default.svc", keycloak.Audience)
+
+	releases := ProfileReleases(profiles)
+	assert.Equal(t, Releases, releases[:len(Releases)])
+	assert.Contains(t, releases, "grafana")
+}
+
+func TestParseReleaseProfilesDuplicate(t *testing.T) {
+	_, err := ParseReleaseProfiles([]byte("[{release: a}, {release: a}]"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+package util
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+)
+
+// RekeyOptions configure a rekey operation
+type RekeyOptions struct {
+	// Shares is the number of key shares to split the new root key into
+	Shares int
+
+	// Threshold is the number of key shares required to reconstruct the new root key
+	Threshold int
+
+	// PGPKeys are base64-encoded PGP public keys to encrypt the new shares with, one per share
+	PGPKeys []string
+
+	// Backup stores the PGP-encrypted shares within Vault's core, so they can be recovered
+	Backup bool
+
+	// Recovery rekeys the recovery keys of an auto-unsealed Vault instead of its unseal keys
+	Recovery bool
+
+	// Reset cancels any rekey operation already in progress before starting a new one
+	Reset bool
+}
+
+// rekeyStatus is the response of the 'sys/rekey/init' and 'sys/rekey/update' endpoints
+type rekeyStatus struct {
+	Nonce           string   `json:"nonce"`
+	Started         bool     `json:"started"`
+	T               int      `json:"t"`
+	N               int      `json:"n"`
+	Progress        int      `json:"progress"`
+	Required        int      `json:"required"`
+	Complete        bool     `json:"complete"`
+	Keys            []string `json:"keys"`
+	KeysBase64      []string `json:"keys_base64"`
+	PgpFingerprints []string `json:"pgp_fingerprints"`
+}
+
+// rootGenerationStatus is the response of the 'sys/generate-root/attempt' and 'sys/generate-root/update'
+// endpoints
+type rootGenerationStatus struct {
+	Nonce        string `json:"nonce"`
+	Started      bool   `json:"started"`
+	Progress     int    `json:"progress"`
+	Required     int    `json:"required"`
+	Complete     bool   `json:"complete"`
+	EncodedToken string `json:"encoded_token"`
+	OTP          string `json:"otp"`
+	OTPLength    int    `json:"otp_length"`
+}
+
+// Rekey generates new unseal (or recovery) key shares by submitting the current keys. If PGP keys are
+// given, the returned shares are encrypted for their respective holders.
+func Rekey(a *app.State, keys []string, opts RekeyOptions) (*Credentials, error) {
+	ctx := context.Background()
+	base := "sys/rekey"
+	if opts.Recovery {
+		base = "sys/rekey-recovery-key"
+	}
+
+	if len(opts.PGPKeys) > 0 && len(opts.PGPKeys) != opts.Shares {
+		return nil, fmt.Errorf("the number of PGP keys (%d) must match the number of key shares (%d)",
+			len(opts.PGPKeys), opts.Shares)
+	}
+
+	if opts.Reset {
+		if _, err := a.VaultClient.Delete(ctx, base+"/init"); err != nil {
+			return nil, fmt.Errorf("could not cancel rekey operation in progress: %v", err)
+		}
+	}
+
+	req := map[string]interface{}{
+		"secret_shares":    opts.Shares,
+		"secret_threshold": opts.Threshold,
+	}
+	if len(opts.PGPKeys) > 0 {
+		req["pgp_keys"] = opts.PGPKeys
+		req["backup"] = opts.Backup
+	}
+
+	res, err := a.VaultClient.Write(ctx, base+"/init", req)
+	if err != nil {
+		return nil, fmt.Errorf("could not start rekey operation: %v", err)
+	}
+
+	var status rekeyStatus
+	if err := decodeResponse(res.Data, &status); err != nil {
+		return nil, fmt.Errorf("invalid rekey response: %v", err)
+	}
+	a.Log.Infof("started rekey operation with nonce: %s. %d key shares are required", status.Nonce, status.Required)
+
+	nonce := status.Nonce
+	for i := 0; !status.Complete; i++ {
+		if i >= len(keys) {
+			return nil, fmt.Errorf("ran out of keys at rekey progress %d/%d", status.Progress, status.Required)
+		}
+
+		res, err := a.VaultClient.Write(ctx, base+"/update", map[string]interface{}{
+			"key":   keys[i],
+			"nonce": nonce,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not submit key share to rekey operation: %v", err)
+		}
+
+		status = rekeyStatus{}
+		if err := decodeResponse(res.Data, &status); err != nil {
+			return nil, fmt.Errorf("invalid rekey response: %v", err)
+		}
+
+		if !status.Complete {
+			a.Log.Infof("rekey progress %d/%d", status.Progress, status.Required)
+		}
+	}
+
+	for i, fp := range status.PgpFingerprints {
+		a.Log.Infof("key share %d is encrypted for PGP key: %s", i+1, fp)
+	}
+
+	return &Credentials{
+		Keys:       status.Keys,
+		KeysBase64: status.KeysBase64,
+	}, nil
+}
+
+// GenerateRoot generates a new root token by submitting unseal (or recovery) keys to an OTP-based
+// root token generation
+func GenerateRoot(a *app.State, keys []string, reset bool) (string, error) {
+	ctx := context.Background()
+	if reset {
+		if _, err := a.VaultClient.System.RootTokenGenerationCancel(ctx); err != nil {
+			return "", fmt.Errorf("could not cancel root token generation in progress: %v", err)
+		}
+	}
+
+	res, err := a.VaultClient.Write(ctx, "sys/generate-root/attempt", map[string]interface{}{})
+	if err != nil {
+		return "", fmt.Errorf("could not start root token generation: %v", err)
+	}
+
+	var status rootGenerationStatus
+	if err := decodeResponse(res.Data, &status); err != nil {
+		return "", fmt.Errorf("invalid root token generation response: %v", err)
+	}
+
+	if status.OTP == "" {
+		return "", fmt.Errorf("vault did not return a one-time password. Vault versions before 1.10 are not supported")
+	}
+	a.Log.Infof("started root token generation with nonce: %s. %d key shares are required", status.Nonce,
+		status.Required)
+
+	otp, nonce := status.OTP, status.Nonce
+	for i := 0; !status.Complete; i++ {
+		if i >= len(keys) {
+			return "", fmt.Errorf("ran out of keys at root token generation progress %d/%d", status.Progress,
+				status.Required)
+		}
+
+		res, err := a.VaultClient.Write(ctx, "sys/generate-root/update", map[string]interface{}{
+			"key":   keys[i],
+			"nonce": nonce,
+		})
+		if err != nil {
+			return "", fmt.Errorf("could not submit key share to root token generation: %v", err)
+		}
+
+		status = rootGenerationStatus{}
+		if err := decodeResponse(res.Data, &status); err != nil {
+			return "", fmt.Errorf("invalid root token generation response: %v", err)
+		}
+
+		if !status.Complete {
+			a.Log.Infof("root token generation progress %d/%d", status.Progress, status.Required)
+		}
+	}
+
+	return DecodeRootToken(status.EncodedToken, otp)
+}
+
+// DecodeRootToken decodes the encoded token of a completed root token generation with the OTP it was
+// started with, just like 'vault operator generate-root -decode' does
+func DecodeRootToken(encoded, otp string) (string, error) {
+	raw, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encoded root token: %v", err)
+	}
+
+	if len(raw) != len(otp) {
+		return "", fmt.Errorf("encoded root token and one-time password differ in length")
+	}
+
+	token := make([]byte, len(raw))
+	for i := range raw {
+		token[i] = raw[i] ^ otp[i]
+	}
+
+	return string(token), nil
+}
@@ -0,0 +1,25 @@
+package util
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeRootToken(t *testing.T) {
+	token := "hvs.Zm9vYmFyYmF6cXV4cXV1eA"
+	otp := "5Bs7KqW1n9Z3eXh2LkP0aQr8Tf4Yc"[:len(token)]
+
+	xored := make([]byte, len(token))
+	for i := range token {
+		xored[i] = token[i] ^ otp[i]
+	}
+
+	decoded, err := DecodeRootToken(base64.RawStdEncoding.EncodeToString(xored), otp)
+	assert.NoError(t, err)
+	assert.Equal(t, token, decoded)
+
+	_, err = DecodeRootToken(base64.RawStdEncoding.EncodeToString(xored), otp[1:])
+	assert.Error(t, err)
+}
@@ -0,0 +1,299 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/kube"
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ShamirSeal is the type of Vault's default seal, which is unsealed with key shares
+const ShamirSeal = "shamir"
+
+// sealAttributes are the attributes the auto-unseal seal types require. Vault reads most of them from
+// environment variables as well, so missing attributes only result in warnings.
+var sealAttributes = map[string][]string{
+	"alicloudkms":   {"kms_key_id"},
+	"awskms":        {"kms_key_id"},
+	"azurekeyvault": {"vault_name", "key_name"},
+	"gcpckms":       {"project", "region", "key_ring", "crypto_key"},
+	"ocikms":        {"key_id", "crypto_endpoint", "management_endpoint"},
+	"pkcs11":        {"lib", "key_label"},
+	"transit":       {"address", "key_name", "mount_path"},
+}
+
+// SealMigration is a validated migration between the current seal of Vault and the seal configured in
+// its ConfigMap
+type SealMigration struct {
+	From     string   `json:"from" yaml:"from"`
+	To       string   `json:"to" yaml:"to"`
+	Leader   string   `json:"leader" yaml:"leader"`
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+
+	// Pods are the Vault Pods in the order they're restarted: the standbys first, the leader last
+	Pods []corev1.Pod `json:"-" yaml:"-"`
+}
+
+// Required reports whether Vault's seal differs from the configured seal
+func (m SealMigration) Required() bool {
+	return m.From != m.To
+}
+
+// PlanSealMigration compares the seal the Vault leader reports with the seal configured in the Vault
+// ConfigMaps and validates the configuration for a migration between them
+func PlanSealMigration(a *app.State, pods []corev1.Pod, timeout time.Duration) (*SealMigration, error) {
+	configs, err := VaultConfigs(a, pods)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("found no Vault configuration ConfigMap. Configure the new seal through the " +
+			"chart's 'config' values first")
+	}
+
+	leader, err := LeaderPod(a, pods)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	vc, err := ForwardPod(ctx, a, *leader)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := waitForSealStatus(ctx, vc)
+	if err != nil {
+		return nil, fmt.Errorf("the Vault API of Pod: %s did not become available: %v", leader.Name, err)
+	}
+
+	if !status.Initialized {
+		return nil, fmt.Errorf("cannot migrate the seal of uninitialized Vault")
+	}
+
+	m := &SealMigration{From: status.Type, Leader: leader.Name}
+	for _, name := range sortedKeys(configs) {
+		to, warnings, err := validateSealConfig(name, configs[name], m.From)
+		if err != nil {
+			return nil, err
+		}
+
+		if m.To != "" && m.To != to {
+			return nil, fmt.Errorf("the Vault configurations disagree on the seal: %s and %s", m.To, to)
+		}
+
+		m.To = to
+		m.Warnings = append(m.Warnings, warnings...)
+	}
+
+	for _, p := range pods {
+		if p.Name != leader.Name {
+			m.Pods = append(m.Pods, p)
+		}
+	}
+	sort.Slice(m.Pods, func(i, j int) bool { return m.Pods[i].Name < m.Pods[j].Name })
+	m.Pods = append(m.Pods, *leader)
+
+	return m, nil
+}
+
+// validateSealConfig returns the seal type the configuration migrates to and warnings about attributes
+// missing from its seal stanza
+func validateSealConfig(name string, cfg *VaultConfig, from string) (string, []string, error) {
+	to := ShamirSeal
+	var warnings []string
+	if s := cfg.ActiveSeal(); s != nil {
+		required, ok := sealAttributes[s.Type]
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported seal type: %s in %s", s.Type, name)
+		}
+
+		attrs, diags := s.Remain.JustAttributes()
+		if diags.HasErrors() {
+			return "", nil, fmt.Errorf("invalid seal stanza: %s in %s. Error: %v", s.Type, name, diags)
+		}
+
+		for _, r := range required {
+			if _, ok := attrs[r]; !ok {
+				warnings = append(warnings, fmt.Sprintf("seal stanza: %s in %s does not set: %s. It must be "+
+					"provided through the environment of the Vault Pods", s.Type, name, r))
+			}
+		}
+
+		to = s.Type
+	}
+
+	if from == to || from == ShamirSeal {
+		return to, warnings, nil
+	}
+
+	// Vault decrypts its root key with the previous seal during the migration
+	for _, s := range cfg.Seals {
+		if s.Type == from && s.IsDisabled() {
+			return to, warnings, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("%s must keep the current seal stanza: %s with 'disabled = \"true\"' to migrate "+
+		"away from it", name, from)
+}
+
+// RenderSealMigration writes the migration to w in the given format
+func RenderSealMigration(w io.Writer, format string, m *SealMigration) error {
+	return RenderOutput(w, format, m, func(tw io.Writer) {
+		fmt.Fprintln(tw, "STEP\tPOD\tROLE\tSEAL")
+		for i, p := range m.Pods {
+			role := "standby"
+			if p.Name == m.Leader {
+				role = "leader"
+			}
+
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s -> %s\n", i+1, p.Name, role, m.From, m.To)
+		}
+	})
+}
+
+// MigratePod restarts a single Vault Pod, so that it picks up the new seal configuration, and unseals it.
+// Pods in migration mode are unsealed with migrate=true, Pods of a Shamir seal regularly, while Pods of an
+// auto-unseal seal are awaited.
+func MigratePod(ctx context.Context, a *app.State, pod corev1.Pod, keys []string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	restarted, err := restartPod(ctx, a, pod)
+	if err != nil {
+		return err
+	}
+
+	vc, err := ForwardPod(ctx, a, *restarted)
+	if err != nil {
+		return err
+	}
+
+	status, err := waitForSealStatus(ctx, vc)
+	if err != nil {
+		return fmt.Errorf("the Vault API of Pod: %s did not become available: %v", pod.Name, err)
+	}
+
+	for status.Sealed {
+		if status.Migration || status.Type == ShamirSeal {
+			a.Log.Infof("submitting keys to Vault Pod: %s with migrate=%t", pod.Name, status.Migration)
+			status, err = submitUnsealKeys(ctx, vc, pod.Name, keys, status.Migration)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for Vault Pod: %s to auto-unseal", pod.Name)
+		case <-time.After(pollInterval):
+		}
+
+		status, err = waitForSealStatus(ctx, vc)
+		if err != nil {
+			return fmt.Errorf("could not get seal status of Vault Pod: %s. Error: %v", pod.Name, err)
+		}
+	}
+
+	a.Log.Infof("Vault Pod: %s is unsealed with seal: %s", pod.Name, status.Type)
+	return nil
+}
+
+// restartPod deletes the Pod and waits until its controller recreated and started it
+func restartPod(ctx context.Context, a *app.State, pod corev1.Pod) (*corev1.Pod, error) {
+	a.Log.Infof("restarting Vault Pod: %s", pod.Name)
+	if err := a.Kube.DeletePod(pod.Namespace, pod.Name, metav1.DeleteOptions{}); err != nil {
+		return nil, fmt.Errorf("could not delete Vault Pod: %s. Error: %v", pod.Name, err)
+	}
+
+	for {
+		p, err := a.Kube.Pod(pod.Namespace, pod.Name, metav1.GetOptions{})
+		switch {
+		case err != nil && !k8serrors.IsNotFound(err):
+			return nil, fmt.Errorf("could not get Vault Pod: %s. Error: %v", pod.Name, err)
+		case err == nil && p.UID != pod.UID && kube.PodRunning(p):
+			return p, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for Vault Pod: %s to restart", pod.Name)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// submitUnsealKeys submits keys to a single Vault Pod until it is unsealed. Unlike the typed client,
+// the raw request supports the 'migrate' parameter.
+func submitUnsealKeys(ctx context.Context, vc *vault.Client, pod string, keys []string,
+	migrate bool) (schema.SealStatusResponse, error) {
+	var status schema.SealStatusResponse
+	for i := range keys {
+		res, err := vc.Write(ctx, "sys/unseal", map[string]interface{}{
+			"key":     keys[i],
+			"migrate": migrate,
+		})
+		if err != nil {
+			return status, fmt.Errorf("could not unseal Vault Pod: %s. Error: %v", pod, err)
+		}
+
+		status = schema.SealStatusResponse{}
+		if err := decodeResponse(res.Data, &status); err != nil {
+			return status, fmt.Errorf("invalid unseal response of Vault Pod: %s. Error: %v", pod, err)
+		}
+
+		if !status.Sealed {
+			return status, nil
+		}
+	}
+
+	return status, fmt.Errorf("ran out of keys for Vault Pod: %s at progress %d/%d", pod, status.Progress,
+		status.T)
+}
+
+// VerifySeal verifies that the Vault leader completed the migration to the given seal and that the keys,
+// which are recovery keys for auto-unseal seals, are valid by generating and revoking a root token
+func VerifySeal(a *app.State, seal string, keys []string) error {
+	res, err := a.VaultClient.System.SealStatus(context.Background())
+	if err != nil {
+		return fmt.Errorf("could not get Vault seal status: %v", err)
+	}
+
+	status := res.Data
+	switch {
+	case status.Sealed:
+		return fmt.Errorf("vault is still sealed")
+	case status.Migration:
+		return fmt.Errorf("vault has not completed the seal migration yet")
+	case status.Type != seal:
+		return fmt.Errorf("vault reports seal: %s instead of: %s", status.Type, seal)
+	case status.RecoverySeal != (seal != ShamirSeal):
+		return fmt.Errorf("vault reports recovery_seal: %t for seal: %s", status.RecoverySeal, seal)
+	}
+
+	token, err := GenerateRoot(a, keys, false)
+	if err != nil {
+		return fmt.Errorf("could not verify keys: %v", err)
+	}
+
+	if err := RevokeToken(a, token, "", false); err != nil {
+		return fmt.Errorf("verified keys but could not revoke the generated root token: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSealConfig(t *testing.T) {
+	transit, err := ParseVaultConfig(`
+seal "transit" {
+  address    = "https://vault-unsealer:8200"
+  key_name   = "autounseal"
+  mount_path = "transit/"
+}
+`, "extraconfig-from-values.hcl")
+	assert.NoError(t, err)
+	assert.Equal(t, "transit", transit.ActiveSeal().Type)
+
+	to, warnings, err := validateSealConfig("vault-config", transit, ShamirSeal)
+	assert.NoError(t, err)
+	assert.Equal(t, "transit", to)
+	assert.Empty(t, warnings)
+
+	kms, err := ParseVaultConfig(`
+seal "awskms" {
+  region = "eu-central-1"
+}
+`, "extraconfig-from-values.hcl")
+	assert.NoError(t, err)
+
+	// the previous seal must be kept as disabled stanza
+	_, _, err = validateSealConfig("vault-config", kms, "transit")
+	assert.Error(t, err)
+
+	to, warnings, err = validateSealConfig("vault-config", kms, ShamirSeal)
+	assert.NoError(t, err)
+	assert.Equal(t, "awskms", to)
+	assert.Len(t, warnings, 1)
+
+	shamir, err := ParseVaultConfig(`
+seal "transit" {
+  disabled   = "true"
+  address    = "https://vault-unsealer:8200"
+  key_name   = "autounseal"
+  mount_path = "transit/"
+}
+`, "extraconfig-from-values.hcl")
+	assert.NoError(t, err)
+	assert.Nil(t, shamir.ActiveSeal())
+
+	to, _, err = validateSealConfig("vault-config", shamir, "transit")
+	assert.NoError(t, err)
+	assert.Equal(t, ShamirSeal, to)
+
+	unknown, err := ParseVaultConfig(`seal "enigma" {}`, "extraconfig-from-values.hcl")
+	assert.NoError(t, err)
+	_, _, err = validateSealConfig("vault-config", unknown, ShamirSeal)
+	assert.Error(t, err)
+}
@@ -0,0 +1,308 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"gopkg.in/yaml.v3"
+)
+
+// SecretsSpec is the file format of 'waltr secrets generate'. It declares KV-v2 secrets whose values
+// Vault generates from password policies.
+type SecretsSpec struct {
+	Mount   string            `yaml:"mount"`
+	Secrets []GeneratedSecret `yaml:"secrets"`
+}
+
+// GeneratedSecret is a secret stored at '<release>/<path>' within the KV-v2 mount. Its Keys map the keys
+// generated by Vault to the password policy to use, falling back to the Policy of the secret and then
+// DefaultPasswordPolicy. Static Values, like usernames, are written as given and overwrite differing values.
+type GeneratedSecret struct {
+	Release string            `yaml:"release"`
+	Path    string            `yaml:"path"`
+	Policy  string            `yaml:"policy"`
+	Keys    map[string]string `yaml:"keys"`
+	Values  map[string]string `yaml:"values"`
+}
+
+// SecretPath returns the path of the secret within the KV-v2 mount
+func (s GeneratedSecret) SecretPath() string {
+	return path.Join(s.Release, s.Path)
+}
+
+// KeyPolicy returns the password policy the given key is generated from
+func (s GeneratedSecret) KeyPolicy(key string) string {
+	switch {
+	case s.Keys[key] != "":
+		return s.Keys[key]
+	case s.Policy != "":
+		return s.Policy
+	default:
+		return DefaultPasswordPolicy
+	}
+}
+
+// GeneratedValue is a value Vault generated while applying the Changes of GenerateSecretsChanges
+type GeneratedValue struct {
+	Path  string `json:"path" yaml:"path"`
+	Key   string `json:"key" yaml:"key"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// GenerateOptions configure which values GenerateSecretsChanges generates
+type GenerateOptions struct {
+	// Mount is the mount path of the KV-v2 secrets engine
+	Mount string
+
+	// Rotate regenerates keys which already exist within Vault
+	Rotate bool
+
+	// Record is called with every value generated while applying the Changes. Unless set, generated
+	// values never leave Vault and the KV secrets.
+	Record func(v GeneratedValue)
+}
+
+// LoadSecretsSpec reads and, if required, decrypts the SecretsSpec at path
+func LoadSecretsSpec(path string) (*SecretsSpec, error) {
+	raw, err := readSecretsFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSecretsSpec(raw)
+}
+
+// ParseSecretsSpec parses and validates a YAML SecretsSpec. The mount defaults to DefaultKVMount.
+func ParseSecretsSpec(raw []byte) (*SecretsSpec, error) {
+	var spec SecretsSpec
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("cannot parse secrets spec: %v", err)
+	}
+
+	if spec.Mount == "" {
+		spec.Mount = DefaultKVMount
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// Validate checks the SecretsSpec for missing fields and duplicate secrets or keys
+func (s *SecretsSpec) Validate() error {
+	seen := make(map[string]bool, len(s.Secrets))
+	for _, secret := range s.Secrets {
+		if secret.Release == "" || secret.Path == "" {
+			return fmt.Errorf("generated secret requires a release and a path")
+		}
+
+		p := secret.SecretPath()
+		if strings.Contains(secret.Release, "/") || path.IsAbs(secret.Path) ||
+			path.Clean("/" + secret.Path)[1:] != secret.Path {
+			return fmt.Errorf("invalid path of generated secret: %s/%s", secret.Release, secret.Path)
+		}
+
+		if seen[p] {
+			return fmt.Errorf("duplicate generated secret: %s", p)
+		}
+		seen[p] = true
+
+		if len(secret.Keys) == 0 {
+			return fmt.Errorf("generated secret: %s does not declare any keys", p)
+		}
+
+		for k := range secret.Keys {
+			if _, ok := secret.Values[k]; ok {
+				return fmt.Errorf("key: %s of secret: %s is both generated and static", k, p)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Select returns the secrets of the given releases. It returns every secret if releases is empty.
+func (s *SecretsSpec) Select(releases []string) ([]GeneratedSecret, error) {
+	if len(releases) == 0 {
+		return s.Secrets, nil
+	}
+
+	var secrets []GeneratedSecret
+	for _, r := range releases {
+		var found bool
+		for _, secret := range s.Secrets {
+			if secret.Release == r {
+				secrets = append(secrets, secret)
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("secrets spec does not contain secrets for release: %s", r)
+		}
+	}
+
+	return secrets, nil
+}
+
+// GenerateSecretsChanges computes the Changes required to write the generated secrets to the KV-v2 mount.
+// Keys which already exist are kept, unless GenerateOptions.Rotate is set, static values overwrite differing
+// values and other keys of existing secrets are preserved. Values are generated by Vault once the Changes are applied, so plans only show
+// the password policy of generated keys and whether other keys are set.
+func GenerateSecretsChanges(a *app.State, secrets []GeneratedSecret, opts GenerateOptions) ([]Change, error) {
+	if err := checkPasswordPolicies(a, secrets); err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, secret := range secrets {
+		p := secret.SecretPath()
+		current, exists, err := ReadKV(a, opts.Mount, p)
+		if err != nil {
+			return nil, err
+		}
+
+		wanted, generate := planGeneratedSecret(secret, current, opts.Rotate)
+		if exists && len(generate) == 0 && EqualKVData(current, wanted) {
+			continue
+		}
+
+		// plans never reveal anything derived from the values, not even fingerprints
+		fields := make(map[string]string, len(wanted))
+		for k, v := range wanted {
+			cur, ok := current[k]
+			switch {
+			case !ok:
+				fields[k] = "set"
+			case cur == v:
+				fields[k] = "unchanged"
+			default:
+				fields[k] = "updated"
+			}
+		}
+		for _, k := range generate {
+			fields[k] = "generated from password policy " + secret.KeyPolicy(k)
+		}
+
+		c := Change{
+			Action: Create,
+			Kind:   KindKVSecret,
+			Name:   path.Join(opts.Mount, p),
+			After:  describe(fields),
+			apply: func(ctx context.Context) error {
+				data := make(map[string]interface{}, len(wanted)+len(generate))
+				for k, v := range wanted {
+					data[k] = v
+				}
+
+				var generated []GeneratedValue
+				for _, k := range generate {
+					value, err := GeneratePasswordFromPolicy(a, secret.KeyPolicy(k))
+					if err != nil {
+						return err
+					}
+
+					data[k] = value
+					generated = append(generated, GeneratedValue{Path: p, Key: k, Value: value})
+				}
+
+				if err := WriteKV(a, opts.Mount, p, data); err != nil {
+					return err
+				}
+
+				if opts.Record == nil {
+					return nil
+				}
+
+				for _, v := range generated {
+					opts.Record(v)
+				}
+
+				return nil
+			},
+		}
+
+		if exists {
+			c.Action = Update
+			c.Before = describeKeys(current)
+		}
+
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+// describeKeys renders the keys of secret data without any information about their values
+func describeKeys(data map[string]interface{}) string {
+	fields := make(map[string]string, len(data))
+	for k := range data {
+		fields[k] = "set"
+	}
+
+	return describe(fields)
+}
+
+// planGeneratedSecret returns the data of the secret without the values to generate, and the sorted keys
+// which Vault has to generate: keys missing from the current data or, when rotating, every generated key
+func planGeneratedSecret(secret GeneratedSecret, current map[string]interface{},
+	rotate bool) (map[string]interface{}, []string) {
+	wanted := make(map[string]interface{}, len(current)+len(secret.Values))
+	for k, v := range current {
+		wanted[k] = v
+	}
+
+	// static values are declarative and overwrite differing values within Vault
+	for k, v := range secret.Values {
+		wanted[k] = v
+	}
+
+	var generate []string
+	for _, k := range sortedKeys(secret.Keys) {
+		if _, ok := wanted[k]; ok && !rotate {
+			continue
+		}
+
+		delete(wanted, k)
+		generate = append(generate, k)
+	}
+
+	return wanted, generate
+}
+
+// checkPasswordPolicies verifies that the password policies of all generated keys exist within Vault
+func checkPasswordPolicies(a *app.State, secrets []GeneratedSecret) error {
+	existing, err := PasswordPolicies(a)
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range secrets {
+		for _, k := range sortedKeys(secret.Keys) {
+			policy := secret.KeyPolicy(k)
+			if !helpers.SliceContains(existing, policy) {
+				return fmt.Errorf("password policy: %s of key: %s in secret: %s does not exist. Create the "+
+					"built-in policies with 'waltr configure'", policy, k, secret.SecretPath())
+			}
+		}
+	}
+
+	return nil
+}
+
+// RenderGeneratedValues writes the generated values to w in the given format
+func RenderGeneratedValues(w io.Writer, format string, values []GeneratedValue) error {
+	return RenderOutput(w, format, values, func(tw io.Writer) {
+		fmt.Fprintln(tw, "PATH\tKEY\tVALUE")
+		for _, v := range values {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Path, v.Key, v.Value)
+		}
+	})
+}
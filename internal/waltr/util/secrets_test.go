@@ -0,0 +1,85 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSecretsSpec(t *testing.T) {
+	spec, err := ParseSecretsSpec([]byte(`
+secrets:
+  - release: gitlab
+    path: credentials/postgresql
+    values:
+      username: gitlab
+    keys:
+      password: ""
+  - release: minio
+    path: credentials/root
+    policy: s3-access-key
+    keys:
+      accesskey: ""
+      secretkey: alphanumeric-password
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultKVMount, spec.Mount)
+	assert.Equal(t, "gitlab/credentials/postgresql", spec.Secrets[0].SecretPath())
+	assert.Equal(t, DefaultPasswordPolicy, spec.Secrets[0].KeyPolicy("password"))
+	assert.Equal(t, "s3-access-key", spec.Secrets[1].KeyPolicy("accesskey"))
+	assert.Equal(t, "alphanumeric-password", spec.Secrets[1].KeyPolicy("secretkey"))
+
+	minio, err := spec.Select([]string{"minio"})
+	assert.NoError(t, err)
+	assert.Len(t, minio, 1)
+
+	_, err = spec.Select([]string{"grafana"})
+	assert.Error(t, err)
+}
+
+func TestParseSecretsSpecInvalid(t *testing.T) {
+	for name, raw := range map[string]string{
+		"missing path":     "secrets: [{release: a, keys: {password: ''}}]",
+		"missing keys":     "secrets: [{release: a, path: b}]",
+		"escaping path":    "secrets: [{release: a, path: ../b, keys: {password: ''}}]",
+		"duplicate":        "secrets: [{release: a, path: b, keys: {k: ''}}, {release: a, path: b/, keys: {k: ''}}]",
+		"generated+static": "secrets: [{release: a, path: b, keys: {k: ''}, values: {k: v}}]",
+	} {
+		_, err := ParseSecretsSpec([]byte(raw))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestPlanGeneratedSecret(t *testing.T) {
+	secret := GeneratedSecret{
+		Release: "keycloak",
+		Path:    "credentials/postgresql",
+		Keys:    map[string]string{"password": "", "admin-password": ""},
+		Values:  map[string]string{"username": "keycloak"},
+	}
+
+	wanted, generate := planGeneratedSecret(secret, nil, false)
+	assert.Equal(t, map[string]interface{}{"username": "keycloak"}, wanted)
+	assert.Equal(t, []string{"admin-password", "password"}, generate)
+
+	current := map[string]interface{}{"username": "kc", "password": "s3cr3t", "extra": "kept"}
+	wanted, generate = planGeneratedSecret(secret, current, false)
+	assert.Equal(t, map[string]interface{}{"username": "keycloak", "password": "s3cr3t", "extra": "kept"}, wanted)
+	assert.Equal(t, []string{"admin-password"}, generate)
+
+	wanted, generate = planGeneratedSecret(secret, current, true)
+	assert.Equal(t, map[string]interface{}{"username": "keycloak", "extra": "kept"}, wanted)
+	assert.Equal(t, []string{"admin-password", "password"}, generate)
+	assert.Equal(t, "s3cr3t", current["password"])
+	assert.Equal(t, "kc", current["username"])
+}
+
+func TestDescribeKeys(t *testing.T) {
+	key, err := fingerprintKey()
+	assert.NoError(t, err)
+
+	described := describeKeys(map[string]interface{}{"password": "s3cr3t"})
+	assert.NotContains(t, described, "s3cr3t")
+	assert.NotContains(t, described, fingerprint("s3cr3t", key))
+	assert.Contains(t, described, "password")
+}
@@ -0,0 +1,203 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/core"
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+)
+
+const (
+	// SnapshotExtension is the file extension of Raft snapshots written by 'waltr snapshot save'
+	SnapshotExtension = ".snap"
+
+	// EncryptedSnapshotExtension is the file extension of passphrase-encrypted Raft snapshots
+	EncryptedSnapshotExtension = ".snap.enc"
+
+	snapshotTimeFormat = "20060102T150405Z"
+)
+
+// snapshotNamePattern matches the names of the snapshots SnapshotPath builds
+var snapshotNamePattern = regexp.MustCompile(`^vault-\d{8}T\d{6}Z\.snap(\.enc)?$`)
+
+// Snapshot describes a Raft snapshot stored on the local filesystem
+type Snapshot struct {
+	Name      string    `json:"name" yaml:"name"`
+	Path      string    `json:"path" yaml:"path"`
+	Size      int64     `json:"size" yaml:"size"`
+	Created   time.Time `json:"created" yaml:"created"`
+	Encrypted bool      `json:"encrypted" yaml:"encrypted"`
+}
+
+// SnapshotDir builds the directory snapshots are stored in for the given core.Environment
+func SnapshotDir(a *app.State, env core.Environment) string {
+	return filepath.Join(a.Paths.Data, "snapshots", env.String())
+}
+
+// SnapshotPath builds a new, timestamped snapshot path within the SnapshotDir
+func SnapshotPath(a *app.State, env core.Environment, encrypted bool) string {
+	ext := SnapshotExtension
+	if encrypted {
+		ext = EncryptedSnapshotExtension
+	}
+
+	name := fmt.Sprintf("vault-%s%s", time.Now().UTC().Format(snapshotTimeFormat), ext)
+	return filepath.Join(SnapshotDir(a, env), name)
+}
+
+// SaveSnapshot takes a snapshot of Vault's integrated storage through the State's VaultClient and
+// writes it to path. If passphrase is set the snapshot is encrypted with it.
+func SaveSnapshot(a *app.State, path, passphrase string) error {
+	res, err := a.VaultClient.ReadRaw(context.Background(), "sys/storage/raft/snapshot")
+	if err != nil {
+		return fmt.Errorf("could not take Raft snapshot: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not take Raft snapshot: Vault responded with status %s", res.Status)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("could not read Raft snapshot: %v", err)
+	}
+
+	if passphrase != "" {
+		data, err = helpers.Encrypt(data, passphrase)
+		if err != nil {
+			return fmt.Errorf("could not encrypt Raft snapshot: %v", err)
+		}
+	}
+
+	if err := fs.Write(path, data); err != nil {
+		return fmt.Errorf("could not write Raft snapshot to: %s. Error: %v", path, err)
+	}
+
+	return nil
+}
+
+// RestoreSnapshot restores the snapshot at path into Vault's integrated storage. Encrypted snapshots
+// require the passphrase they were encrypted with. With force, snapshots of a different cluster (i.e.
+// with other unseal keys) are accepted.
+func RestoreSnapshot(a *app.State, path, passphrase string, force bool) error {
+	data, err := fs.Read(path)
+	if err != nil {
+		return fmt.Errorf("could not read Raft snapshot: %s. Error: %v", path, err)
+	}
+
+	if helpers.IsEncrypted(data) {
+		if passphrase == "" {
+			return fmt.Errorf("raft snapshot: %s is encrypted but no passphrase was given", path)
+		}
+
+		data, err = helpers.Decrypt(data, passphrase)
+		if err != nil {
+			return fmt.Errorf("could not decrypt Raft snapshot: %s. Error: %v", path, err)
+		}
+	}
+
+	endpoint := "sys/storage/raft/snapshot"
+	if force {
+		endpoint = "sys/storage/raft/snapshot-force"
+	}
+
+	if _, err := a.VaultClient.WriteFromBytes(context.Background(), endpoint, data); err != nil {
+		return fmt.Errorf("could not restore Raft snapshot: %s. Error: %v", path, err)
+	}
+
+	return nil
+}
+
+// Snapshots lists the snapshots within the SnapshotDir of the given core.Environment, newest first
+func Snapshots(a *app.State, env core.Environment) ([]Snapshot, error) {
+	return SnapshotsIn(SnapshotDir(a, env))
+}
+
+// SnapshotsIn lists the snapshots within dir, newest first
+func SnapshotsIn(dir string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Snapshot{}, nil
+		}
+
+		return nil, fmt.Errorf("could not list snapshot directory: %s. Error: %v", dir, err)
+	}
+
+	snapshots := make([]Snapshot, 0, len(entries))
+	for _, e := range entries {
+		encrypted := strings.HasSuffix(e.Name(), EncryptedSnapshotExtension)
+		if e.IsDir() || !(encrypted || strings.HasSuffix(e.Name(), SnapshotExtension)) {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, Snapshot{
+			Name:      e.Name(),
+			Path:      filepath.Join(dir, e.Name()),
+			Size:      info.Size(),
+			Created:   info.ModTime(),
+			Encrypted: encrypted,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.After(snapshots[j].Created)
+	})
+
+	return snapshots, nil
+}
+
+// PruneSnapshots removes all but the newest retain snapshots within dir and returns the paths of the
+// removed snapshots. Only snapshots named like the ones SnapshotPath builds are considered, so that
+// snapshots which weren't created by waltr are never removed.
+func PruneSnapshots(dir string, retain int) ([]string, error) {
+	all, err := SnapshotsIn(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, s := range all {
+		if snapshotNamePattern.MatchString(s.Name) {
+			snapshots = append(snapshots, s)
+		}
+	}
+
+	var removed []string
+	for i := retain; i < len(snapshots); i++ {
+		if err := os.Remove(snapshots[i].Path); err != nil {
+			return removed, fmt.Errorf("could not remove snapshot: %s. Error: %v", snapshots[i].Path, err)
+		}
+
+		removed = append(removed, snapshots[i].Path)
+	}
+
+	return removed, nil
+}
+
+// RenderSnapshots writes the given snapshots to w in the given output format
+func RenderSnapshots(w io.Writer, format string, snapshots []Snapshot) error {
+	return RenderOutput(w, format, snapshots, func(tw io.Writer) {
+		fmt.Fprintln(tw, "NAME\tCREATED\tSIZE\tENCRYPTED")
+		for _, s := range snapshots {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%t\n", s.Name, s.Created.Format(time.RFC3339), s.Size, s.Encrypted)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	names := []string{
+		"vault-20240103T000000Z.snap",
+		"vault-20240102T000000Z.snap.enc",
+		"vault-20240101T000000Z.snap",
+		"manual.snap",
+		"notes.txt",
+	}
+	for i, name := range names {
+		p := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(p, []byte("snapshot"), 0o600))
+		assert.NoError(t, os.Chtimes(p, now, now.Add(-time.Duration(i)*time.Hour)))
+	}
+
+	removed, err := PruneSnapshots(dir, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "vault-20240101T000000Z.snap")}, removed)
+
+	// snapshots not created by waltr are kept, even though they're the oldest
+	remaining, err := SnapshotsIn(dir)
+	assert.NoError(t, err)
+	assert.Len(t, remaining, 3)
+	assert.FileExists(t, filepath.Join(dir, "manual.snap"))
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"))
+
+	// pruning a missing directory must not fail
+	removed, err = PruneSnapshots(filepath.Join(dir, "missing"), 1)
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+}
@@ -0,0 +1,290 @@
+package util
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the declarative description of a Vault instance's configuration. 'waltr apply' converges the
+// live Vault to it. Specs can be written in YAML or HCL, the format is chosen by the file extension.
+type Spec struct {
+	// Releases are the Helm releases for which a read-only policy on 'kv/data/<release>/*' is created
+	Releases []string `yaml:"releases" hcl:"releases,optional"`
+
+	// AuthMethods are the authentication methods to enable
+	AuthMethods []AuthMethodSpec `yaml:"auth" hcl:"auth,block"`
+
+	// SecretsEngines are the secrets engines to mount
+	SecretsEngines []SecretsEngineSpec `yaml:"secrets" hcl:"secrets,block"`
+
+	// Policies are the ACL policies to write in addition to the per-release policies
+	Policies []PolicySpec `yaml:"policies" hcl:"policy,block"`
+
+	// PasswordPolicies are the password policies to write
+	PasswordPolicies []PolicySpec `yaml:"passwordPolicies" hcl:"password_policy,block"`
+
+	// KubernetesRoles are the roles to create within Kubernetes authentication methods
+	KubernetesRoles []KubernetesRoleSpec `yaml:"kubernetesRoles" hcl:"kubernetes_role,block"`
+
+	// TransitKeys are the keys to create within transit secrets engines
+	TransitKeys []TransitKeySpec `yaml:"transitKeys" hcl:"transit_key,block"`
+}
+
+// AuthMethodSpec describes an authentication method mounted at Path
+type AuthMethodSpec struct {
+	Path        string            `yaml:"path" hcl:"path,label"`
+	Type        string            `yaml:"type" hcl:"type"`
+	Description string            `yaml:"description" hcl:"description,optional"`
+	Config      map[string]string `yaml:"config" hcl:"config,optional"`
+}
+
+// SecretsEngineSpec describes a secrets engine mounted at Path
+type SecretsEngineSpec struct {
+	Path        string            `yaml:"path" hcl:"path,label"`
+	Type        string            `yaml:"type" hcl:"type"`
+	Description string            `yaml:"description" hcl:"description,optional"`
+	Options     map[string]string `yaml:"options" hcl:"options,optional"`
+}
+
+// PolicySpec describes a named ACL or password policy
+type PolicySpec struct {
+	Name   string `yaml:"name" hcl:"name,label"`
+	Policy string `yaml:"policy" hcl:"policy"`
+}
+
+// KubernetesRoleSpec describes a role within a Kubernetes authentication method. If ReleasePolicies is
+// set, the policies of all releases within the Spec are appended to the role's Policies.
+type KubernetesRoleSpec struct {
+	Name            string   `yaml:"name" hcl:"name,label"`
+	Mount           string   `yaml:"mount" hcl:"mount,optional"`
+	ServiceAccounts []string `yaml:"serviceAccounts" hcl:"service_accounts"`
+	Namespaces      []string `yaml:"namespaces" hcl:"namespaces"`
+	Audience        string   `yaml:"audience" hcl:"audience,optional"`
+	Policies        []string `yaml:"policies" hcl:"policies,optional"`
+	ReleasePolicies bool     `yaml:"releasePolicies" hcl:"release_policies,optional"`
+	TokenPeriod     string   `yaml:"tokenPeriod" hcl:"token_period,optional"`
+	TokenTTL        string   `yaml:"tokenTTL" hcl:"token_ttl,optional"`
+}
+
+// TransitKeySpec describes a key of the transit secrets engine at Mount, which defaults to
+// DefaultTransitMount. The Type defaults to Vault's default key type.
+type TransitKeySpec struct {
+	Name  string `yaml:"name" hcl:"name,label"`
+	Mount string `yaml:"mount" hcl:"mount,optional"`
+	Type  string `yaml:"type" hcl:"type,optional"`
+}
+
+// LoadSpec reads a Spec from the YAML or HCL file at the given path
+func LoadSpec(path string) (*Spec, error) {
+	raw, err := fs.Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSpec(raw, path)
+}
+
+// ParseSpec parses a Spec from raw file contents. The filename's extension determines whether the
+// contents are decoded as HCL ('.hcl') or YAML (anything else).
+func ParseSpec(raw []byte, filename string) (*Spec, error) {
+	var spec Spec
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".hcl":
+		hcp := hclparse.NewParser()
+		f, diags := hcp.ParseHCL(raw, filepath.Base(filename))
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("cannot parse HCL specification: %s. Error: %v", filename, diags)
+		}
+
+		if diags := gohcl.DecodeBody(f.Body, nil, &spec); diags.HasErrors() {
+			return nil, fmt.Errorf("invalid HCL specification: %s. Error: %v", filename, diags)
+		}
+	default:
+		if err := yaml.Unmarshal(raw, &spec); err != nil {
+			return nil, fmt.Errorf("cannot parse YAML specification: %s. Error: %v", filename, err)
+		}
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// Validate checks the Spec for missing required values
+func (s *Spec) Validate() error {
+	for _, m := range s.AuthMethods {
+		if m.Path == "" || m.Type == "" {
+			return fmt.Errorf("invalid authentication method %q: path and type are required", m.Path)
+		}
+	}
+
+	for _, e := range s.SecretsEngines {
+		if e.Path == "" || e.Type == "" {
+			return fmt.Errorf("invalid secrets engine %q: path and type are required", e.Path)
+		}
+	}
+
+	for _, p := range s.Policies {
+		if p.Name == "" || p.Policy == "" {
+			return fmt.Errorf("invalid ACL policy %q: name and policy are required", p.Name)
+		}
+	}
+
+	for _, p := range s.PasswordPolicies {
+		if p.Name == "" || p.Policy == "" {
+			return fmt.Errorf("invalid password policy %q: name and policy are required", p.Name)
+		}
+	}
+
+	for _, r := range s.KubernetesRoles {
+		if r.Name == "" || len(r.ServiceAccounts) == 0 || len(r.Namespaces) == 0 {
+			return fmt.Errorf("invalid Kubernetes role %q: name, service accounts and namespaces are required",
+				r.Name)
+		}
+	}
+
+	for _, k := range s.TransitKeys {
+		if k.Name == "" {
+			return fmt.Errorf("invalid transit key: name is required")
+		}
+	}
+
+	return nil
+}
+
+// AclPolicies returns all ACL policies described by the Spec, including the per-release policies
+// built from ConfigReleasePolicyTemplate
+func (s *Spec) AclPolicies() map[string]string {
+	policies := make(map[string]string)
+	for _, r := range s.Releases {
+		policies[r] = fmt.Sprintf(ConfigReleasePolicyTemplate, r)
+	}
+
+	// explicit policies take precedence over the release template
+	for _, p := range s.Policies {
+		policies[p.Name] = p.Policy
+	}
+
+	return policies
+}
+
+// RolePolicies returns the token policies for the given KubernetesRoleSpec
+func (s *Spec) RolePolicies(r KubernetesRoleSpec) []string {
+	policies := append([]string{}, r.Policies...)
+	if r.ReleasePolicies {
+		policies = append(policies, s.Releases...)
+	}
+
+	return removeEmpty(policies)
+}
+
+// MountScope returns the part of the Spec describing authentication methods and secrets engines
+func (s *Spec) MountScope() *Spec {
+	return &Spec{
+		AuthMethods:    s.AuthMethods,
+		SecretsEngines: s.SecretsEngines,
+	}
+}
+
+// PolicyScope returns the part of the Spec describing ACL policies, including the per-release policies,
+// and password policies
+func (s *Spec) PolicyScope() *Spec {
+	return &Spec{
+		Releases:         s.Releases,
+		Policies:         s.Policies,
+		PasswordPolicies: s.PasswordPolicies,
+	}
+}
+
+// TransitScope returns the part of the Spec the Vault Secrets Operator requires for transit encryption:
+// the transit secrets engines and keys as well as the VSOAuthRole Kubernetes Auth role and the ACL
+// policies it grants
+func (s *Spec) TransitScope() *Spec {
+	scope := &Spec{
+		TransitKeys: s.TransitKeys,
+	}
+
+	for _, e := range s.SecretsEngines {
+		if e.Type == "transit" {
+			scope.SecretsEngines = append(scope.SecretsEngines, e)
+		}
+	}
+
+	var policies []string
+	for _, r := range s.KubernetesRoles {
+		if r.Name != VSOAuthRole {
+			continue
+		}
+
+		scope.KubernetesRoles = append(scope.KubernetesRoles, r)
+		policies = append(policies, r.Policies...)
+		if r.ReleasePolicies {
+			scope.Releases = s.Releases
+		}
+	}
+
+	for _, p := range s.Policies {
+		if helpers.SliceContains(policies, p.Name) {
+			scope.Policies = append(scope.Policies, p)
+		}
+	}
+
+	return scope
+}
+
+// removeEmpty removes empty strings and duplicates from a slice
+func removeEmpty(s []string) []string {
+	out := make([]string, 0, len(s))
+	for _, v := range s {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+
+	return helpers.RemoveDuplicates(out)
+}
+
+// DefaultSpec returns the built-in Spec, which mirrors the configuration the 'mounts', 'configure'
+// and 'transit' subcommands apply
+func DefaultSpec() *Spec {
+	spec := &Spec{
+		Releases: append([]string{}, Releases...),
+		AuthMethods: []AuthMethodSpec{
+			{Path: "kubernetes", Type: "kubernetes", Description: "authenticate with Kubernetes Service Account Tokens"},
+			{Path: "oidc", Type: "oidc", Description: "authenticate with OpenID Connect"},
+		},
+		SecretsEngines: []SecretsEngineSpec{
+			{Path: "kv", Type: "kv-v2", Description: "store secret values in key/value storage"},
+			{Path: "transit", Type: "transit", Description: "encrypt secrets in transit"},
+		},
+		Policies: []PolicySpec{
+			{Name: VSOAuthRole, Policy: fmt.Sprintf(ConfigReleasePolicyTemplate, VSOAuthRole)},
+		},
+		KubernetesRoles: []KubernetesRoleSpec{
+			DefaultVSOOptions().KubernetesRole(Releases),
+		},
+		TransitKeys: []TransitKeySpec{
+			{Name: VSOTransitKey},
+		},
+	}
+
+	for _, k := range sortedKeys(ConfigAclPolicies) {
+		spec.Policies = append(spec.Policies, PolicySpec{Name: k, Policy: ConfigAclPolicies[k]})
+	}
+
+	for _, k := range sortedKeys(ConfigPasswordPolicies) {
+		spec.PasswordPolicies = append(spec.PasswordPolicies, PolicySpec{Name: k, Policy: ConfigPasswordPolicies[k]})
+	}
+
+	return spec
+}
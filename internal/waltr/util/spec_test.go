@@ -0,0 +1,148 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	yamlSpec = `
+releases: ["gitlab", "harbor"]
+auth:
+  - path: kubernetes
+    type: kubernetes
+secrets:
+  - path: kv
+    type: kv-v2
+policies:
+  - name: admin
+    policy: |
+      path "sys/health" { capabilities = ["read"] }
+kubernetesRoles:
+  - name: vso-auth
+    serviceAccounts: ["vault-secrets-operator"]
+    namespaces: ["vault-secrets-operator"]
+    policies: ["vso-auth"]
+    releasePolicies: true
+    tokenPeriod: 2m
+transitKeys:
+  - name: vso-client-cache
+`
+
+	hclSpec = `
+releases = ["gitlab", "harbor"]
+
+auth "kubernetes" {
+  type = "kubernetes"
+  config = {
+    kubernetes_host = "https://10.0.0.1:443"
+  }
+}
+
+secrets "kv" {
+  type = "kv-v2"
+}
+
+policy "gitlab" {
+  policy = "path \"kv/data/gitlab/*\" { capabilities = [\"read\", \"list\"] }"
+}
+
+kubernetes_role "vso-auth" {
+  service_accounts = ["vault-secrets-operator"]
+  namespaces       = ["vault-secrets-operator"]
+  policies         = ["vso-auth"]
+  release_policies = true
+}
+
+transit_key "vso-client-cache" {
+  mount = "transit"
+  type  = "aes256-gcm96"
+}
+`
+)
+
+func TestParseSpec(t *testing.T) {
+	asrt := assert.New(t)
+
+	for name, raw := range map[string]string{"vault.yaml": yamlSpec, "vault.hcl": hclSpec} {
+		spec, err := ParseSpec([]byte(raw), name)
+		if !asrt.NoError(err, name) {
+			continue
+		}
+
+		asrt.Equal([]string{"gitlab", "harbor"}, spec.Releases, name)
+		asrt.Len(spec.AuthMethods, 1, name)
+		asrt.Equal("kubernetes", spec.AuthMethods[0].Path, name)
+		asrt.Len(spec.SecretsEngines, 1, name)
+		asrt.Len(spec.KubernetesRoles, 1, name)
+		asrt.ElementsMatch([]string{"vso-auth", "gitlab", "harbor"}, spec.RolePolicies(spec.KubernetesRoles[0]), name)
+		asrt.Len(spec.TransitKeys, 1, name)
+		asrt.Equal(VSOTransitKey, spec.TransitKeys[0].Name, name)
+	}
+
+	spec, _ := ParseSpec([]byte(yamlSpec), "vault.yaml")
+	asrt.Len(spec.AclPolicies(), 3)
+
+	// explicit policies override the release template
+	spec, _ = ParseSpec([]byte(hclSpec), "vault.hcl")
+	asrt.Len(spec.AclPolicies(), 2)
+	asrt.Contains(spec.AclPolicies()["gitlab"], "list")
+	asrt.Equal("https://10.0.0.1:443", spec.AuthMethods[0].Config["kubernetes_host"])
+}
+
+func TestParseSpecInvalid(t *testing.T) {
+	_, err := ParseSpec([]byte("auth:\n  - path: kubernetes\n"), "vault.yaml")
+	assert.Error(t, err)
+
+	_, err = ParseSpec([]byte("transitKeys:\n  - type: aes256-gcm96\n"), "vault.yaml")
+	assert.Error(t, err)
+}
+
+func TestDefaultSpec(t *testing.T) {
+	spec := DefaultSpec()
+	assert.NoError(t, spec.Validate())
+
+	// 'waltr vso generate' and 'waltr transit rewrap' rely on the client cache key
+	assert.Contains(t, spec.TransitKeys, TransitKeySpec{Name: VSOTransitKey})
+
+	// plans of the built-in Spec are stable between runs
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, spec, DefaultSpec())
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	asrt := assert.New(t)
+
+	asrt.Equal(int64(120), seconds("120"))
+	asrt.Equal(int64(86400), seconds("24h"))
+	asrt.Equal(int64(7*86400), seconds("7d"))
+	asrt.Equal(int64(-1), seconds("1.5d"))
+	asrt.Equal(int64(0), seconds(""))
+	asrt.Equal(int64(60), seconds(float64(60)))
+}
+
+func TestSpecScopes(t *testing.T) {
+	asrt := assert.New(t)
+	spec := DefaultSpec()
+
+	mounts := spec.MountScope()
+	asrt.Equal(spec.AuthMethods, mounts.AuthMethods)
+	asrt.Equal(spec.SecretsEngines, mounts.SecretsEngines)
+	asrt.Empty(mounts.AclPolicies())
+
+	policies := spec.PolicyScope()
+	asrt.Equal(spec.AclPolicies(), policies.AclPolicies())
+	asrt.Empty(policies.KubernetesRoles)
+
+	// the transit scope grants the release policies to the operator's role without hardcoding them
+	transit := spec.TransitScope()
+	asrt.Equal([]SecretsEngineSpec{{Path: "transit", Type: "transit", Description: "encrypt secrets in transit"}},
+		transit.SecretsEngines)
+	asrt.Len(transit.KubernetesRoles, 1)
+	asrt.ElementsMatch(append([]string{VSOAuthRole}, Releases...), transit.RolePolicies(transit.KubernetesRoles[0]))
+	asrt.Contains(transit.AclPolicies(), VSOAuthRole)
+	asrt.Empty(transit.PasswordPolicies)
+	asrt.Equal(spec.TransitKeys, transit.TransitKeys)
+}
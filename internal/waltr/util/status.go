@@ -0,0 +1,206 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/hashicorp/vault-client-go"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultStatusTimeout is the time the status of a single Pod may take to be retrieved
+const DefaultStatusTimeout = 30 * time.Second
+
+// PodStatus is the health of a single Vault Pod as reported by 'waltr status'
+type PodStatus struct {
+	Pod         string `json:"pod" yaml:"pod"`
+	Namespace   string `json:"namespace" yaml:"namespace"`
+	Phase       string `json:"phase" yaml:"phase"`
+	Version     string `json:"version,omitempty" yaml:"version,omitempty"`
+	Initialized bool   `json:"initialized" yaml:"initialized"`
+	Sealed      bool   `json:"sealed" yaml:"sealed"`
+	SealType    string `json:"sealType,omitempty" yaml:"sealType,omitempty"`
+	Progress    int32  `json:"unsealProgress" yaml:"unsealProgress"`
+	Threshold   int32  `json:"unsealThreshold" yaml:"unsealThreshold"`
+	HAEnabled   bool   `json:"haEnabled" yaml:"haEnabled"`
+	Leader      bool   `json:"leader" yaml:"leader"`
+	LeaderAddr  string `json:"leaderAddress,omitempty" yaml:"leaderAddress,omitempty"`
+	RaftRole    string `json:"raftRole,omitempty" yaml:"raftRole,omitempty"`
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// raftServer is a single peer of the Raft configuration returned by 'sys/storage/raft/configuration'
+type raftServer struct {
+	NodeID  string `json:"node_id"`
+	Address string `json:"address"`
+	Leader  bool   `json:"leader"`
+	Voter   bool   `json:"voter"`
+}
+
+// Status queries the seal, HA and Raft status of every given Vault Pod concurrently. The Raft peer roles
+// can only be read with a token; if token is empty they are omitted.
+func Status(a *app.State, pods []corev1.Pod, token string, timeout time.Duration) []PodStatus {
+	statuses := make([]PodStatus, len(pods))
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		peers []raftServer
+	)
+
+	for i, p := range pods {
+		wg.Add(1)
+		go func(i int, p corev1.Pod) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			status, vc := podStatus(ctx, a, p)
+			statuses[i] = status
+			if vc == nil || token == "" || status.Sealed {
+				return
+			}
+
+			if err := vc.SetToken(token); err != nil {
+				return
+			}
+
+			servers, err := raftConfiguration(ctx, vc)
+			if err != nil {
+				a.Log.Debugf("could not read Raft configuration from Vault Pod: %s. Error: %v", p.Name, err)
+				return
+			}
+
+			once.Do(func() { peers = servers })
+		}(i, p)
+	}
+	wg.Wait()
+
+	for i := range statuses {
+		statuses[i].RaftRole = raftRole(peers, statuses[i].Pod)
+	}
+
+	return statuses
+}
+
+// podStatus retrieves the status of a single Pod. The returned client is nil if the Vault API of the
+// Pod is unreachable.
+func podStatus(ctx context.Context, a *app.State, pod corev1.Pod) (PodStatus, *vault.Client) {
+	status := PodStatus{
+		Pod:       pod.Name,
+		Namespace: pod.Namespace,
+		Phase:     string(pod.Status.Phase),
+	}
+
+	if pod.Status.Phase != corev1.PodRunning {
+		status.Error = "Pod is not running"
+		return status, nil
+	}
+
+	vc, err := ForwardPod(ctx, a, pod)
+	if err != nil {
+		status.Error = err.Error()
+		return status, nil
+	}
+
+	seal, err := vc.System.SealStatus(ctx)
+	if err != nil {
+		status.Error = fmt.Sprintf("could not get Vault seal status: %v", err)
+		return status, nil
+	}
+
+	status.Version = seal.Data.Version
+	status.Initialized = seal.Data.Initialized
+	status.Sealed = seal.Data.Sealed
+	status.SealType = seal.Data.Type
+	status.Progress = seal.Data.Progress
+	status.Threshold = seal.Data.T
+
+	// sealed nodes don't know about their leader
+	if status.Sealed {
+		return status, vc
+	}
+
+	leader, err := vc.System.LeaderStatus(ctx)
+	if err != nil {
+		status.Error = fmt.Sprintf("could not get Vault leader status: %v", err)
+		return status, vc
+	}
+
+	status.HAEnabled = leader.Data.HaEnabled
+	status.Leader = leader.Data.IsSelf
+	status.LeaderAddr = leader.Data.LeaderAddress
+
+	return status, vc
+}
+
+// raftConfiguration reads the Raft peers from 'sys/storage/raft/configuration'
+func raftConfiguration(ctx context.Context, vc *vault.Client) ([]raftServer, error) {
+	res, err := vc.Read(ctx, "sys/storage/raft/configuration")
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Config struct {
+			Servers []raftServer `json:"servers"`
+		} `json:"config"`
+	}
+	if err := decodeResponse(res.Data, &config); err != nil {
+		return nil, err
+	}
+
+	return config.Config.Servers, nil
+}
+
+// raftRole determines the Raft role of a Pod. The official chart uses the Pod name as node ID and
+// addresses peers via the headless service, i.e. '<pod>.<release>-internal:8201'.
+func raftRole(peers []raftServer, pod string) string {
+	for _, s := range peers {
+		if s.NodeID != pod && !strings.HasPrefix(s.Address, pod+".") {
+			continue
+		}
+
+		switch {
+		case s.Leader:
+			return "leader"
+		case s.Voter:
+			return "follower"
+		default:
+			return "non-voter"
+		}
+	}
+
+	return ""
+}
+
+// RenderStatus writes the given statuses to w in the given output format
+func RenderStatus(w io.Writer, format string, statuses []PodStatus) error {
+	return RenderOutput(w, format, statuses, func(tw io.Writer) {
+		fmt.Fprintln(tw, "POD\tNAMESPACE\tPHASE\tVERSION\tINITIALIZED\tSEALED\tUNSEAL\tHA\tLEADER\tRAFT\tERROR")
+		for _, s := range statuses {
+			unseal := "-"
+			if s.Sealed {
+				unseal = fmt.Sprintf("%d/%d", s.Progress, s.Threshold)
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%t\t%s\t%t\t%t\t%s\t%s\n", s.Pod, s.Namespace, s.Phase,
+				orDash(s.Version), s.Initialized, s.Sealed, unseal, s.HAEnabled, s.Leader, orDash(s.RaftRole),
+				orDash(s.Error))
+		}
+	})
+}
+
+// orDash returns "-" for empty strings to keep table columns aligned
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
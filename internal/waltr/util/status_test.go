@@ -0,0 +1,20 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRaftRole(t *testing.T) {
+	peers := []raftServer{
+		{NodeID: "vault-0", Address: "vault-0.vault-internal:8201", Leader: true, Voter: true},
+		{NodeID: "6f4c2a1e", Address: "vault-1.vault-internal:8201", Voter: true},
+		{NodeID: "vault-2", Address: "vault-2.vault-internal:8201"},
+	}
+
+	assert.Equal(t, "leader", raftRole(peers, "vault-0"))
+	assert.Equal(t, "follower", raftRole(peers, "vault-1"))
+	assert.Equal(t, "non-voter", raftRole(peers, "vault-2"))
+	assert.Equal(t, "", raftRole(peers, "vault-10"))
+}
@@ -0,0 +1,325 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/tools"
+	"github.com/hashicorp/vault-client-go"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TokenSecretKey is the key of the Kubernetes Secret data tokens are written to
+const TokenSecretKey = "token"
+
+// TokenOptions configure the tokens created by CreateToken
+type TokenOptions struct {
+	DisplayName string
+	Policies    []string
+
+	// NoDefaultPolicy omits the 'default' policy which Vault attaches to tokens otherwise
+	NoDefaultPolicy bool
+
+	TTL         string
+	ExplicitMax string
+
+	// Period creates a periodic token, which can be renewed indefinitely within the period
+	Period string
+
+	// Orphan creates a token without parent, so that it outlives the revocation of the creating token
+	Orphan bool
+
+	NumUses int
+	Meta    map[string]string
+}
+
+// Token is a Vault token as returned by a lookup. The Token itself is only set for newly created tokens.
+type Token struct {
+	Token       string            `json:"token,omitempty" yaml:"token,omitempty"`
+	Accessor    string            `json:"accessor" yaml:"accessor"`
+	DisplayName string            `json:"display_name" yaml:"displayName"`
+	Policies    []string          `json:"policies" yaml:"policies"`
+	TTL         int64             `json:"ttl" yaml:"ttl"`
+	Period      int64             `json:"period,omitempty" yaml:"period,omitempty"`
+	ExpireTime  string            `json:"expire_time,omitempty" yaml:"expireTime,omitempty"`
+	Renewable   bool              `json:"renewable" yaml:"renewable"`
+	Orphan      bool              `json:"orphan" yaml:"orphan"`
+	Path        string            `json:"path,omitempty" yaml:"path,omitempty"`
+	Meta        map[string]string `json:"meta,omitempty" yaml:"meta,omitempty"`
+}
+
+// CreateToken creates a child or orphan token of the token waltr is authenticated with
+func CreateToken(a *app.State, opts TokenOptions) (*Token, error) {
+	data := map[string]interface{}{
+		"display_name":      opts.DisplayName,
+		"policies":          removeEmpty(opts.Policies),
+		"no_default_policy": opts.NoDefaultPolicy,
+		"num_uses":          opts.NumUses,
+	}
+
+	for k, v := range map[string]string{"ttl": opts.TTL, "explicit_max_ttl": opts.ExplicitMax, "period": opts.Period} {
+		if v != "" {
+			data[k] = v
+		}
+	}
+
+	if len(opts.Meta) > 0 {
+		data["meta"] = opts.Meta
+	}
+
+	p := "auth/token/create"
+	if opts.Orphan {
+		p = "auth/token/create-orphan"
+	}
+
+	res, err := a.VaultClient.Write(context.Background(), p, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not create Vault token: %v", err)
+	}
+
+	if res == nil || res.Auth == nil {
+		return nil, fmt.Errorf("vault did not return a token")
+	}
+
+	return &Token{
+		Token:       res.Auth.ClientToken,
+		Accessor:    res.Auth.Accessor,
+		DisplayName: opts.DisplayName,
+		Policies:    res.Auth.Policies,
+		TTL:         int64(res.Auth.LeaseDuration),
+		Period:      seconds(opts.Period),
+		Renewable:   res.Auth.Renewable,
+		Orphan:      res.Auth.Orphan,
+		Meta:        opts.Meta,
+	}, nil
+}
+
+// tokenRequest returns the path suffix and body addressing a token by accessor or by value. If both
+// are empty, the token waltr is authenticated with is addressed.
+func tokenRequest(op, token, accessor string) (string, map[string]interface{}) {
+	switch {
+	case accessor != "":
+		return fmt.Sprintf("auth/token/%s-accessor", op), map[string]interface{}{"accessor": accessor}
+	case token != "":
+		return "auth/token/" + op, map[string]interface{}{"token": token}
+	default:
+		return fmt.Sprintf("auth/token/%s-self", op), map[string]interface{}{}
+	}
+}
+
+// LookupToken looks up a token by accessor or by value
+func LookupToken(a *app.State, token, accessor string) (*Token, error) {
+	p, data := tokenRequest("lookup", token, accessor)
+
+	var res *vault.Response[map[string]interface{}]
+	var err error
+	if len(data) == 0 {
+		res, err = a.VaultClient.Read(context.Background(), p)
+	} else {
+		res, err = a.VaultClient.Write(context.Background(), p, data)
+	}
+
+	if err != nil {
+		if vault.IsErrorStatus(err, http.StatusForbidden) || vault.IsErrorStatus(err, http.StatusBadRequest) {
+			return nil, fmt.Errorf("token does not exist or is not accessible: %v", err)
+		}
+
+		return nil, fmt.Errorf("could not look up Vault token: %v", err)
+	}
+
+	return tokenFromLookup(res.Data), nil
+}
+
+// tokenFromLookup converts the data of a token lookup
+func tokenFromLookup(data map[string]interface{}) *Token {
+	t := &Token{
+		Accessor:    toString(data["accessor"]),
+		DisplayName: toString(data["display_name"]),
+		Policies:    toStrings(data["policies"]),
+		TTL:         seconds(data["ttl"]),
+		Period:      seconds(data["period"]),
+		ExpireTime:  toString(data["expire_time"]),
+		Renewable:   data["renewable"] == true,
+		Orphan:      data["orphan"] == true,
+		Path:        toString(data["path"]),
+	}
+
+	if meta, ok := data["meta"].(map[string]interface{}); ok {
+		t.Meta = make(map[string]string, len(meta))
+		for k, v := range meta {
+			t.Meta[k] = toString(v)
+		}
+	}
+
+	return t
+}
+
+// RenewToken renews a token by accessor or by value with the given increment and returns its new TTL
+func RenewToken(a *app.State, token, accessor, increment string) (time.Duration, error) {
+	p, data := tokenRequest("renew", token, accessor)
+	if increment != "" {
+		data["increment"] = increment
+	}
+
+	res, err := a.VaultClient.Write(context.Background(), p, data)
+	if err != nil {
+		return 0, fmt.Errorf("could not renew Vault token: %v", err)
+	}
+
+	if res == nil || res.Auth == nil {
+		return 0, fmt.Errorf("vault did not return the renewed token")
+	}
+
+	return time.Duration(res.Auth.LeaseDuration) * time.Second, nil
+}
+
+// RevokeToken revokes a token by accessor or by value. Unless orphan is set, all child tokens are
+// revoked as well. Orphaning the children requires the token's value.
+func RevokeToken(a *app.State, token, accessor string, orphan bool) error {
+	if orphan && token == "" {
+		return fmt.Errorf("revoking a token while keeping its children requires the token instead of its accessor")
+	}
+
+	if token == "" && accessor == "" {
+		return fmt.Errorf("either a token or an accessor is required")
+	}
+
+	p, data := tokenRequest("revoke", token, accessor)
+	if orphan {
+		p = "auth/token/revoke-orphan"
+	}
+
+	if _, err := a.VaultClient.Write(context.Background(), p, data); err != nil {
+		return fmt.Errorf("could not revoke Vault token: %v", err)
+	}
+
+	return nil
+}
+
+// TokenAccessors lists the accessors of all tokens. If lookup is set every accessor is looked up,
+// otherwise only the accessors are set.
+func TokenAccessors(a *app.State, lookup bool) ([]Token, error) {
+	res, err := a.VaultClient.Auth.TokenListAccessors(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("could not list Vault token accessors: %v", err)
+	}
+
+	tokens := make([]Token, 0, len(res.Data.Keys))
+	for _, acc := range res.Data.Keys {
+		if !lookup {
+			tokens = append(tokens, Token{Accessor: acc})
+			continue
+		}
+
+		t, err := LookupToken(a, "", acc)
+		if err != nil {
+			// tokens may expire between listing and looking them up
+			a.Log.Debugf("could not look up token accessor: %s. Error: %v", acc, err)
+			continue
+		}
+
+		tokens = append(tokens, *t)
+	}
+
+	return tokens, nil
+}
+
+// RenderTokens writes the tokens to w in the given format. The table includes the tokens themselves
+// only if they're known, i.e. for newly created tokens.
+func RenderTokens(w io.Writer, format string, tokens []Token) error {
+	var created bool
+	for _, t := range tokens {
+		created = created || t.Token != ""
+	}
+
+	return RenderOutput(w, format, tokens, func(tw io.Writer) {
+		header := "ACCESSOR\tDISPLAY NAME\tPOLICIES\tTTL\tPERIOD\tORPHAN\tPATH"
+		if created {
+			header = "TOKEN\t" + header
+		}
+		fmt.Fprintln(tw, header)
+
+		for _, t := range tokens {
+			period := "-"
+			if t.Period > 0 {
+				period = (time.Duration(t.Period) * time.Second).String()
+			}
+
+			if created {
+				fmt.Fprintf(tw, "%s\t", t.Token)
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%t\t%s\n", t.Accessor, orDash(t.DisplayName),
+				orDash(strings.Join(t.Policies, ",")), time.Duration(t.TTL)*time.Second, period, t.Orphan,
+				orDash(t.Path))
+		}
+	})
+}
+
+// WriteTokenSecret writes the token to the TokenSecretKey of a Kubernetes Secret, creating the Secret
+// if it doesn't exist
+func WriteTokenSecret(a *app.State, namespace, name, token string) error {
+	secret, err := a.Kube.Secret(namespace, name, metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("could not read Secret: %s/%s. Error: %v", namespace, name, err)
+		}
+
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": app.Name,
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{TokenSecretKey: []byte(token)},
+		}
+
+		if err := a.Kube.CreateSecret(namespace, secret, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("could not create Secret: %s/%s. Error: %v", namespace, name, err)
+		}
+
+		return nil
+	}
+
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data[TokenSecretKey] = []byte(token)
+
+	if err := a.Kube.UpdateSecret(namespace, secret, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not update Secret: %s/%s. Error: %v", namespace, name, err)
+	}
+
+	return nil
+}
+
+// WriteTokenSecretFile adds the token to a helm-secrets file below 'secrets.<key>', where key is a
+// dot-separated path like 'vault.tokens.ci'
+func WriteTokenSecretFile(path, key, token string, unencrypted bool) error {
+	if _, err := tools.AddSecretValue(path, nestedValue(key, token), unencrypted); err != nil {
+		return fmt.Errorf("could not add token to secrets file: %s. Error: %v", path, err)
+	}
+
+	return nil
+}
+
+// nestedValue builds nested maps from a dot-separated key with the value at the innermost key
+func nestedValue(key string, value interface{}) map[string]interface{} {
+	parts := strings.Split(key, ".")
+	out := map[string]interface{}{parts[len(parts)-1]: value}
+	for i := len(parts) - 2; i >= 0; i-- {
+		out = map[string]interface{}{parts[i]: out}
+	}
+
+	return out
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNestedValue(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{"token": "s"}, nestedValue("token", "s"))
+	assert.Equal(t, map[string]interface{}{
+		"vault": map[string]interface{}{
+			"tokens": map[string]interface{}{"ci": "s"},
+		},
+	}, nestedValue("vault.tokens.ci", "s"))
+}
+
+func TestTokenRequest(t *testing.T) {
+	p, data := tokenRequest("lookup", "", "acc")
+	assert.Equal(t, "auth/token/lookup-accessor", p)
+	assert.Equal(t, map[string]interface{}{"accessor": "acc"}, data)
+
+	p, data = tokenRequest("renew", "hvs.x", "")
+	assert.Equal(t, "auth/token/renew", p)
+	assert.Equal(t, map[string]interface{}{"token": "hvs.x"}, data)
+
+	p, data = tokenRequest("lookup", "", "")
+	assert.Equal(t, "auth/token/lookup-self", p)
+	assert.Empty(t, data)
+}
+
+func TestTokenFromLookup(t *testing.T) {
+	tok := tokenFromLookup(map[string]interface{}{
+		"accessor":     "acc",
+		"display_name": "token-ci",
+		"policies":     []interface{}{"default", "ci"},
+		"ttl":          float64(3600),
+		"period":       float64(86400),
+		"orphan":       true,
+		"meta":         map[string]interface{}{"team": "platform"},
+	})
+
+	assert.Equal(t, "acc", tok.Accessor)
+	assert.Equal(t, []string{"default", "ci"}, tok.Policies)
+	assert.Equal(t, int64(3600), tok.TTL)
+	assert.Equal(t, int64(86400), tok.Period)
+	assert.True(t, tok.Orphan)
+	assert.Equal(t, "platform", tok.Meta["team"])
+
+	var buf bytes.Buffer
+	assert.NoError(t, RenderTokens(&buf, OutputTable, []Token{*tok}))
+	assert.Contains(t, buf.String(), "24h0m0s")
+	assert.NotContains(t, buf.String(), "hvs.")
+}
+
+func TestRenderCreatedToken(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, RenderTokens(&buf, OutputTable, []Token{{Token: "hvs.secret", Accessor: "acc"}}))
+	assert.Contains(t, buf.String(), "TOKEN")
+	assert.Contains(t, buf.String(), "hvs.secret")
+}
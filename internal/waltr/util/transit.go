@@ -0,0 +1,342 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/hashicorp/vault-client-go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultTransitMount is the mount path of the transit secrets engine 'waltr transit' enables
+const DefaultTransitMount = "transit"
+
+// transitCiphertext matches ciphertexts of the transit secrets engine, e.g. 'vault:v2:<base64>'
+var transitCiphertext = regexp.MustCompile(`vault:v(\d+):[A-Za-z0-9+/]+={0,2}`)
+
+// TransitKey is the state of a transit key relevant for its rotation
+type TransitKey struct {
+	Name                 string `json:"name" yaml:"name"`
+	Type                 string `json:"type" yaml:"type"`
+	LatestVersion        int64  `json:"latest_version" yaml:"latestVersion"`
+	MinDecryptionVersion int64  `json:"min_decryption_version" yaml:"minDecryptionVersion"`
+	MinEncryptionVersion int64  `json:"min_encryption_version" yaml:"minEncryptionVersion"`
+	AutoRotatePeriod     int64  `json:"auto_rotate_period" yaml:"autoRotatePeriod"`
+}
+
+// TransitKeyConfig updates the configuration of a transit key. Unset fields remain unchanged.
+type TransitKeyConfig struct {
+	// MinDecryptionVersion is the oldest key version which can still decrypt ciphertexts. Ciphertexts of
+	// older versions must be rewrapped beforehand.
+	MinDecryptionVersion *int64
+
+	// MinEncryptionVersion is the oldest key version allowed for encryption, 0 is the latest version
+	MinEncryptionVersion *int64
+
+	// AutoRotatePeriod lets Vault rotate the key automatically, e.g. '720h'. '0' disables it.
+	AutoRotatePeriod string
+}
+
+// ReadTransitKey reads the transit key with the given name
+func ReadTransitKey(a *app.State, mount, name string) (*TransitKey, error) {
+	res, err := a.VaultClient.Read(context.Background(), path.Join(mount, "keys", name))
+	if err != nil {
+		if vault.IsErrorStatus(err, http.StatusNotFound) {
+			return nil, fmt.Errorf("transit key: %s does not exist within mount: %s", name, mount)
+		}
+
+		return nil, fmt.Errorf("could not read transit key: %s. Error: %v", name, err)
+	}
+
+	// versions are plain numbers, which seconds converts as well
+	return &TransitKey{
+		Name:                 name,
+		Type:                 toString(res.Data["type"]),
+		LatestVersion:        seconds(res.Data["latest_version"]),
+		MinDecryptionVersion: seconds(res.Data["min_decryption_version"]),
+		MinEncryptionVersion: seconds(res.Data["min_encryption_version"]),
+		AutoRotatePeriod:     seconds(res.Data["auto_rotate_period"]),
+	}, nil
+}
+
+// RotateTransitKey creates a new version of the transit key and returns the updated key
+func RotateTransitKey(a *app.State, mount, name string) (*TransitKey, error) {
+	p := path.Join(mount, "keys", name, "rotate")
+	if _, err := a.VaultClient.Write(context.Background(), p, map[string]interface{}{}); err != nil {
+		return nil, fmt.Errorf("could not rotate transit key: %s. Error: %v", name, err)
+	}
+
+	return ReadTransitKey(a, mount, name)
+}
+
+// ConfigureTransitKey updates the minimum versions and the auto-rotate period of the transit key
+func ConfigureTransitKey(a *app.State, mount, name string, cfg TransitKeyConfig) (*TransitKey, error) {
+	key, err := ReadTransitKey(a, mount, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]interface{})
+	if v := cfg.MinDecryptionVersion; v != nil {
+		if *v < 1 || *v > key.LatestVersion {
+			return nil, fmt.Errorf("min_decryption_version must be between 1 and the latest version: %d",
+				key.LatestVersion)
+		}
+
+		data["min_decryption_version"] = *v
+	}
+
+	if v := cfg.MinEncryptionVersion; v != nil {
+		if *v < 0 || *v > key.LatestVersion {
+			return nil, fmt.Errorf("min_encryption_version must be between 0 and the latest version: %d",
+				key.LatestVersion)
+		}
+
+		data["min_encryption_version"] = *v
+	}
+
+	if cfg.AutoRotatePeriod != "" {
+		data["auto_rotate_period"] = cfg.AutoRotatePeriod
+	}
+
+	if len(data) == 0 {
+		return key, nil
+	}
+
+	if _, err := a.VaultClient.Write(context.Background(), path.Join(mount, "keys", name, "config"), data); err != nil {
+		return nil, fmt.Errorf("could not configure transit key: %s. Error: %v", name, err)
+	}
+
+	return ReadTransitKey(a, mount, name)
+}
+
+// RenderTransitKeys writes the transit keys to w in the given format
+func RenderTransitKeys(w io.Writer, format string, keys []TransitKey) error {
+	return RenderOutput(w, format, keys, func(tw io.Writer) {
+		fmt.Fprintln(tw, "NAME\tTYPE\tLATEST\tMIN DECRYPTION\tMIN ENCRYPTION\tAUTO ROTATE")
+		for _, k := range keys {
+			rotate := "-"
+			if k.AutoRotatePeriod > 0 {
+				rotate = (time.Duration(k.AutoRotatePeriod) * time.Second).String()
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\n", k.Name, k.Type, k.LatestVersion, k.MinDecryptionVersion,
+				k.MinEncryptionVersion, rotate)
+		}
+	})
+}
+
+// CiphertextVersion returns the key version of a transit ciphertext or 0 if s is no ciphertext
+func CiphertextVersion(s string) int64 {
+	m := transitCiphertext.FindStringSubmatch(s)
+	if m == nil || m[0] != s {
+		return 0
+	}
+
+	v, _ := strconv.ParseInt(m[1], 10, 64)
+	return v
+}
+
+// RewrapCiphertexts rewraps the ciphertexts with the latest version of the transit key without
+// exposing their plaintexts. The results are returned in order.
+func RewrapCiphertexts(a *app.State, mount, name string, ciphertexts []string) ([]string, error) {
+	if len(ciphertexts) == 0 {
+		return nil, nil
+	}
+
+	batch := make([]map[string]interface{}, 0, len(ciphertexts))
+	for _, c := range ciphertexts {
+		batch = append(batch, map[string]interface{}{"ciphertext": c})
+	}
+
+	res, err := a.VaultClient.Write(context.Background(), path.Join(mount, "rewrap", name),
+		map[string]interface{}{"batch_input": batch})
+	if err != nil {
+		return nil, fmt.Errorf("could not rewrap ciphertexts with transit key: %s. Error: %v", name, err)
+	}
+
+	results, _ := res.Data["batch_results"].([]interface{})
+	if len(results) != len(ciphertexts) {
+		return nil, fmt.Errorf("vault returned %d rewrapped ciphertexts instead of %d", len(results),
+			len(ciphertexts))
+	}
+
+	out := make([]string, 0, len(results))
+	for i, r := range results {
+		m, _ := r.(map[string]interface{})
+		if e := toString(m["error"]); e != "" {
+			return nil, fmt.Errorf("could not rewrap ciphertext %d: %s", i, e)
+		}
+
+		out = append(out, toString(m["ciphertext"]))
+	}
+
+	return out, nil
+}
+
+// RewrapResult reports how many ciphertexts of a Secret or file were rewrapped
+type RewrapResult struct {
+	Target    string `json:"target" yaml:"target"`
+	Found     int    `json:"found" yaml:"found"`
+	Rewrapped int    `json:"rewrapped" yaml:"rewrapped"`
+}
+
+// RenderRewrapResults writes the results to w in the given format
+func RenderRewrapResults(w io.Writer, format string, results []RewrapResult) error {
+	return RenderOutput(w, format, results, func(tw io.Writer) {
+		fmt.Fprintln(tw, "TARGET\tCIPHERTEXTS\tREWRAPPED")
+		for _, r := range results {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", r.Target, r.Found, r.Rewrapped)
+		}
+	})
+}
+
+// RewrapSecrets rewraps the values of the Kubernetes Secrets which are transit ciphertexts of an older
+// version than the latest key version. With dryRun the outdated ciphertexts are only counted.
+func RewrapSecrets(a *app.State, mount string, key *TransitKey, secrets []corev1.Secret,
+	dryRun bool) ([]RewrapResult, error) {
+	var results []RewrapResult
+	for _, s := range secrets {
+		res := RewrapResult{Target: fmt.Sprintf("secret/%s/%s", s.Namespace, s.Name)}
+
+		var (
+			keys        []string
+			ciphertexts []string
+		)
+		for _, k := range sortedKeys(s.Data) {
+			v := CiphertextVersion(string(s.Data[k]))
+			if v == 0 {
+				continue
+			}
+
+			res.Found++
+			if v < key.LatestVersion {
+				keys = append(keys, k)
+				ciphertexts = append(ciphertexts, string(s.Data[k]))
+			}
+		}
+
+		if dryRun || len(ciphertexts) == 0 {
+			res.Rewrapped = len(ciphertexts)
+			results = append(results, res)
+			continue
+		}
+
+		rewrapped, err := RewrapCiphertexts(a, mount, key.Name, ciphertexts)
+		if err != nil {
+			return nil, fmt.Errorf("could not rewrap %s. Error: %v", res.Target, err)
+		}
+
+		for i, k := range keys {
+			s.Data[k] = []byte(rewrapped[i])
+		}
+
+		if err := a.Kube.UpdateSecret(s.Namespace, &s, metav1.UpdateOptions{}); err != nil {
+			return nil, fmt.Errorf("could not update Secret: %s/%s. Error: %v", s.Namespace, s.Name, err)
+		}
+
+		res.Rewrapped = len(rewrapped)
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// TransitSecrets resolves Secrets given as '<namespace>/<name>' and the Secrets matching the label
+// selector within namespace
+func TransitSecrets(a *app.State, names []string, namespace, selector string) ([]corev1.Secret, error) {
+	var secrets []corev1.Secret
+	for _, n := range names {
+		ns, name, ok := strings.Cut(n, "/")
+		if !ok || ns == "" || name == "" {
+			return nil, fmt.Errorf("invalid Secret: %s. Must be of the form <namespace>/<name>", n)
+		}
+
+		s, err := a.Kube.Secret(ns, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not read Secret: %s. Error: %v", n, err)
+		}
+
+		secrets = append(secrets, *s)
+	}
+
+	if selector != "" {
+		s, err := a.Kube.Secrets(namespace, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, fmt.Errorf("could not list Secrets for label: %s. Error: %v", selector, err)
+		}
+
+		secrets = append(secrets, s...)
+	}
+
+	return secrets, nil
+}
+
+// RewrapFiles rewraps the transit ciphertexts embedded into the files, which are older than the latest
+// key version, in place. With dryRun the outdated ciphertexts are only counted.
+func RewrapFiles(a *app.State, mount string, key *TransitKey, files []string, dryRun bool) ([]RewrapResult, error) {
+	var results []RewrapResult
+	for _, f := range files {
+		res := RewrapResult{Target: "file/" + f}
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read file: %s. Error: %v", f, err)
+		}
+
+		var outdated []string
+		for _, c := range transitCiphertext.FindAllString(string(raw), -1) {
+			res.Found++
+			if CiphertextVersion(c) < key.LatestVersion {
+				outdated = append(outdated, c)
+			}
+		}
+
+		if dryRun || len(outdated) == 0 {
+			res.Rewrapped = len(outdated)
+			results = append(results, res)
+			continue
+		}
+
+		rewrapped, err := RewrapCiphertexts(a, mount, key.Name, outdated)
+		if err != nil {
+			return nil, fmt.Errorf("could not rewrap %s. Error: %v", res.Target, err)
+		}
+
+		replacements := make(map[string]string, len(outdated))
+		for i, c := range outdated {
+			replacements[c] = rewrapped[i]
+		}
+
+		out := transitCiphertext.ReplaceAllStringFunc(string(raw), func(c string) string {
+			if r, ok := replacements[c]; ok {
+				return r
+			}
+
+			return c
+		})
+
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot stat file: %s. Error: %v", f, err)
+		}
+
+		if err := os.WriteFile(f, []byte(out), info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("cannot write file: %s. Error: %v", f, err)
+		}
+
+		res.Rewrapped = len(outdated)
+		results = append(results, res)
+	}
+
+	return results, nil
+}
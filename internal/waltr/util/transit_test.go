@@ -0,0 +1,29 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCiphertextVersion(t *testing.T) {
+	assert.Equal(t, int64(3), CiphertextVersion("vault:v3:AbC+/d=="))
+	assert.Equal(t, int64(12), CiphertextVersion("vault:v12:Zm9v"))
+	assert.Zero(t, CiphertextVersion("plaintext"))
+	assert.Zero(t, CiphertextVersion("prefix vault:v1:Zm9v"))
+}
+
+func TestTransitCiphertextInFiles(t *testing.T) {
+	raw := "db:\n  password: vault:v1:Zm9vYmFy==\n  user: \"vault:v2:YmFy\"\n"
+	assert.Equal(t, []string{"vault:v1:Zm9vYmFy==", "vault:v2:YmFy"}, transitCiphertext.FindAllString(raw, -1))
+}
+
+func TestRenderTransitKeys(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, RenderTransitKeys(&buf, OutputTable, []TransitKey{
+		{Name: VSOTransitKey, Type: "aes256-gcm96", LatestVersion: 3, MinDecryptionVersion: 2, AutoRotatePeriod: 86400},
+	}))
+	assert.Contains(t, buf.String(), "24h0m0s")
+	assert.Contains(t, buf.String(), VSOTransitKey)
+}
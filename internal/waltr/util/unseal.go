@@ -0,0 +1,167 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/kube"
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultUnsealTimeout is the time a single Pod may take to start, serve its API and become unsealed
+	DefaultUnsealTimeout = 5 * time.Minute
+
+	// pollInterval is the interval in which Pod and Vault status are re-checked
+	pollInterval = 2 * time.Second
+)
+
+// UnsealResult is the outcome of unsealing a single Vault Pod
+type UnsealResult struct {
+	// Pod is the name of the Vault Pod
+	Pod string
+
+	// AlreadyUnsealed is set if the Pod did not need any unseal keys
+	AlreadyUnsealed bool
+
+	// Err is the reason unsealing the Pod failed, if it did
+	Err error
+}
+
+// UnsealPods unseals the given Vault Pods concurrently. Every Pod is port-forwarded on its own local
+// port, so that a failure or a slow start of one Pod doesn't affect the others.
+func UnsealPods(a *app.State, pods []corev1.Pod, keys []string, timeout time.Duration) []UnsealResult {
+	results := make([]UnsealResult, len(pods))
+
+	var wg sync.WaitGroup
+	for i, p := range pods {
+		wg.Add(1)
+		go func(i int, p corev1.Pod) {
+			defer wg.Done()
+
+			already, err := UnsealPod(context.Background(), a, p, keys, timeout)
+			results[i] = UnsealResult{Pod: p.Name, AlreadyUnsealed: already, Err: err}
+		}(i, p)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// UnsealPod port-forwards a single Vault Pod, waits until its API responds and submits unseal keys
+// until Vault reports it as unsealed. It returns true if the Pod was unsealed already.
+func UnsealPod(ctx context.Context, a *app.State, pod corev1.Pod, keys []string, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	vc, err := ForwardPod(ctx, a, pod)
+	if err != nil {
+		return false, err
+	}
+
+	status, err := waitForSealStatus(ctx, vc)
+	if err != nil {
+		return false, fmt.Errorf("the Vault API of Pod: %s did not become available: %v", pod.Name, err)
+	}
+
+	if !status.Initialized {
+		return false, fmt.Errorf("cannot unseal uninitialized Vault Pod: %s", pod.Name)
+	}
+
+	if !status.Sealed {
+		a.Log.Infof("Vault Pod: %s is already unsealed", pod.Name)
+		return true, nil
+	}
+
+	for i := 0; status.Sealed; i++ {
+		if i >= len(keys) {
+			return false, fmt.Errorf("ran out of unseal keys for Vault Pod: %s at progress %d/%d",
+				pod.Name, status.Progress, status.T)
+		}
+
+		res, err := vc.System.Unseal(ctx, schema.UnsealRequest{
+			Key: keys[i],
+		})
+		if err != nil {
+			return false, fmt.Errorf("could not unseal Vault Pod: %s. Error: %v", pod.Name, err)
+		}
+
+		status = sealStatus(res.Data)
+		if status.Sealed {
+			a.Log.Infof("Vault Pod: %s unseal progress %d/%d", pod.Name, status.Progress, status.T)
+		}
+	}
+
+	a.Log.Infof("successfully unsealed Vault Pod: %s", pod.Name)
+	return false, nil
+}
+
+// ForwardPod waits until the given Pod is running, port-forwards it on a free local port and returns
+// a Vault client for that port. The port-forward is shut down once ctx is done.
+func ForwardPod(ctx context.Context, a *app.State, pod corev1.Pod) (*vault.Client, error) {
+	running, err := WaitForRunning(ctx, a, pod)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := kube.FreeLocalPort()
+	if err != nil {
+		return nil, fmt.Errorf("could not find a free local port for Pod: %s. Error: %v", pod.Name, err)
+	}
+
+	ready := make(chan struct{})
+	failed := make(chan error, 1)
+	go func() {
+		failed <- a.Kube.PortForward(ctx, *running, kube.WithLocalPort(port), kube.WithReadyChannel(ready))
+	}()
+
+	select {
+	case <-ready:
+		a.Log.Debugf("port-forwarding Vault Pod: %s on local port %s", pod.Name, port)
+	case err := <-failed:
+		return nil, fmt.Errorf("could not port-forward Vault Pod: %s. Error: %v", pod.Name, err)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timed out port-forwarding Vault Pod: %s", pod.Name)
+	}
+
+	vc, err := a.VaultClientFor(fmt.Sprintf("https://127.0.0.1:%s", port), ServiceHost(*running))
+	if err != nil {
+		return nil, fmt.Errorf("could not create vault client for Pod: %s. Error: %v", pod.Name, err)
+	}
+
+	return vc, nil
+}
+
+// waitForSealStatus polls the seal status until the Vault API responds or ctx is done
+func waitForSealStatus(ctx context.Context, vc *vault.Client) (schema.SealStatusResponse, error) {
+	for {
+		res, err := vc.System.SealStatus(ctx)
+		if err == nil {
+			return res.Data, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return schema.SealStatusResponse{}, err
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// sealStatus converts the response of an unseal request to the seal status it contains
+func sealStatus(res schema.UnsealResponse) schema.SealStatusResponse {
+	return schema.SealStatusResponse{
+		Initialized: res.Initialized,
+		N:           res.N,
+		Progress:    res.Progress,
+		Sealed:      res.Sealed,
+		T:           res.T,
+		Type:        res.Type,
+		Version:     res.Version,
+	}
+}
@@ -4,73 +4,174 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"path/filepath"
+	"os"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/fmjstudios/gopskit/internal/waltr/app"
 	"github.com/fmjstudios/gopskit/pkg/core"
-	fs "github.com/fmjstudios/gopskit/pkg/fsi"
-	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/fmjstudios/gopskit/pkg/kube"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type VaultConfig struct {
-	DisableMLock bool        `hcl:"disable_mlock"`
-	UI           bool        `hcl:"ui"`
-	Seal         *SealConfig `hcl:"seal,block"`
-	Remain       hcl.Body    `hcl:",remain"`
+	DisableMLock bool             `hcl:"disable_mlock,optional"`
+	UI           bool             `hcl:"ui,optional"`
+	Seals        []SealConfig     `hcl:"seal,block"`
+	Listeners    []ListenerConfig `hcl:"listener,block"`
+	Remain       hcl.Body         `hcl:",remain"`
 }
 
+// SealConfig is a 'seal' stanza of the Vault configuration. Seal migrations keep the previous seal as
+// disabled stanza.
 type SealConfig struct {
-	Type   string   `hcl:"type,label"`
-	Remain hcl.Body `hcl:",remain"`
+	Type     string   `hcl:"type,label"`
+	Disabled string   `hcl:"disabled,optional"`
+	Remain   hcl.Body `hcl:",remain"`
 }
 
-// Credentials is a custom type which is used to write and load Vault credentials to and from a file
+// IsDisabled reports whether the seal is only configured to migrate away from it
+func (s SealConfig) IsDisabled() bool {
+	disabled, _ := strconv.ParseBool(strings.Trim(s.Disabled, `"`))
+	return disabled
+}
+
+// ActiveSeal returns the seal stanza which isn't disabled or nil if Vault uses the default Shamir seal
+func (c VaultConfig) ActiveSeal() *SealConfig {
+	for i := range c.Seals {
+		if !c.Seals[i].IsDisabled() {
+			return &c.Seals[i]
+		}
+	}
+
+	return nil
+}
+
+// ListenerConfig is a 'listener' stanza of the Vault configuration. TLSDisable is kept as string since
+// the official chart sets it as number.
+type ListenerConfig struct {
+	Type          string   `hcl:"type,label"`
+	Address       string   `hcl:"address,optional"`
+	TLSDisable    string   `hcl:"tls_disable,optional"`
+	TLSCertFile   string   `hcl:"tls_cert_file,optional"`
+	TLSMinVersion string   `hcl:"tls_min_version,optional"`
+	Remain        hcl.Body `hcl:",remain"`
+}
+
+// TLSDisabled reports whether the listener serves plain HTTP
+func (l ListenerConfig) TLSDisabled() bool {
+	disabled, _ := strconv.ParseBool(strings.Trim(l.TLSDisable, `"`))
+	return disabled
+}
+
+// ParseVaultConfig decodes the HCL configuration of a Vault server
+func ParseVaultConfig(raw, filename string) (*VaultConfig, error) {
+	f, diags := hclparse.NewParser().ParseHCL([]byte(raw), filename)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("cannot parse Vault HCL configuration. Error: %v", diags)
+	}
+
+	var cfg VaultConfig
+	if diags := gohcl.DecodeBody(f.Body, nil, &cfg); diags.HasErrors() {
+		return nil, fmt.Errorf("invalid Vault configuration. Error: %v", diags)
+	}
+
+	return &cfg, nil
+}
+
+// VaultConfigs reads and decodes the HCL configuration files of the ConfigMaps mounted as 'config'
+// volume into the Vault Pods, like the official chart does
+// ref: https://github.com/hashicorp/vault-helm/blob/main/templates/_helpers.tpl#L187
+func VaultConfigs(a *app.State, pods []corev1.Pod) (map[string]*VaultConfig, error) {
+	configs := make(map[string]*VaultConfig)
+	seen := make(map[string]bool)
+	for _, p := range pods {
+		for _, vol := range p.Spec.Volumes {
+			if vol.Name != "config" || vol.ConfigMap == nil {
+				continue
+			}
+
+			name := vol.ConfigMap.Name
+			if seen[path.Join(p.Namespace, name)] {
+				continue
+			}
+			seen[path.Join(p.Namespace, name)] = true
+
+			cm, err := a.Kube.ConfigMap(p.Namespace, name, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("cannot read Vault configuration ConfigMap: %s. Error: %v", name, err)
+			}
+
+			for key, val := range cm.Data {
+				if !strings.HasSuffix(key, ".hcl") {
+					continue
+				}
+
+				cfg, err := ParseVaultConfig(val, key)
+				if err != nil {
+					return nil, fmt.Errorf("ConfigMap: %s key: %s: %v", name, key, err)
+				}
+
+				configs[path.Join(p.Namespace, name, key)] = cfg
+			}
+		}
+	}
+
+	return configs, nil
+}
+
+// Credentials is a custom type which is used to write and load Vault credentials to and from the credential store
 type Credentials struct {
 	Keys       []string `json:"keys"`
 	KeysBase64 []string `json:"keys_base64"`
 	Token      string   `json:"token"`
 }
 
-// CredentialPath builds the filesystem path to write the credentials to after we unseal the Vault,
-// since it most likely is required for later commands. This function make the path deterministic
-// per execution env.Environment.
-func CredentialPath(a *app.State, env core.Environment) string {
-	return filepath.Join(a.Paths.Cache, env.String(), "vault-credentials.json")
+// CredentialKey is the key the Vault Credentials are stored under within the app's credential store
+// for the given env.Environment
+func CredentialKey(env core.Environment) string {
+	return path.Join(env.String(), "vault-credentials.json")
 }
 
-// WriteCredentials writes the Vault Credentials to the CredentialPath for the given env.Environment
+// WriteCredentials writes the Vault Credentials to the app's credential store for the given env.Environment
 func WriteCredentials(a *app.State, env core.Environment, credentials *Credentials) error {
-	p := CredentialPath(a, env)
-	jsn, err := json.MarshalIndent(credentials, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return fs.Write(p, jsn)
+	a.MigrateCredentials(CredentialKey(env))
+	return a.Credentials.Write(CredentialKey(env), credentials)
 }
 
-// ReadCredentials reads the Vault Credentials from the CredentialPath for the given env.Environment
+// ReadCredentials reads the Vault Credentials from the app's credential store for the given env.Environment
 func ReadCredentials(a *app.State, env core.Environment) (*Credentials, error) {
-	p := CredentialPath(a, env)
-	raw, err := fs.Read(p)
-	if err != nil {
-		return nil, err
-	}
+	a.MigrateCredentials(CredentialKey(env))
 
 	var credentials Credentials
-	err = json.Unmarshal(raw, &credentials)
-	if err != nil {
+	if err := a.Credentials.Read(CredentialKey(env), &credentials); err != nil {
 		return nil, err
 	}
 
 	return &credentials, nil
 }
 
+// EmitCredentials prints credentials which could not be persisted as JSON to stdout, so that they aren't
+// lost once waltr exits, and returns the error which prevented persisting them
+func EmitCredentials(a *app.State, credentials *Credentials, cause error) error {
+	a.Log.Errorf("could not persist Vault credentials, which cannot be recovered from Vault. Store the "+
+		"credentials printed below securely. Error: %v", cause)
+
+	raw, err := json.MarshalIndent(credentials, "", "  ")
+	if err != nil {
+		raw = []byte(fmt.Sprintf("%+v", *credentials))
+	}
+
+	fmt.Fprintln(os.Stdout, string(raw))
+	return cause
+}
+
 // AuthMethods retrieves the list of enabled authentication methods from the current Vault instance
 func AuthMethods(a *app.State) ([]string, error) {
 	// get current methods
@@ -145,7 +246,7 @@ func KubernetesAuthRoles(a *app.State) ([]string, error) {
 	return k.Data.Keys, nil
 }
 
-// GeneratePasswordFromPolicy ...
+// GeneratePasswordFromPolicy generates a password from the given password policy within Vault
 func GeneratePasswordFromPolicy(a *app.State, policy string) (string, error) {
 	pass, err := a.VaultClient.System.PoliciesGeneratePasswordFromPasswordPolicy(
 		context.Background(),
@@ -157,6 +258,17 @@ func GeneratePasswordFromPolicy(a *app.State, policy string) (string, error) {
 	return pass.Data.Password, nil
 }
 
+// decodeResponse converts the generically decoded data of a Vault response into v. Many 'sys/' endpoints
+// are either missing from or mistyped within the generated schema, so they're read with the raw client.
+func decodeResponse(data map[string]interface{}, v interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
 // Pods returns a list of Kubernetes' Pods matching the default (or custom) Vault label
 func Pods(a *app.State, namespace, label string) ([]corev1.Pod, error) {
 	if label == "" {
@@ -176,72 +288,100 @@ func Pods(a *app.State, namespace, label string) ([]corev1.Pod, error) {
 	return pods, nil
 }
 
-func LeaderPod(a *app.State, pods []corev1.Pod, namespace, label string) (*corev1.Pod, error) {
-	var leader *corev1.Pod
-	var activePods []corev1.Pod
-	var err error
-
-	if len(pods) > 1 {
-		label = fmt.Sprintf("%s,%s", label, "vault-active=true")
-		activePods, err = a.Kube.Pods(namespace, metav1.ListOptions{
-			LabelSelector: label,
-		})
+// ServiceAddress derives the in-cluster address of Vault from the discovered Vault Pods. Vault's Service
+// is named like the Helm release, which labels the Pods as instance.
+func ServiceAddress(a *app.State, namespace, label string) (string, error) {
+	pods, err := Pods(a, namespace, label)
+	if err != nil {
+		return "", fmt.Errorf("could not retrieve Vault pods for label: %s. Error: %v", label, err)
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	if len(pods) == 0 {
+		return "", fmt.Errorf("could not find any Vault pods for label: %s", label)
 	}
 
-	// matched more than one
-	if len(activePods) > 1 {
-		return nil, fmt.Errorf("could not determine Vault leader pod. "+
-			"Invalid configuration: Kubernetes label %s matched more than one pod", label)
+	ns, err := kube.ResolveNamespace(pods, namespace)
+	if err != nil {
+		return "", err
 	}
 
-	// no pod is labeled with "vault-active=true"
-	if len(activePods) == 0 {
-		for _, pod := range pods {
-			if strings.Contains(pod.Name, "0") {
-				leader = &pod
-			}
-		}
+	return fmt.Sprintf("https://%s.%s.svc:8200", serviceName(pods[0]), ns), nil
+}
 
-		if leader == nil {
-			return nil, fmt.Errorf("could not determine Vault leader pod. Unfamiliar naming scheme. " +
-				"None of your Vault Pod names contain a zero")
-		}
+// ServiceHost returns the in-cluster DNS name of the Vault Service selecting the Pod, which Vault's TLS
+// certificate is usually issued for
+func ServiceHost(pod corev1.Pod) string {
+	return fmt.Sprintf("%s.%s.svc", serviceName(pod), pod.Namespace)
+}
 
-		return leader, nil
+// serviceName returns the name of the Vault Service, which is named like the Helm release labeling the
+// Pod as instance
+func serviceName(pod corev1.Pod) string {
+	if instance := pod.Labels["app.kubernetes.io/instance"]; instance != "" {
+		return instance
 	}
 
-	return &pods[0], nil
+	return "vault"
 }
 
-// EnsureNamespace ensures we only find and use Vault Pods within a single namespace
-func EnsureNamespace(pods []corev1.Pod) (string, error) {
-	var ns []string
-	for _, pod := range pods {
-		ns = append(ns, pod.Namespace)
-	}
+// leaderProbeTimeout is the time asking a single Vault Pod for its leadership may take
+const leaderProbeTimeout = 15 * time.Second
+
+// VaultLeader is the kube.LeaderStrategy for Vault. It prefers the Pod labeled by Vault's Kubernetes
+// service registration, then asks every Pod's 'sys/leader' endpoint and finally falls back to the
+// first Pod of the StatefulSet, which is the one to initialize.
+func VaultLeader(a *app.State) kube.LeaderStrategy {
+	return kube.FirstOf(
+		kube.LabelLeader("vault-active=true"),
+		kube.ProbeLeader(func(ctx context.Context, pod corev1.Pod) (bool, error) {
+			if !kube.PodRunning(&pod) {
+				return false, fmt.Errorf("pod is not running")
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, leaderProbeTimeout)
+			defer cancel()
+
+			vc, err := ForwardPod(ctx, a, pod)
+			if err != nil {
+				return false, err
+			}
+
+			res, err := vc.System.LeaderStatus(ctx)
+			if err != nil {
+				return false, err
+			}
 
-	rns := helpers.RemoveDuplicates(ns)
-	if len(rns) > 1 {
-		return "", fmt.Errorf("discovered Vault pods in multiple namespaces: %v! Please set the namespace option", rns)
+			return res.Data.IsSelf, nil
+		}),
+		kube.OrdinalLeader(0),
+	)
+}
+
+// LeaderPod determines the Vault leader among the given Pods with the VaultLeader strategy
+func LeaderPod(a *app.State, pods []corev1.Pod) (*corev1.Pod, error) {
+	leader, err := a.Kube.LeaderPod(context.Background(), pods, VaultLeader(a))
+	if err != nil {
+		return nil, fmt.Errorf("could not determine Vault leader pod: %v", err)
 	}
 
-	return ns[0], nil
+	a.Log.Debugf("determined Vault leader Pod: %s", leader.Name)
+	return leader, nil
 }
 
-func WaitUntilRunning(a *app.State, pod corev1.Pod) {
-	for {
-		if pod.Status.Phase != corev1.PodRunning {
-			a.Log.Infof("main vault Pod: %s is not running yet - waiting for Pod to start", pod.Name)
-			time.Sleep(2500 * time.Millisecond)
-		} else {
-			a.Log.Infof("main vault Pod: %s is running", pod.Name)
-			break
-		}
+// WaitForRunning waits until the Vault Pod is running and returns its latest state
+func WaitForRunning(ctx context.Context, a *app.State, pod corev1.Pod) (*corev1.Pod, error) {
+	if kube.PodRunning(&pod) {
+		return &pod, nil
 	}
+
+	a.Log.Infof("Vault Pod: %s is not running yet - waiting for Pod to start", pod.Name)
+	running, err := a.Kube.WaitForPod(ctx, pod.Namespace, pod.Name, kube.PodRunning)
+	if err != nil {
+		return nil, fmt.Errorf("vault Pod: %s did not start: %v", pod.Name, err)
+	}
+
+	a.Log.Infof("Vault Pod: %s is running", pod.Name)
+	return running, nil
 }
 
 // Policies
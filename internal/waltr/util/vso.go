@@ -0,0 +1,251 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/fmjstudios/gopskit/internal/waltr/app"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/hashicorp/vault-client-go"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// VSOGroup is the API group of the Vault Secrets Operator's custom resources
+	VSOGroup = "secrets.hashicorp.com"
+
+	// VSOVersion is the API version of the Vault Secrets Operator's custom resources
+	VSOVersion = "v1beta1"
+
+	// DefaultVSONamespace is the namespace the Vault Secrets Operator is installed to
+	DefaultVSONamespace = "vault-secrets-operator"
+
+	// VSOAuthRole is the Kubernetes Auth role and ACL policy 'waltr transit' creates for the operator
+	VSOAuthRole = "vso-auth"
+
+	// VSOTransitKey is the transit key 'waltr transit' creates to encrypt the operator's client cache
+	VSOTransitKey = "vso-client-cache"
+
+	// vsoResourceName is the name of the shared VaultConnection and VaultAuth
+	vsoResourceName = "default"
+)
+
+// VSOOptions configure the manifests generated by VSOManifests
+type VSOOptions struct {
+	// Namespace is the namespace of the operator, which holds the shared VaultConnection and VaultAuth
+	Namespace string
+
+	// Address is the in-cluster address of Vault
+	Address string
+
+	// CACertSecret is the name of a Secret within Namespace holding Vault's CA certificate as 'ca.crt'
+	CACertSecret string
+
+	// SkipTLSVerify disables the verification of Vault's certificate
+	SkipTLSVerify bool
+
+	// AuthMount is the mount path of the Kubernetes authentication method
+	AuthMount string
+
+	// Role is the Kubernetes Auth role the operator authenticates with
+	Role string
+
+	// ServiceAccount is the Service Account bound to Role. The operator requests its tokens within the
+	// namespace of every VaultStaticSecret, so it's created within every release namespace.
+	ServiceAccount string
+
+	// Audience is the audience of the Service Account tokens bound to Role
+	Audience string
+
+	// KVMount is the mount path of the KV-v2 secrets engine
+	KVMount string
+
+	// TransitMount is the mount path of the transit secrets engine
+	TransitMount string
+
+	// TransitKey is the transit key encrypting the operator's client cache
+	TransitKey string
+
+	// RefreshAfter is the interval in which static secrets are synced
+	RefreshAfter string
+
+	// Namespaces maps releases to the namespaces their secrets are synced to. Releases without a
+	// mapping use a namespace with their own name.
+	Namespaces map[string]string
+}
+
+// DefaultVSOOptions returns the VSOOptions matching the configuration 'waltr transit' creates
+func DefaultVSOOptions() VSOOptions {
+	return VSOOptions{
+		Namespace:      DefaultVSONamespace,
+		AuthMount:      DefaultKubernetesMount,
+		Role:           VSOAuthRole,
+		ServiceAccount: "vault-secrets-operator",
+		Audience:       "vault",
+		KVMount:        DefaultKVMount,
+		TransitMount:   "transit",
+		TransitKey:     VSOTransitKey,
+		RefreshAfter:   "1h",
+	}
+}
+
+// ReleaseNamespace returns the namespace the secrets of a release are synced to
+func (o VSOOptions) ReleaseNamespace(release string) string {
+	if ns, ok := o.Namespaces[release]; ok && ns != "" {
+		return ns
+	}
+
+	return release
+}
+
+// ReleaseNamespaces returns the sorted namespaces the secrets of the given releases are synced to
+func (o VSOOptions) ReleaseNamespaces(releases []string) []string {
+	namespaces := make([]string, 0, len(releases))
+	for _, r := range releases {
+		namespaces = append(namespaces, o.ReleaseNamespace(r))
+	}
+
+	sort.Strings(namespaces)
+	return removeEmpty(namespaces)
+}
+
+// KubernetesRole returns the Kubernetes Auth role the operator authenticates with. It binds the
+// ServiceAccount within the operator's namespace and the namespaces of all given releases, since the
+// operator authenticates with the Service Account of the namespace a VaultStaticSecret resides in.
+func (o VSOOptions) KubernetesRole(releases []string) KubernetesRoleSpec {
+	return KubernetesRoleSpec{
+		Name:            o.Role,
+		Mount:           o.AuthMount,
+		ServiceAccounts: []string{o.ServiceAccount},
+		Namespaces:      removeEmpty(append([]string{o.Namespace}, o.ReleaseNamespaces(releases)...)),
+		Audience:        o.Audience,
+		Policies:        []string{o.Role},
+		ReleasePolicies: true,
+		TokenPeriod:     "120",
+		TokenTTL:        "0",
+	}
+}
+
+// VSOUnboundNamespaces returns the namespaces which the live Kubernetes Auth role of the operator
+// doesn't bind, i.e. in which VaultStaticSecrets would fail to authenticate
+func VSOUnboundNamespaces(a *app.State, opts VSOOptions, namespaces []string) ([]string, error) {
+	role, err := a.VaultClient.Auth.KubernetesReadAuthRole(context.Background(), opts.Role,
+		vault.WithMountPath(opts.AuthMount))
+	if err != nil {
+		return nil, fmt.Errorf("could not read Kubernetes Auth role %s: %v", opts.Role, err)
+	}
+
+	bound := toStrings(role.Data["bound_service_account_namespaces"])
+	if helpers.SliceContains(bound, "*") {
+		return nil, nil
+	}
+
+	var unbound []string
+	for _, ns := range namespaces {
+		if !helpers.SliceContains(bound, ns) {
+			unbound = append(unbound, ns)
+		}
+	}
+
+	return unbound, nil
+}
+
+// VSOReleasePaths lists the KV paths of every release below '<release>/' within the KV-v2 mount
+func VSOReleasePaths(a *app.State, mount string, releases []string) (map[string][]string, error) {
+	paths := make(map[string][]string, len(releases))
+	for _, r := range releases {
+		p, err := ListKV(a, mount, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(p) == 0 {
+			a.Log.Warnf("found no KV secrets for release: %s below %s/%s/", r, mount, r)
+		}
+
+		paths[r] = p
+	}
+
+	return paths, nil
+}
+
+// VSOManifests builds a shared VaultConnection and VaultAuth within the operator's namespace and a
+// VaultStaticSecret for every KV path of every release. The VaultAuth allows the namespaces of all
+// releases to reference it. Since the operator authenticates with the Service Account of the namespace
+// of each VaultStaticSecret, the ServiceAccount is created within every release namespace.
+func VSOManifests(opts VSOOptions, paths map[string][]string) []*unstructured.Unstructured {
+	releases := sortedKeys(paths)
+
+	connection := map[string]interface{}{
+		"address":       opts.Address,
+		"skipTLSVerify": opts.SkipTLSVerify,
+	}
+	if opts.CACertSecret != "" {
+		connection["caCertSecretRef"] = opts.CACertSecret
+	}
+
+	namespaces := opts.ReleaseNamespaces(releases)
+	allowed := make([]interface{}, 0, len(namespaces))
+	for _, ns := range namespaces {
+		allowed = append(allowed, ns)
+	}
+
+	auth := map[string]interface{}{
+		"vaultConnectionRef": vsoResourceName,
+		"method":             "kubernetes",
+		"mount":              opts.AuthMount,
+		"kubernetes": map[string]interface{}{
+			"role":           opts.Role,
+			"serviceAccount": opts.ServiceAccount,
+			"audiences":      []interface{}{opts.Audience},
+		},
+		"allowedNamespaces": allowed,
+	}
+	if opts.TransitKey != "" {
+		auth["storageEncryption"] = map[string]interface{}{
+			"mount":   opts.TransitMount,
+			"keyName": opts.TransitKey,
+		}
+	}
+
+	objs := []*unstructured.Unstructured{
+		vsoObject("VaultConnection", vsoResourceName, opts.Namespace, connection),
+		vsoObject("VaultAuth", vsoResourceName, opts.Namespace, auth),
+	}
+
+	// the operator's own Service Account is managed by its Helm release
+	for _, ns := range namespaces {
+		if ns != opts.Namespace {
+			objs = append(objs, manifestObject("v1", "ServiceAccount", opts.ServiceAccount, ns, nil))
+		}
+	}
+
+	for _, r := range releases {
+		for _, p := range paths[r] {
+			name := manifestName(p)
+			objs = append(objs, vsoObject("VaultStaticSecret", name, opts.ReleaseNamespace(r),
+				map[string]interface{}{
+					"vaultAuthRef": path.Join(opts.Namespace, vsoResourceName),
+					"mount":        opts.KVMount,
+					"type":         "kv-v2",
+					"path":         p,
+					"refreshAfter": opts.RefreshAfter,
+					"destination": map[string]interface{}{
+						"name":   name,
+						"create": true,
+					},
+				}))
+		}
+	}
+
+	return objs
+}
+
+// vsoObject builds a custom resource of the Vault Secrets Operator
+func vsoObject(kind, name, namespace string, spec map[string]interface{}) *unstructured.Unstructured {
+	return manifestObject(VSOGroup+"/"+VSOVersion, kind, name, namespace, map[string]interface{}{
+		"spec": spec,
+	})
+}
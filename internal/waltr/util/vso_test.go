@@ -0,0 +1,66 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVSOManifests(t *testing.T) {
+	opts := DefaultVSOOptions()
+	opts.Address = "https://vault.vault.svc:8200"
+	opts.Namespaces = map[string]string{"gitlab-runner": "gitlab"}
+
+	objs := VSOManifests(opts, map[string][]string{
+		"gitlab":        {"gitlab/postgresql", "gitlab/values"},
+		"gitlab-runner": {"gitlab-runner/Registration_Token"},
+	})
+	assert.Len(t, objs, 6)
+
+	assert.Equal(t, "VaultConnection", objs[0].GetKind())
+	assert.Equal(t, DefaultVSONamespace, objs[0].GetNamespace())
+
+	auth := objs[1].Object["spec"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"gitlab"}, auth["allowedNamespaces"])
+	assert.Equal(t, VSOTransitKey, auth["storageEncryption"].(map[string]interface{})["keyName"])
+
+	// the operator authenticates with the Service Account of each VaultStaticSecret's namespace
+	sa := objs[2]
+	assert.Equal(t, "ServiceAccount", sa.GetKind())
+	assert.Equal(t, opts.ServiceAccount, sa.GetName())
+	assert.Equal(t, "gitlab", sa.GetNamespace())
+	assert.Equal(t, opts.ServiceAccount, auth["kubernetes"].(map[string]interface{})["serviceAccount"])
+
+	role := opts.KubernetesRole([]string{"gitlab", "gitlab-runner"})
+	assert.Equal(t, opts.Role, auth["kubernetes"].(map[string]interface{})["role"])
+	assert.Equal(t, []string{opts.ServiceAccount}, role.ServiceAccounts)
+	assert.Equal(t, []string{DefaultVSONamespace, "gitlab"}, role.Namespaces)
+
+	secret := objs[5]
+	assert.Equal(t, "VaultStaticSecret", secret.GetKind())
+	assert.Equal(t, "gitlab-runner-registration-token", secret.GetName())
+	assert.Equal(t, "gitlab", secret.GetNamespace())
+	assert.Equal(t, "vault-secrets-operator/default", secret.Object["spec"].(map[string]interface{})["vaultAuthRef"])
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteManifests(&buf, objs))
+	assert.Equal(t, 5, bytes.Count(buf.Bytes(), []byte("---\n")))
+}
+
+func TestDefaultSpecVSORole(t *testing.T) {
+	spec := DefaultSpec()
+
+	// the built-in role binds the namespace of every built-in release
+	var role KubernetesRoleSpec
+	for _, r := range spec.KubernetesRoles {
+		if r.Name == VSOAuthRole {
+			role = r
+		}
+	}
+
+	assert.Contains(t, role.Namespaces, DefaultVSONamespace)
+	for _, r := range Releases {
+		assert.Contains(t, role.Namespaces, r)
+	}
+}
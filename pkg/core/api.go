@@ -1,6 +1,9 @@
 package core
 
 import (
+	"errors"
+
+	"github.com/fmjstudios/gopskit/pkg/credstore"
 	fs "github.com/fmjstudios/gopskit/pkg/fsi"
 	"github.com/fmjstudios/gopskit/pkg/kube"
 	"github.com/fmjstudios/gopskit/pkg/log"
@@ -35,8 +38,51 @@ type API struct {
 	// These values may or may not be made accessible via the CLIs via a subcommand.
 	// KV *kv.Database
 
+	// Credentials is the store for credentials obtained by the application, like Vault unseal keys or
+	// Keycloak admin logins. It may persist them encrypted and to various backends.
+	Credentials *credstore.Store
+
 	// Stamp is build-time information that is linked into the final executable
 	// by LD. Our Bazel builds stamps builds via LD using the 'x_defs' attribute
 	// on the 'go_library' rule
 	Stamp *stamp.Stamps
 }
+
+// ConfigureCredentials (re-)configures the API's credential store with the given credstore.Config and
+// warns if it would write plaintext credentials to the local filesystem
+func (a *API) ConfigureCredentials(cfg credstore.Config) error {
+	store, err := credstore.FromConfig(cfg, a.Name, a.Paths, a.Kube)
+	if err != nil {
+		return err
+	}
+
+	if store.Plaintext() {
+		a.Log.Warnf("credentials like root tokens and unseal keys are stored UNENCRYPTED in %s. Set %s to "+
+			"encrypt them or pass --credentials-unencrypted to store them in plaintext deliberately", store.Backend,
+			credstore.PassphraseEnv(a.Name))
+	}
+
+	a.Credentials = store
+	return nil
+}
+
+// MigrateCredentials moves plaintext credentials for key, which earlier releases wrote to the application's
+// cache directory, into the API's credential store. Failures are only logged, since they must not prevent
+// using the credentials.
+func (a *API) MigrateCredentials(key string) {
+	legacy := credstore.LegacyBackend(a.Paths)
+	migrated, err := a.Credentials.Migrate(legacy, key)
+	if errors.Is(err, credstore.ErrUnencrypted) {
+		a.Log.Warnf("%v. Set %s to encrypt them or pass --credentials-unencrypted to migrate them in plaintext",
+			err, credstore.PassphraseEnv(a.Name))
+		return
+	}
+	if err != nil {
+		a.Log.Warnf("%v", err)
+		return
+	}
+
+	if migrated {
+		a.Log.Infof("migrated plaintext credentials: %s to %s", legacy.Path(key), a.Credentials.Backend)
+	}
+}
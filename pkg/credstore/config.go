@@ -0,0 +1,96 @@
+package credstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/kube"
+)
+
+// Config selects the Backend of a Store and where it persists credentials
+type Config struct {
+	// Backend is the name of the Backend, one of Backends. Defaults to BackendFile.
+	Backend string
+
+	// Location is the Backend-specific location of the credentials:
+	//   - file: the directory to write files to, defaults to the application's cache directory
+	//   - kubernetes: the Secret as '<namespace>/<name>', required
+	//   - helm-secrets: the path of the Helm Secrets plugin-encrypted values file, required
+	//   - kv: the directory of the BadgerDB database, defaults to 'credentials' in the data directory
+	Location string
+
+	// Passphrase encrypts the credentials if set
+	Passphrase string
+
+	// Unencrypted explicitly allows storing credentials without a Passphrase
+	Unencrypted bool
+}
+
+// PassphraseEnv returns the environment variable the credential passphrase of an application is read from
+func PassphraseEnv(app string) string {
+	return fmt.Sprintf("%s_CREDENTIALS_PASSPHRASE", strings.ToUpper(app))
+}
+
+// DefaultConfig returns the Config of a file Store within the application's cache directory, encrypted
+// with the passphrase from PassphraseEnv if it is set
+func DefaultConfig(app string) Config {
+	return Config{
+		Backend:    BackendFile,
+		Passphrase: os.Getenv(PassphraseEnv(app)),
+	}
+}
+
+// LegacyBackend returns the FileBackend the gopskit CLIs wrote plaintext credentials to before they used
+// a Store, i.e. the application's cache directory
+func LegacyBackend(paths *fs.PlatformPaths) *FileBackend {
+	return &FileBackend{Dir: paths.Cache}
+}
+
+// FromConfig creates the Store described by cfg for the given application
+func FromConfig(cfg Config, app string, paths *fs.PlatformPaths, kc *kube.Client) (*Store, error) {
+	var backend Backend
+
+	switch cfg.Backend {
+	case BackendFile, "":
+		dir := cfg.Location
+		if dir == "" {
+			dir = paths.Cache
+		}
+		backend = &FileBackend{Dir: dir}
+	case BackendKubernetes:
+		// credentials must not end up in whatever namespace happens to be the default
+		namespace, name, _ := strings.Cut(cfg.Location, "/")
+		if namespace == "" || name == "" {
+			return nil, fmt.Errorf("the %s credential backend requires the Secret as '<namespace>/<name>'",
+				cfg.Backend)
+		}
+		backend = &KubernetesBackend{Client: kc, Namespace: namespace, Name: name}
+	case BackendHelmSecrets:
+		if cfg.Location == "" {
+			return nil, fmt.Errorf("the %s credential backend requires the path of a values file", cfg.Backend)
+		}
+		backend = &HelmSecretsBackend{Path: cfg.Location}
+	case BackendKV:
+		dir := cfg.Location
+		if dir == "" {
+			dir = filepath.Join(paths.Data, "credentials")
+		}
+		backend = &KVBackend{Path: dir, Namespace: app}
+	default:
+		return nil, fmt.Errorf("unsupported credential backend: %s. Must be one of: %v", cfg.Backend, Backends)
+	}
+
+	var opts []Opt
+	if cfg.Passphrase != "" {
+		opts = append(opts, WithPassphrase(cfg.Passphrase))
+	}
+
+	if cfg.Unencrypted {
+		opts = append(opts, WithUnencrypted())
+	}
+
+	return New(backend, opts...), nil
+}
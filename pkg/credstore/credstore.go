@@ -0,0 +1,217 @@
+// Package credstore implements a pluggable store for credentials obtained by the gopskit CLIs, like Vault
+// unseal keys and root tokens or Keycloak admin logins. Credentials are serialized to JSON and, if a
+// passphrase is configured, encrypted before they're handed to the Backend which persists them.
+package credstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+)
+
+const (
+	BackendFile        = "file"
+	BackendKubernetes  = "kubernetes"
+	BackendHelmSecrets = "helm-secrets"
+	BackendKV          = "kv"
+)
+
+var (
+	// ErrNotFound is returned if a Backend holds no data for a key
+	ErrNotFound = errors.New("no credentials stored")
+
+	// ErrUnencrypted is returned by Migrate if the Store would write the migrated credentials without
+	// encrypting them and unencrypted credentials weren't explicitly allowed
+	ErrUnencrypted = errors.New("the credential store doesn't encrypt credentials")
+)
+
+// Backends are the names of all supported Backend implementations
+var Backends = []string{BackendFile, BackendKubernetes, BackendHelmSecrets, BackendKV}
+
+// Backend persists raw credential data by key. Keys are slash-separated paths like 'dev/vault.json',
+// which Backend implementations may translate into their own naming scheme.
+type Backend interface {
+	// Read retrieves the data stored for key. It returns an error wrapping ErrNotFound if there is none.
+	Read(key string) ([]byte, error)
+
+	// Write persists data for key, replacing existing data
+	Write(key string, data []byte) error
+
+	// String describes the Backend and its location for log messages
+	String() string
+}
+
+// Opt is a configuration option for the Store
+type Opt func(s *Store)
+
+// Store reads and writes credentials through a Backend
+type Store struct {
+	// Backend is the Backend credentials are persisted with
+	Backend Backend
+
+	// passphrase is used to encrypt credentials before they're written. Encrypted credentials can only
+	// be read with the same passphrase.
+	passphrase string
+
+	// unencrypted explicitly allows writing credentials without a passphrase
+	unencrypted bool
+}
+
+// New creates a new Store writing to the given Backend and configures it with the given Opt
+// configuration options
+func New(backend Backend, opts ...Opt) *Store {
+	s := &Store{
+		Backend: backend,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+// WithPassphrase configures the Store to encrypt credentials with the given passphrase
+func WithPassphrase(passphrase string) Opt {
+	return func(s *Store) {
+		s.passphrase = passphrase
+	}
+}
+
+// WithUnencrypted explicitly allows the Store to write credentials without a passphrase, e.g. to a backend
+// which encrypts them itself
+func WithUnencrypted() Opt {
+	return func(s *Store) {
+		s.unencrypted = true
+	}
+}
+
+// Encrypted reports whether the Store encrypts the credentials it writes
+func (s *Store) Encrypted() bool {
+	return s.passphrase != ""
+}
+
+// Plaintext reports whether the Store writes credentials as plaintext to the local filesystem without
+// unencrypted credentials being explicitly allowed
+func (s *Store) Plaintext() bool {
+	if s.Encrypted() || s.unencrypted {
+		return false
+	}
+
+	switch s.Backend.(type) {
+	case *FileBackend, *KVBackend:
+		return true
+	default:
+		return false
+	}
+}
+
+// WithBackend returns a copy of the Store which writes to a different Backend with the same encryption
+func (s *Store) WithBackend(backend Backend) *Store {
+	return &Store{
+		Backend:     backend,
+		passphrase:  s.passphrase,
+		unencrypted: s.unencrypted,
+	}
+}
+
+// Write serializes v to JSON and persists it for key, encrypting it if the Store has a passphrase
+func (s *Store) Write(key string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if s.Encrypted() {
+		data, err = helpers.Encrypt(data, s.passphrase)
+		if err != nil {
+			return fmt.Errorf("could not encrypt credentials: %v", err)
+		}
+	}
+
+	if err := s.Backend.Write(key, data); err != nil {
+		return fmt.Errorf("could not write credentials to %s: %v", s.Backend, err)
+	}
+
+	return nil
+}
+
+// Read retrieves the credentials stored for key and deserializes them into v. Plaintext credentials are
+// read regardless of the passphrase, so existing credentials are encrypted on their next Write. If there are
+// no credentials for key, the error wraps ErrNotFound.
+func (s *Store) Read(key string, v interface{}) error {
+	data, err := s.Backend.Read(key)
+	if err != nil {
+		return fmt.Errorf("could not read credentials from %s: %w", s.Backend, err)
+	}
+
+	if helpers.IsEncrypted(data) {
+		if !s.Encrypted() {
+			return fmt.Errorf("credentials in %s are encrypted but no passphrase is configured", s.Backend)
+		}
+
+		data, err = helpers.Decrypt(data, s.passphrase)
+		if err != nil {
+			return fmt.Errorf("could not decrypt credentials: %v", err)
+		}
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// Migrate moves the plaintext credentials stored for key within legacy into the Store, encrypting them if
+// the Store has a passphrase, and removes the plaintext file afterwards. It reports whether credentials
+// were migrated. Credentials the Store already holds for key are never overwritten, instead an error
+// points to the superseded plaintext file. Stores without a passphrase refuse to migrate with
+// ErrUnencrypted, unless unencrypted credentials are explicitly allowed.
+func (s *Store) Migrate(legacy *FileBackend, key string) (bool, error) {
+	p := legacy.Path(key)
+	if !fs.CheckIfExists(p) {
+		return false, nil
+	}
+
+	data, err := legacy.Read(key)
+	if err != nil {
+		return false, fmt.Errorf("could not read plaintext credentials: %s. Error: %v", p, err)
+	}
+
+	if helpers.IsEncrypted(data) {
+		return false, nil
+	}
+
+	if !json.Valid(data) {
+		return false, fmt.Errorf("plaintext credentials: %s are not valid JSON", p)
+	}
+
+	// a Store writing to the legacy location only has to encrypt the credentials in place
+	if f, ok := s.Backend.(*FileBackend); ok && f.Path(key) == p {
+		if !s.Encrypted() {
+			return false, nil
+		}
+
+		return true, s.Write(key, json.RawMessage(data))
+	}
+
+	if !s.Encrypted() && !s.unencrypted {
+		return false, fmt.Errorf("%w: plaintext credentials: %s were not migrated to %s", ErrUnencrypted, p, s.Backend)
+	}
+
+	if _, err := s.Backend.Read(key); err == nil {
+		return false, fmt.Errorf("plaintext credentials: %s are superseded by the credentials in %s. Remove them "+
+			"once they're no longer required", p, s.Backend)
+	}
+
+	if err := s.Write(key, json.RawMessage(data)); err != nil {
+		return false, err
+	}
+
+	if err := os.Remove(p); err != nil {
+		return true, fmt.Errorf("could not remove migrated plaintext credentials: %s. Error: %v", p, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,121 @@
+package credstore
+
+import (
+	"testing"
+
+	"github.com/fmjstudios/gopskit/pkg/helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+type credentials struct {
+	Token string   `json:"token"`
+	Keys  []string `json:"keys"`
+}
+
+func TestStore(t *testing.T) {
+	backend := &FileBackend{Dir: t.TempDir()}
+	want := credentials{Token: "hvs.root", Keys: []string{"a", "b"}}
+
+	// plaintext credentials remain readable after enabling encryption
+	plain := New(backend)
+	assert.NoError(t, plain.Write("dev/vault.json", want))
+
+	encrypted := New(backend, WithPassphrase("secret"))
+	var got credentials
+	assert.NoError(t, encrypted.Read("dev/vault.json", &got))
+	assert.Equal(t, want, got)
+
+	assert.NoError(t, encrypted.Write("dev/vault.json", want))
+	raw, err := backend.Read("dev/vault.json")
+	assert.NoError(t, err)
+	assert.True(t, helpers.IsEncrypted(raw))
+
+	got = credentials{}
+	assert.NoError(t, encrypted.Read("dev/vault.json", &got))
+	assert.Equal(t, want, got)
+
+	// only missing credentials are reported as ErrNotFound
+	err = plain.Read("dev/vault.json", &got)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	err = New(backend, WithPassphrase("wrong")).Read("dev/vault.json", &got)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	assert.ErrorIs(t, encrypted.Read("prod/vault.json", &got), ErrNotFound)
+}
+
+func TestMigrate(t *testing.T) {
+	legacy := &FileBackend{Dir: t.TempDir()}
+	want := credentials{Token: "hvs.root", Keys: []string{"a", "b"}}
+	assert.NoError(t, New(legacy).Write("dev/vault.json", want))
+
+	// plaintext Stores at the legacy location have nothing to migrate
+	migrated, err := New(legacy).Migrate(legacy, "dev/vault.json")
+	assert.NoError(t, err)
+	assert.False(t, migrated)
+
+	// plaintext credentials are only moved to another plaintext store if explicitly allowed
+	migrated, err = New(&FileBackend{Dir: t.TempDir()}).Migrate(legacy, "dev/vault.json")
+	assert.ErrorIs(t, err, ErrUnencrypted)
+	assert.False(t, migrated)
+	assert.FileExists(t, legacy.Path("dev/vault.json"))
+
+	migrated, err = New(&FileBackend{Dir: t.TempDir()}, WithUnencrypted()).Migrate(legacy, "dev/vault.json")
+	assert.NoError(t, err)
+	assert.True(t, migrated)
+	assert.NoError(t, New(legacy).Write("dev/vault.json", want))
+
+	backend := &FileBackend{Dir: t.TempDir()}
+	store := New(backend, WithPassphrase("secret"))
+	migrated, err = store.Migrate(legacy, "dev/vault.json")
+	assert.NoError(t, err)
+	assert.True(t, migrated)
+	assert.NoFileExists(t, legacy.Path("dev/vault.json"))
+
+	raw, err := backend.Read("dev/vault.json")
+	assert.NoError(t, err)
+	assert.True(t, helpers.IsEncrypted(raw))
+
+	var got credentials
+	assert.NoError(t, store.Read("dev/vault.json", &got))
+	assert.Equal(t, want, got)
+
+	// existing credentials are never overwritten by stale plaintext credentials
+	assert.NoError(t, New(legacy).Write("dev/vault.json", credentials{Token: "hvs.stale"}))
+	migrated, err = store.Migrate(legacy, "dev/vault.json")
+	assert.Error(t, err)
+	assert.False(t, migrated)
+	assert.FileExists(t, legacy.Path("dev/vault.json"))
+
+	// encrypting in place
+	migrated, err = New(legacy, WithPassphrase("secret")).Migrate(legacy, "dev/vault.json")
+	assert.NoError(t, err)
+	assert.True(t, migrated)
+
+	raw, err = legacy.Read("dev/vault.json")
+	assert.NoError(t, err)
+	assert.True(t, helpers.IsEncrypted(raw))
+}
+
+func TestFromConfigKubernetes(t *testing.T) {
+	for _, location := range []string{"", "credentials", "/credentials", "vault/"} {
+		_, err := FromConfig(Config{Backend: BackendKubernetes, Location: location}, "waltr", nil, nil)
+		assert.Error(t, err, location)
+	}
+
+	store, err := FromConfig(Config{Backend: BackendKubernetes, Location: "vault/waltr-credentials"}, "waltr", nil,
+		nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Kubernetes Secret vault/waltr-credentials", store.Backend.String())
+}
+
+func TestPlaintext(t *testing.T) {
+	backend := &FileBackend{Dir: t.TempDir()}
+
+	assert.True(t, New(backend).Plaintext())
+	assert.False(t, New(backend, WithPassphrase("secret")).Plaintext())
+	assert.False(t, New(backend, WithUnencrypted()).Plaintext())
+	assert.False(t, New(&HelmSecretsBackend{Path: "secrets.yaml"}).Plaintext())
+}
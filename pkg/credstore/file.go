@@ -0,0 +1,40 @@
+package credstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+)
+
+// enforce implementation of the interface
+var _ Backend = (*FileBackend)(nil)
+
+// FileBackend stores every key as a file below Dir
+type FileBackend struct {
+	Dir string
+}
+
+// Path returns the filesystem path of key
+func (f *FileBackend) Path(key string) string {
+	return filepath.Join(f.Dir, filepath.FromSlash(key))
+}
+
+func (f *FileBackend) Read(key string) ([]byte, error) {
+	data, err := fs.Read(f.Path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+
+	return data, err
+}
+
+func (f *FileBackend) Write(key string, data []byte) error {
+	return fs.Write(f.Path(key), data)
+}
+
+func (f *FileBackend) String() string {
+	return fmt.Sprintf("directory %s", f.Dir)
+}
@@ -0,0 +1,55 @@
+package credstore
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	fs "github.com/fmjstudios/gopskit/pkg/fsi"
+	"github.com/fmjstudios/gopskit/pkg/tools"
+)
+
+// enforce implementation of the interface
+var _ Backend = (*HelmSecretsBackend)(nil)
+
+// HelmSecretsBackend stores every key base64-encoded below 'secrets.credentials' of a Helm Secrets
+// plugin-encrypted values file
+type HelmSecretsBackend struct {
+	Path string
+}
+
+func (h *HelmSecretsBackend) Read(key string) ([]byte, error) {
+	if !fs.CheckIfExists(h.Path) {
+		return nil, fmt.Errorf("%w: file %s does not exist", ErrNotFound, h.Path)
+	}
+
+	val, err := tools.GetSecretValue(h.Path, fmt.Sprintf("$.secrets.credentials['%s']", secretKey(key)), false)
+	if errors.Is(err, tools.ErrNoMatch) {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(val)
+}
+
+func (h *HelmSecretsBackend) Write(key string, data []byte) error {
+	if !fs.CheckIfExists(h.Path) {
+		if err := fs.Write(h.Path, []byte("secrets: {}\n")); err != nil {
+			return err
+		}
+	}
+
+	_, err := tools.AddSecretValue(h.Path, map[string]interface{}{
+		"credentials": map[string]interface{}{
+			secretKey(key): base64.StdEncoding.EncodeToString(data),
+		},
+	}, false)
+
+	return err
+}
+
+func (h *HelmSecretsBackend) String() string {
+	return fmt.Sprintf("Helm Secrets file %s", h.Path)
+}
@@ -0,0 +1,76 @@
+package credstore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fmjstudios/gopskit/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// enforce implementation of the interface
+var _ Backend = (*KubernetesBackend)(nil)
+
+// KubernetesBackend stores every key as an entry of a single Kubernetes Secret. Slashes within keys are
+// replaced with dots, since Secret keys may not contain them.
+type KubernetesBackend struct {
+	Client    *kube.Client
+	Namespace string
+	Name      string
+}
+
+func (k *KubernetesBackend) Read(key string) ([]byte, error) {
+	sec, err := k.Client.Secret(k.Namespace, k.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := sec.Data[secretKey(key)]
+	if !ok {
+		return nil, fmt.Errorf("%w: secret %s/%s has no key: %s", ErrNotFound, k.Namespace, k.Name,
+			secretKey(key))
+	}
+
+	return data, nil
+}
+
+func (k *KubernetesBackend) Write(key string, data []byte) error {
+	sec, err := k.Client.Secret(k.Namespace, k.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return k.Client.CreateSecret(k.Namespace, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      k.Name,
+				Namespace: k.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "gopskit",
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{secretKey(key): data},
+		}, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	if sec.Data == nil {
+		sec.Data = make(map[string][]byte)
+	}
+	sec.Data[secretKey(key)] = data
+
+	return k.Client.UpdateSecret(k.Namespace, sec, metav1.UpdateOptions{})
+}
+
+func (k *KubernetesBackend) String() string {
+	return fmt.Sprintf("Kubernetes Secret %s/%s", k.Namespace, k.Name)
+}
+
+// secretKey converts a key into a valid Kubernetes Secret data key
+func secretKey(key string) string {
+	return strings.ReplaceAll(key, "/", ".")
+}
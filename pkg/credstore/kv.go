@@ -0,0 +1,56 @@
+package credstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/fmjstudios/gopskit/pkg/kv"
+)
+
+// enforce implementation of the interface
+var _ Backend = (*KVBackend)(nil)
+
+// KVBackend stores every key within the embedded BadgerDB database at Path. The database is only
+// opened for the duration of a single operation, since BadgerDB holds an exclusive lock on it.
+type KVBackend struct {
+	Path      string
+	Namespace string
+}
+
+func (k *KVBackend) Read(key string) ([]byte, error) {
+	db, err := k.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	data, err := db.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	return data, err
+}
+
+func (k *KVBackend) Write(key string, data []byte) error {
+	db, err := k.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Set(key, data)
+}
+
+func (k *KVBackend) String() string {
+	return fmt.Sprintf("BadgerDB database %s", k.Path)
+}
+
+// open opens the database without BadgerDB's logging, which would clutter the CLI output
+func (k *KVBackend) open() (*kv.Database, error) {
+	return kv.New(k.Path,
+		kv.WithBadgerOptions(badger.DefaultOptions(k.Path).WithLogger(nil)),
+		kv.WithNamespace(k.Namespace),
+	)
+}
@@ -1,7 +1,11 @@
 package helpers
 
 import (
+	"bytes"
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
 	b64 "encoding/base64"
 	"fmt"
 	"math/rand/v2"
@@ -9,14 +13,25 @@ import (
 
 	"github.com/Luzifer/go-dhparam"
 	"github.com/fmjstudios/gopskit/pkg/proc"
+	"golang.org/x/crypto/scrypt"
 )
 
 const (
 	PassphraseDefaultCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
 	PassphraseDefaultLength  = 48
 	DHParamDefaultBits       = 4096
+
+	// scrypt parameters as recommended for interactive logins
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLen      = 16
 )
 
+// encryptionHeader marks data produced by Encrypt
+var encryptionHeader = []byte("gopskit-enc-v1\n")
+
 type PassphraseConfig struct {
 	Length  int
 	CharSet string
@@ -123,3 +138,81 @@ func GenerateDiffieHellmanParams(opts ...DiffieHellmanOpt) (string, error) {
 		return "", fmt.Errorf("invalid DiffieHellman encoding: %v", cfg.Encoding)
 	}
 }
+
+// Encrypt encrypts plaintext with AES-256-GCM using a key derived from the passphrase with scrypt. The
+// random salt and nonce are prepended to the ciphertext, so Decrypt only requires the passphrase.
+func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("cannot encrypt with an empty passphrase")
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err := crand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := passphraseCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := crand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte{}, encryptionHeader...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, encryptionHeader), nil
+}
+
+// Decrypt decrypts data produced by Encrypt with the passphrase it was encrypted with
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("data is not encrypted")
+	}
+
+	data = data[len(encryptionHeader):]
+	if len(data) < saltLen {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+
+	salt, data := data[:saltLen], data[saltLen:]
+	gcm, err := passphraseCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, data, encryptionHeader)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt data. wrong passphrase?")
+	}
+
+	return plaintext, nil
+}
+
+// IsEncrypted checks whether data was produced by Encrypt
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptionHeader)
+}
+
+// passphraseCipher derives an AES-256-GCM cipher from the passphrase and salt
+func passphraseCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,27 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncrypt(t *testing.T) {
+	plaintext := []byte("vault snapshot")
+
+	enc, err := Encrypt(plaintext, "correct horse")
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(enc))
+	assert.NotContains(t, string(enc), string(plaintext))
+
+	dec, err := Decrypt(enc, "correct horse")
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, dec)
+
+	_, err = Decrypt(enc, "battery staple")
+	assert.Error(t, err)
+
+	_, err = Encrypt(plaintext, "")
+	assert.Error(t, err)
+	assert.False(t, IsEncrypted(plaintext))
+}
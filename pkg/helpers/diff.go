@@ -0,0 +1,63 @@
+package helpers
+
+import "strings"
+
+// DiffLines computes a line-based diff between two texts using their longest common subsequence.
+// Every line of the result is prefixed with "- " if it was removed, "+ " if it was added or with
+// two spaces if both texts contain it.
+func DiffLines(before, after string) []string {
+	a, b := splitLines(before), splitLines(after)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+
+	return out
+}
+
+// splitLines splits a text into its lines, ignoring a trailing newline
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
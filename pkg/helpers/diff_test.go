@@ -0,0 +1,16 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLines(t *testing.T) {
+	asrt := assert.New(t)
+
+	asrt.Equal([]string{"  a", "- b", "+ c", "  d"}, DiffLines("a\nb\nd\n", "a\nc\nd\n"))
+	asrt.Equal([]string{"+ a", "+ b"}, DiffLines("", "a\nb"))
+	asrt.Equal([]string{"- a"}, DiffLines("a", ""))
+	asrt.Empty(DiffLines("", ""))
+}
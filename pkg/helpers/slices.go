@@ -23,3 +23,19 @@ func SliceContains[T comparable](s []T, e T) bool {
 	}
 	return false
 }
+
+// SameElements checks if two slices contain the same elements regardless of their order
+func SameElements[T comparable](a, b []T) bool {
+	ra, rb := RemoveDuplicates(a), RemoveDuplicates(b)
+	if len(ra) != len(rb) {
+		return false
+	}
+
+	for _, e := range ra {
+		if !SliceContains(rb, e) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -4,49 +4,82 @@ import (
 	"context"
 	"fmt"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 )
 
+// ApplyOptions configure Apply. Name and Namespace default to those of the applied resource, so
+// cluster-scoped resources are applied if neither sets a namespace.
 type ApplyOptions struct {
 	Name          string
+	Namespace     string
 	GetOptions    *metav1.GetOptions
 	CreateOptions *metav1.CreateOptions
 	UpdateOptions *metav1.UpdateOptions
 }
 
+// Apply creates the resource or updates it if it exists already
 func (c *Client) Apply(schema schema.GroupVersionResource, resource *unstructured.Unstructured, opts *ApplyOptions) error {
-	var result *unstructured.Unstructured
-	var err error
+	if opts == nil {
+		opts = &ApplyOptions{}
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = resource.GetName()
+	}
+
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = resource.GetNamespace()
+	}
+
+	getOpts, createOpts, updateOpts := metav1.GetOptions{}, metav1.CreateOptions{}, metav1.UpdateOptions{}
+	if opts.GetOptions != nil {
+		getOpts = *opts.GetOptions
+	}
+	if opts.CreateOptions != nil {
+		createOpts = *opts.CreateOptions
+	}
+	if opts.UpdateOptions != nil {
+		updateOpts = *opts.UpdateOptions
+	}
 
 	dc, err := dynamic.NewForConfig(c.Config)
 	if err != nil {
 		return err
 	}
 
-	result, err = dc.Resource(schema).Get(context.Background(), opts.Name, *opts.GetOptions)
-	if err != nil {
-		return err
+	var ri dynamic.ResourceInterface = dc.Resource(schema)
+	if namespace != "" {
+		ri = dc.Resource(schema).Namespace(namespace)
 	}
 
-	exists := result.Object["metadata"] != nil
-	if !exists {
-		result, err = dc.Resource(schema).Create(context.Background(), resource, *opts.CreateOptions)
+	existing, err := ri.Get(context.Background(), name, getOpts)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		result, err := ri.Create(context.Background(), resource, createOpts)
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf(`Created Kubernetes Resource: %v named %v\n`, result.GetKind(), result.GetName())
+		fmt.Printf("Created Kubernetes Resource: %v named %v\n", result.GetKind(), result.GetName())
 		return nil
 	}
 
-	result, err = dc.Resource(schema).Update(context.Background(), resource, *opts.UpdateOptions)
+	// updates are rejected without the current resource version
+	resource.SetResourceVersion(existing.GetResourceVersion())
+	result, err := ri.Update(context.Background(), resource, updateOpts)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf(`Updated Kubernetes Resource: %v named %v\n`, result.GetKind(), result.GetName())
+	fmt.Printf("Updated Kubernetes Resource: %v named %v\n", result.GetKind(), result.GetName())
 	return nil
 }
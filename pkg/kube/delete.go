@@ -0,0 +1,16 @@
+package kube
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (c *Client) DeletePod(namespace, name string, opts metav1.DeleteOptions) error {
+	err := c.Client.CoreV1().Pods(namespace).Delete(context.Background(), name, opts)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,282 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// DefaultWaitTimeout is the time WaitForPod waits for a Pod's condition if the context has no deadline
+const DefaultWaitTimeout = 5 * time.Minute
+
+// ErrNoLeader is returned by a LeaderStrategy which could not determine a leader among the Pods
+var ErrNoLeader = errors.New("could not determine leader pod")
+
+// LeaderStrategy determines the leader among the Pods of a workload, i.e. the Pod CLIs should talk to
+type LeaderStrategy interface {
+	Leader(ctx context.Context, c *Client, pods []corev1.Pod) (*corev1.Pod, error)
+}
+
+// LeaderFunc is an adapter to use ordinary functions as LeaderStrategy
+type LeaderFunc func(ctx context.Context, c *Client, pods []corev1.Pod) (*corev1.Pod, error)
+
+func (f LeaderFunc) Leader(ctx context.Context, c *Client, pods []corev1.Pod) (*corev1.Pod, error) {
+	return f(ctx, c, pods)
+}
+
+// LabelLeader selects the Pod which additionally matches the given label selector, e.g. the
+// 'vault-active=true' label Vault's Kubernetes service registration maintains
+func LabelLeader(selector string) LeaderStrategy {
+	return LeaderFunc(func(ctx context.Context, c *Client, pods []corev1.Pod) (*corev1.Pod, error) {
+		var matched []corev1.Pod
+		for _, ns := range podNamespaces(pods) {
+			active, err := c.Client.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, a := range active.Items {
+				if containsPod(pods, a) {
+					matched = append(matched, a)
+				}
+			}
+		}
+
+		switch len(matched) {
+		case 0:
+			return nil, fmt.Errorf("%w: no pod matches label %s", ErrNoLeader, selector)
+		case 1:
+			return &matched[0], nil
+		default:
+			return nil, fmt.Errorf("%w: label %s matched %d pods", ErrNoLeader, selector, len(matched))
+		}
+	})
+}
+
+// ordinalPattern matches the ordinal suffix StatefulSet controllers append to their Pod names
+var ordinalPattern = regexp.MustCompile(`-(\d+)$`)
+
+// OrdinalLeader selects the Pod with the given ordinal of a StatefulSet, e.g. 'vault-0'
+func OrdinalLeader(ordinal int) LeaderStrategy {
+	return LeaderFunc(func(_ context.Context, _ *Client, pods []corev1.Pod) (*corev1.Pod, error) {
+		for i, p := range pods {
+			if !ownedBy(p, "StatefulSet") {
+				continue
+			}
+
+			m := ordinalPattern.FindStringSubmatch(p.Name)
+			if m != nil && m[1] == strconv.Itoa(ordinal) {
+				return &pods[i], nil
+			}
+		}
+
+		return nil, fmt.Errorf("%w: no StatefulSet pod with ordinal %d", ErrNoLeader, ordinal)
+	})
+}
+
+// ReadyLeader selects the oldest Ready Pod, which suits workloads without a leader concept
+func ReadyLeader() LeaderStrategy {
+	return LeaderFunc(func(_ context.Context, _ *Client, pods []corev1.Pod) (*corev1.Pod, error) {
+		var ready []corev1.Pod
+		for _, p := range pods {
+			if PodReady(&p) {
+				ready = append(ready, p)
+			}
+		}
+
+		if len(ready) == 0 {
+			return nil, fmt.Errorf("%w: no pod is ready", ErrNoLeader)
+		}
+
+		sort.Slice(ready, func(i, j int) bool {
+			return ready[i].CreationTimestamp.Before(&ready[j].CreationTimestamp)
+		})
+
+		return &ready[0], nil
+	})
+}
+
+// ProbeLeader selects the first Pod for which probe reports leadership, e.g. by asking the application's
+// own API. Pods which cannot be probed are skipped.
+func ProbeLeader(probe func(ctx context.Context, pod corev1.Pod) (bool, error)) LeaderStrategy {
+	return LeaderFunc(func(ctx context.Context, _ *Client, pods []corev1.Pod) (*corev1.Pod, error) {
+		var errs []error
+		for i, p := range pods {
+			leader, err := probe(ctx, p)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("pod %s: %w", p.Name, err))
+				continue
+			}
+
+			if leader {
+				return &pods[i], nil
+			}
+		}
+
+		return nil, fmt.Errorf("%w: no pod reported leadership %v", ErrNoLeader, errors.Join(errs...))
+	})
+}
+
+// FirstOf tries the given strategies in order and returns the leader of the first one to succeed
+func FirstOf(strategies ...LeaderStrategy) LeaderStrategy {
+	return LeaderFunc(func(ctx context.Context, c *Client, pods []corev1.Pod) (*corev1.Pod, error) {
+		var errs []error
+		for _, s := range strategies {
+			leader, err := s.Leader(ctx, c, pods)
+			if err == nil {
+				return leader, nil
+			}
+
+			errs = append(errs, err)
+		}
+
+		return nil, errors.Join(errs...)
+	})
+}
+
+// LeaderPod determines the leader among pods with the given LeaderStrategy. A single Pod is always its
+// own leader.
+func (c *Client) LeaderPod(ctx context.Context, pods []corev1.Pod, strategy LeaderStrategy) (*corev1.Pod, error) {
+	switch len(pods) {
+	case 0:
+		return nil, fmt.Errorf("%w: no pods given", ErrNoLeader)
+	case 1:
+		return &pods[0], nil
+	}
+
+	return strategy.Leader(ctx, c, pods)
+}
+
+// ResolveNamespace determines the namespace of a workload. If namespace is set it is used as-is,
+// otherwise all pods are required to reside within a single namespace.
+func ResolveNamespace(pods []corev1.Pod, namespace string) (string, error) {
+	if namespace != "" {
+		return namespace, nil
+	}
+
+	namespaces := podNamespaces(pods)
+	switch len(namespaces) {
+	case 0:
+		return "", fmt.Errorf("cannot resolve the namespace of zero pods")
+	case 1:
+		return namespaces[0], nil
+	default:
+		return "", fmt.Errorf("discovered pods in multiple namespaces: %v! Please set the namespace option",
+			namespaces)
+	}
+}
+
+// PodCondition reports whether a Pod reached the state a caller waits for
+type PodCondition func(pod *corev1.Pod) bool
+
+// PodRunning reports whether the Pod is in the Running phase
+func PodRunning(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodRunning
+}
+
+// PodReady reports whether the Pod's Ready condition is true
+func PodReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// WaitForPod watches the Pod until it satisfies condition and returns its latest state. If ctx has no
+// deadline, DefaultWaitTimeout applies.
+func (c *Client) WaitForPod(ctx context.Context, namespace, name string, condition PodCondition) (*corev1.Pod, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultWaitTimeout)
+		defer cancel()
+	}
+
+	pods := c.Client.CoreV1().Pods(namespace)
+	pod, err := pods.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if condition(pod) {
+		return pod, nil
+	}
+
+	w, err := pods.Watch(ctx, metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+		ResourceVersion: pod.ResourceVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for pod %s/%s: %w", namespace, name, ctx.Err())
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				// the API server closed the watch, start over from the current state
+				return c.WaitForPod(ctx, namespace, name, condition)
+			}
+
+			switch ev.Type {
+			case watch.Deleted:
+				return nil, fmt.Errorf("pod %s/%s was deleted while waiting for it", namespace, name)
+			case watch.Added, watch.Modified:
+				p, ok := ev.Object.(*corev1.Pod)
+				if ok && condition(p) {
+					return p, nil
+				}
+			}
+		}
+	}
+}
+
+// podNamespaces returns the distinct namespaces of the given pods in order of appearance
+func podNamespaces(pods []corev1.Pod) []string {
+	var namespaces []string
+	seen := make(map[string]bool)
+	for _, p := range pods {
+		if !seen[p.Namespace] {
+			seen[p.Namespace] = true
+			namespaces = append(namespaces, p.Namespace)
+		}
+	}
+
+	return namespaces
+}
+
+// containsPod checks whether pod is part of pods
+func containsPod(pods []corev1.Pod, pod corev1.Pod) bool {
+	for _, p := range pods {
+		if p.Namespace == pod.Namespace && p.Name == pod.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownedBy checks whether the pod is controlled by a resource of the given kind
+func ownedBy(pod corev1.Pod, kind string) bool {
+	for _, o := range pod.OwnerReferences {
+		if o.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
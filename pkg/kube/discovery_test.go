@@ -0,0 +1,96 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func statefulSetPod(name, namespace string, ready bool, created time.Time) corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			CreationTimestamp: metav1.NewTime(created),
+			OwnerReferences:   []metav1.OwnerReference{{Kind: "StatefulSet", Name: "vault"}},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestLeaderStrategies(t *testing.T) {
+	now := time.Now()
+	pods := []corev1.Pod{
+		statefulSetPod("vault-10", "vault", true, now.Add(-3*time.Hour)),
+		statefulSetPod("vault-1", "vault", true, now.Add(-1*time.Hour)),
+		statefulSetPod("vault-0", "vault", false, now.Add(-2*time.Hour)),
+	}
+	ctx := context.Background()
+
+	leader, err := OrdinalLeader(1).Leader(ctx, nil, pods)
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-1", leader.Name)
+
+	_, err = OrdinalLeader(2).Leader(ctx, nil, pods)
+	assert.ErrorIs(t, err, ErrNoLeader)
+
+	leader, err = ReadyLeader().Leader(ctx, nil, pods)
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-10", leader.Name)
+
+	probe := ProbeLeader(func(_ context.Context, pod corev1.Pod) (bool, error) {
+		if pod.Name == "vault-10" {
+			return false, errors.New("connection refused")
+		}
+
+		return pod.Name == "vault-0", nil
+	})
+	leader, err = probe.Leader(ctx, nil, pods)
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-0", leader.Name)
+
+	leader, err = FirstOf(OrdinalLeader(5), ReadyLeader()).Leader(ctx, nil, pods)
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-10", leader.Name)
+
+	c := &Client{}
+	leader, err = c.LeaderPod(ctx, pods[2:], OrdinalLeader(5))
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-0", leader.Name)
+
+	_, err = c.LeaderPod(ctx, nil, ReadyLeader())
+	assert.ErrorIs(t, err, ErrNoLeader)
+}
+
+func TestResolveNamespace(t *testing.T) {
+	now := time.Now()
+	pods := []corev1.Pod{
+		statefulSetPod("vault-0", "vault", true, now),
+		statefulSetPod("vault-1", "vault", true, now),
+	}
+
+	ns, err := ResolveNamespace(pods, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "vault", ns)
+
+	pods = append(pods, statefulSetPod("vault-0", "staging", true, now))
+	_, err = ResolveNamespace(pods, "")
+	assert.Error(t, err)
+
+	ns, err = ResolveNamespace(pods, "staging")
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", ns)
+}
@@ -18,6 +18,15 @@ func (c *Client) Namespaces(opts metav1.ListOptions) ([]corev1.Namespace, error)
 	return ns.Items, nil
 }
 
+func (c *Client) Pod(namespace, name string, opts metav1.GetOptions) (*corev1.Pod, error) {
+	pod, err := c.Client.CoreV1().Pods(namespace).Get(context.Background(), name, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return pod, nil
+}
+
 func (c *Client) Pods(namespace string, opts metav1.ListOptions) ([]corev1.Pod, error) {
 	podL, err := c.Client.CoreV1().Pods(namespace).List(context.Background(), opts)
 	if err != nil {
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/httpstream"
@@ -57,15 +59,50 @@ func (*DefaultPortForwarder) ForwardPorts(method string, restConfig *rest.Config
 	return pf.ForwardPorts()
 }
 
+// PortForwardOpt represents a configuration option for a single PortForward call
+type PortForwardOpt func(o *portForwardOptions)
+
+type portForwardOptions struct {
+	// localPort is the port on the local machine the remote port is forwarded to
+	localPort string
+
+	// readyChan is closed once the port-forward accepts connections
+	readyChan chan struct{}
+}
+
+// WithLocalPort forwards the remote port to the given local port instead of the DefaultLocalPort,
+// which allows multiple Pods to be port-forwarded at the same time
+func WithLocalPort(port string) PortForwardOpt {
+	return func(o *portForwardOptions) {
+		o.localPort = port
+	}
+}
+
+// WithReadyChannel configures a channel which is closed once the port-forward accepts connections
+func WithReadyChannel(ready chan struct{}) PortForwardOpt {
+	return func(o *portForwardOptions) {
+		o.readyChan = ready
+	}
+}
+
 // PortForward port-forwards a remote port of a Kubernetes container to the local machine
-func (c *Client) PortForward(ctx context.Context, pod corev1.Pod) error {
+func (c *Client) PortForward(ctx context.Context, pod corev1.Pod, opts ...PortForwardOpt) error {
 	if pod.Status.Phase != corev1.PodRunning {
 		return fmt.Errorf("uanble to forward ports to a pod that isn't running. Current status: %v", pod.Status.Phase)
 	}
 
+	o := &portForwardOptions{
+		localPort: DefaultLocalPort,
+		readyChan: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	// create control channels
 	stopChan := make(chan struct{})
-	readyChan := make(chan struct{})
+	readyChan := o.readyChan
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt)
@@ -97,7 +134,7 @@ func (c *Client) PortForward(ctx context.Context, pod corev1.Pod) error {
 		"POST",
 		c.Config,
 		req.URL(),
-		BuildDefaultPortMap(pod.Spec.Containers[0].Ports[0].ContainerPort),
+		BuildPortMap(o.localPort, pod.Spec.Containers[0].Ports[0].ContainerPort),
 		stopChan,
 		readyChan,
 	)
@@ -127,5 +164,21 @@ func createDialer(method string, url *url.URL, restConfig *rest.Config) (httpstr
 
 // only require remoteport as input
 func BuildDefaultPortMap(remotePort int32) []string {
-	return []string{fmt.Sprintf("%s:%d", DefaultLocalPort, remotePort)}
+	return BuildPortMap(DefaultLocalPort, remotePort)
+}
+
+// BuildPortMap maps a local port to the remote port of a container
+func BuildPortMap(localPort string, remotePort int32) []string {
+	return []string{fmt.Sprintf("%s:%d", localPort, remotePort)}
+}
+
+// FreeLocalPort asks the operating system for a currently unused local TCP port
+func FreeLocalPort() (string, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port), nil
 }
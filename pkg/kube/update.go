@@ -0,0 +1,17 @@
+package kube
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (c *Client) UpdateSecret(namespace string, secret *corev1.Secret, opts metav1.UpdateOptions) error {
+	_, err := c.Client.CoreV1().Secrets(namespace).Update(context.Background(), secret, opts)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
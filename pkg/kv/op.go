@@ -6,7 +6,6 @@ import (
 	"github.com/fmjstudios/gopskit/pkg/helpers"
 	"golang.org/x/sync/errgroup"
 	"strings"
-	"time"
 )
 
 // enforce implementation of the interface
@@ -198,9 +197,7 @@ func (d *Database) Close() error {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	doneC := make(chan struct{})
-	go d.gc(doneC)
-	<-doneC
+	d.gc()
 
 	return d.kv.Close()
 }
@@ -242,19 +239,12 @@ func (d *Database) ensureNonNamespaced(key string) error {
 	return nil
 }
 
-// gc runs the garbage-collection for the BadgerDB which saves filesystem space. It is run
-// as a goroutine before closing the database connection.
-func (d *Database) gc(doneChan chan struct{}) {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-	again:
-		err := d.kv.RunValueLogGC(0.7)
-		if err == nil {
-			goto again
+// gc runs the garbage-collection for the BadgerDB which saves filesystem space. Value log files are
+// rewritten until BadgerDB reports that there is nothing left to rewrite.
+func (d *Database) gc() {
+	for {
+		if err := d.kv.RunValueLogGC(d.discardRatio); err != nil {
+			return
 		}
 	}
-
-	doneChan <- struct{}{}
 }
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -13,6 +14,9 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// ErrNoMatch is returned by GetSecretValue if the JSONPath expression doesn't match any value
+var ErrNoMatch = errors.New("did not match any YAML node")
+
 // HelmPlugin represents a Helm plugin required for gopskit to work
 type HelmPlugin int
 
@@ -237,6 +241,16 @@ func GetFileState(path string) (FileState, error) {
 	return encrypted, nil
 }
 
+// IsEncryptedFile reports whether the file at path is currently SOPS-encrypted
+func IsEncryptedFile(path string) (bool, error) {
+	state, err := GetFileState(path)
+	if err != nil {
+		return false, err
+	}
+
+	return state == encrypted, nil
+}
+
 // EncryptFile encrypts a file using the Helm Secrets Plugin
 func EncryptFile(path string) error {
 	e, err := proc.NewExecutor(proc.WithInheritedEnv())
@@ -300,7 +314,6 @@ func GetSecretValue(path, jsonPath string, unencrypted bool) (string, error) {
 	}
 
 	state, err := GetFileState(path)
-	fmt.Printf("GetSecretValue file state is: %s\n", state)
 	if err != nil {
 		return "", err
 	}
@@ -340,6 +353,10 @@ func GetSecretValue(path, jsonPath string, unencrypted bool) (string, error) {
 		return "", fmt.Errorf("JSONPath expression: %s did not result in unique YAML node", jsonPath)
 	}
 
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("JSONPath expression: %s %w", jsonPath, ErrNoMatch)
+	}
+
 	return nodes[0].Value, nil
 }
 